@@ -1,13 +1,15 @@
 package cache
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gomodule/redigo/redis"
 )
 
-// redigo 目前不支持 redis 集群
+// redigo 不支持 redis 集群，集群模式的槽位路由以及重定向见 cluster.go
 // 命令的文字注释来自于 http://doc.redisfans.com，稍有修改
 
 var (
@@ -28,6 +30,14 @@ type Cache interface {
 	DO(cmd string, args ...interface{}) (interface{}, error)
 	Conn() Conn
 
+	// Context 绑定的 context，没有绑定时为 context.Background()
+	Context() context.Context
+	// WithContext 返回绑定了 ctx 的 Cache，与原 Cache 共享连接池
+	// 通过返回的 Cache 执行的命令、管道以及事务都会使用 ctx
+	WithContext(ctx context.Context) Cache
+	// DoContext 使用 ctx 执行命令
+	DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error)
+
 	Convert
 	Key
 	String
@@ -35,6 +45,11 @@ type Cache interface {
 	List
 	Set
 	SortedSet
+	Transaction
+	PubSub
+	Stream
+	Scanner
+	Scripting
 }
 
 // Conn ..
@@ -142,29 +157,46 @@ type Set interface {
 // SortedSet 有序集合
 type SortedSet interface {
 	ZAdd(v ...interface{}) (int, error)
+	ZAddZ(key string, members ...Z) (int, error)
 	ZCard(key string) (int, error)
-	ZCount(key string, min int, max int) (int, error)
-	ZIncrby(key, member string, incrment int) (int, error)
+	ZCount(key, min, max string) (int, error)
+	ZLexCount(key, min, max string) (int, error)
+	ZIncrby(key, member string, increment float64) (float64, error)
 	ZRange(key string, start, stop int) ([]string, error)
-	ZRangeWithScores(key string, start, stop int) ([]string, error)
-	ZScore(key, member string) (int, error)
+	ZRangeWithScores(key string, start, stop int) ([]Z, error)
+	ZScore(key, member string) (float64, error)
 	ZRank(key, member string) (int, error)
-	ZRangeByScore(key string, min, max, limit, offset, count int) ([]string, error)
+	ZRangeByScore(key, min, max string, offset, count int) ([]Z, error)
 	ZRevRank(key, member string) (int, error)
-	ZRevRangeByScore(key string, max, min, limit, offset, count int) ([]string, error)
+	ZRevRangeByScore(key, max, min string, offset, count int) ([]Z, error)
 	ZRevRange(key string, start, stop int) ([]string, error)
-	ZRemRangeByScore(key string, min, max int) (int, error)
+	ZRevRangeWithScores(key string, start, stop int) ([]Z, error)
+	ZRemRangeByScore(key, min, max string) (int, error)
 	ZRemRangeByRank(key string, start, stop int) (int, error)
 	ZRem(v ...interface{}) (int, error)
+	ZUnionStore(destination string, store ZStore) (int, error)
+	ZInterStore(destination string, store ZStore) (int, error)
+	ZPopMin(key string, count int) ([]Z, error)
+	ZPopMax(key string, count int) ([]Z, error)
+	BZPopMin(timeout time.Duration, keys ...string) (*ZWithKey, error)
+	BZPopMax(timeout time.Duration, keys ...string) (*ZWithKey, error)
 }
 
-// TODO Pub/Sub
-// TODO Transaction
 // TODO Server
 
+// connPool 连接池，单机模式使用 redis.Pool，哨兵模式使用 sentinelPool，集群模式使用 clusterPool
+type connPool interface {
+	Get() redis.Conn
+	Close() error
+}
+
 type cache struct {
 	conf *config
-	pool *redis.Pool
+	pool connPool
+	// memory 内存数据，仅在 WithMemory 时使用
+	memory *memoryStore
+	// ctx 通过 WithContext 绑定
+	ctx context.Context
 }
 
 // NewCache ..
@@ -204,15 +236,7 @@ func (c *cache) Close() error {
 
 // DO ..
 func (c *cache) DO(cmd string, args ...interface{}) (interface{}, error) {
-	conn := c.pool.Get()
-	if conn == nil {
-		return nil, ErrInvalidConn
-	}
-
-	// 执行结束后，没有错误，没有关闭连接，没有超过 MaxIdle 情况下，activeConn 会放入 idle 队列
-	defer conn.Close()
-
-	return conn.Do(cmd, args...)
+	return c.DoContext(c.Context(), cmd, args...)
 }
 
 // Conn 获取 redigo Conn
@@ -224,6 +248,29 @@ func (c *cache) Conn() Conn {
 func (c *cache) initRedis() error {
 	conf := c.conf
 
+	switch {
+	case conf.memory:
+		if c.memory == nil {
+			c.memory = newMemoryStore()
+		}
+		c.pool = c.newPool(c.dialMemory)
+	case len(conf.clusterAddrs) > 0:
+		// 集群只支持 0 号数据库
+		conf.db = 0
+		c.pool = newClusterPool(c)
+	case conf.sentinelMaster != "":
+		c.pool = newSentinelPool(c)
+	default:
+		c.pool = c.newPool(c.dialRedis)
+	}
+
+	return nil
+}
+
+// newPool 创建连接池，dial 用于创建新连接
+func (c *cache) newPool(dial func() (redis.Conn, error)) *redis.Pool {
+	conf := c.conf
+
 	pool := &redis.Pool{
 		MaxIdle:     conf.maxIdle,
 		MaxActive:   conf.maxActive,
@@ -232,9 +279,7 @@ func (c *cache) initRedis() error {
 
 	// 创建新连接
 	pool.Dial = func() (redis.Conn, error) {
-		options := redis.DialPassword(conf.password)
-		addr := fmt.Sprintf("%s:%d", conf.host, conf.port)
-		conn, err := redis.Dial("tcp", addr, options)
+		conn, err := dial()
 		if err != nil {
 			return nil, err
 		}
@@ -247,9 +292,24 @@ func (c *cache) initRedis() error {
 		return conn, nil
 	}
 
-	c.pool = pool
+	return pool
+}
 
-	return nil
+// dialRedis 连接 redis-server
+func (c *cache) dialRedis() (redis.Conn, error) {
+	conf := c.conf
+	return c.dialAddr(fmt.Sprintf("%s:%d", conf.host, conf.port))
+}
+
+// dialAddr 连接指定地址的 redis-server
+func (c *cache) dialAddr(addr string) (redis.Conn, error) {
+	options := redis.DialPassword(c.conf.password)
+	return redis.Dial("tcp", addr, options)
+}
+
+// dialMemory 连接进程内存
+func (c *cache) dialMemory() (redis.Conn, error) {
+	return newMemoryConn(c.memory), nil
 }
 
 func (c *cache) Int(reply interface{}, err error) (int, error) {
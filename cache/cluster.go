@@ -0,0 +1,562 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// 集群模式
+// 根据 key 计算槽位，将命令发送到槽位所在的主节点，并处理 MOVED/ASK 重定向
+// 限制:
+// 只能使用 0 号数据库
+// 多个 key 的命令 (MGET, DEL 等)，所有 key 需要在同一个槽位
+// 管道、事务、Watch 以及 Conn 获取的连接在 Send 之后，所有命令都发送到第一个带 key 的命令所在的节点
+// 可以使用 hash tag 让多个 key 分配到同一个槽位，例如 {user:1}:name, {user:1}:age
+//
+// eg:
+// c := cache.NewCache(cache.WithCluster("10.0.0.1:7000", "10.0.0.2:7000", "10.0.0.3:7000"))
+
+const (
+	// clusterSlots 槽位数量
+	clusterSlots = 16384
+	// clusterMaxRedirects 一条命令最多重定向的次数
+	clusterMaxRedirects = 5
+	// clusterRetryDelay 集群返回 TRYAGAIN, CLUSTERDOWN 时的重试间隔
+	clusterRetryDelay = 50 * time.Millisecond
+)
+
+var (
+	// ErrClusterNoNode 无法获取集群的槽位信息
+	ErrClusterNoNode = errors.New("cluster: no available node")
+)
+
+type clusterPool struct {
+	c *cache
+
+	mu sync.RWMutex
+	// slots 槽位所在的主节点地址
+	slots []string
+	// pools 每个节点的连接池
+	pools map[string]*redis.Pool
+	// refreshing 正在后台更新槽位信息
+	refreshing int32
+}
+
+func newClusterPool(c *cache) *clusterPool {
+	return &clusterPool{
+		c:     c,
+		pools: make(map[string]*redis.Pool),
+	}
+}
+
+// Get 获取连接，连接在执行命令时才会选择节点
+func (p *clusterPool) Get() redis.Conn {
+	return &clusterConn{p: p}
+}
+
+// Close 关闭所有节点的连接池
+func (p *clusterPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var err error
+	for addr, pool := range p.pools {
+		if e := pool.Close(); e != nil && err == nil {
+			err = e
+		}
+		delete(p.pools, addr)
+	}
+	return err
+}
+
+// nodePool 获取节点的连接池，不存在时创建
+func (p *clusterPool) nodePool(addr string) *redis.Pool {
+	p.mu.RLock()
+	pool, exist := p.pools[addr]
+	p.mu.RUnlock()
+	if exist {
+		return pool
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pool, exist = p.pools[addr]; !exist {
+		pool = p.c.newPool(func() (redis.Conn, error) {
+			return p.c.dialAddr(addr)
+		})
+		p.pools[addr] = pool
+	}
+	return pool
+}
+
+// addr 槽位所在的节点，slot 小于 0 或者槽位没有分配时返回任意一个节点
+func (p *clusterPool) addr(slot int) (string, error) {
+	p.mu.RLock()
+	loaded := p.slots != nil
+	p.mu.RUnlock()
+	if !loaded {
+		if err := p.refresh(); err != nil {
+			return "", err
+		}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if slot >= 0 && p.slots[slot] != "" {
+		return p.slots[slot], nil
+	}
+	for _, addr := range p.slots {
+		if addr != "" {
+			return addr, nil
+		}
+	}
+	return "", ErrClusterNoNode
+}
+
+// doNode 在指定的节点上执行命令
+func (c *cache) doNode(addr, cmd string, args ...interface{}) (interface{}, error) {
+	pool := c.pool.(*clusterPool).nodePool(addr)
+	return c.process(c.Context(), cmd, args, func(ctx context.Context) (interface{}, error) {
+		conn := pool.Get()
+		return runContext(ctx, func() (interface{}, error) {
+			return doContext(ctx, conn, cmd, args...)
+		}, func() {
+			conn.Close()
+		})
+	})
+}
+
+// masters 所有分配了槽位的主节点，用于需要在每个节点上执行的命令，例如 SCAN
+func (p *clusterPool) masters() ([]string, error) {
+	if _, err := p.addr(-1); err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	seen := make(map[string]struct{})
+	var addrs []string
+	for _, addr := range p.slots {
+		if _, exist := seen[addr]; addr != "" && !exist {
+			seen[addr] = struct{}{}
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+// nodes 已知的节点地址，配置的地址排在最后
+func (p *clusterPool) nodes() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	seen := make(map[string]struct{})
+	var addrs []string
+	for _, addr := range p.slots {
+		if _, exist := seen[addr]; addr != "" && !exist {
+			seen[addr] = struct{}{}
+			addrs = append(addrs, addr)
+		}
+	}
+	for _, addr := range p.c.conf.clusterAddrs {
+		if _, exist := seen[addr]; !exist {
+			seen[addr] = struct{}{}
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// refresh 通过 CLUSTER SLOTS 更新槽位信息
+func (p *clusterPool) refresh() error {
+	err := ErrClusterNoNode
+	for _, addr := range p.nodes() {
+		conn := p.nodePool(addr).Get()
+		reply, e := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+		conn.Close()
+		if e != nil {
+			err = e
+			continue
+		}
+
+		slots, e := parseClusterSlots(reply, addr)
+		if e != nil {
+			err = e
+			continue
+		}
+
+		p.mu.Lock()
+		p.slots = slots
+		p.mu.Unlock()
+		return nil
+	}
+	return err
+}
+
+// refreshAsync 在后台更新槽位信息，同一时间只有一个更新
+func (p *clusterPool) refreshAsync() {
+	if !atomic.CompareAndSwapInt32(&p.refreshing, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&p.refreshing, 0)
+		p.refresh()
+	}()
+}
+
+// moved 收到 MOVED 之后立即更新该槽位，并在后台更新所有槽位
+func (p *clusterPool) moved(slot int, addr string) {
+	p.mu.Lock()
+	if p.slots != nil && slot >= 0 && slot < clusterSlots {
+		p.slots[slot] = addr
+	}
+	p.mu.Unlock()
+
+	p.refreshAsync()
+}
+
+// do 执行单条命令，处理重定向
+func (p *clusterPool) do(timeout time.Duration, cmd string, args []interface{}) (interface{}, error) {
+	slot := -1
+	if key, ok := clusterKey(cmd, args); ok {
+		slot = clusterSlot(key)
+	}
+	addr, err := p.addr(slot)
+	if err != nil {
+		return nil, err
+	}
+
+	asking := false
+	for i := 0; ; i++ {
+		conn := p.nodePool(addr).Get()
+		if asking {
+			conn.Send("ASKING")
+		}
+		var reply interface{}
+		if timeout > 0 {
+			reply, err = redis.DoWithTimeout(conn, timeout, cmd, args...)
+		} else {
+			reply, err = conn.Do(cmd, args...)
+		}
+		conn.Close()
+
+		if i >= clusterMaxRedirects {
+			return reply, err
+		}
+		kind, target, ok := clusterRedirect(err)
+		if !ok {
+			return reply, err
+		}
+
+		switch kind {
+		case "MOVED":
+			p.moved(slot, target)
+			addr, asking = target, false
+		case "ASK":
+			addr, asking = target, true
+		default:
+			time.Sleep(clusterRetryDelay)
+		}
+	}
+}
+
+// parseClusterSlots 解析 CLUSTER SLOTS 的回复
+// [[start, end, [host, port, id], [replica host, port, id] ...] ...]
+// host 为空时表示与 from 为同一个节点
+func parseClusterSlots(reply []interface{}, from string) ([]string, error) {
+	fromHost, _, _ := net.SplitHostPort(from)
+
+	slots := make([]string, clusterSlots)
+	for _, item := range reply {
+		values, err := redis.Values(item, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) < 3 {
+			return nil, errors.New("cluster: unexpected CLUSTER SLOTS reply")
+		}
+		start, err := redis.Int(values[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		end, err := redis.Int(values[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		node, err := redis.Values(values[2], nil)
+		if err != nil || len(node) < 2 {
+			return nil, errors.New("cluster: unexpected CLUSTER SLOTS node")
+		}
+		host, _ := redis.String(node[0], nil)
+		port, err := redis.Int(node[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		if host == "" {
+			host = fromHost
+		}
+		if start < 0 || end >= clusterSlots || start > end {
+			return nil, fmt.Errorf("cluster: invalid slot range %d-%d", start, end)
+		}
+
+		addr := net.JoinHostPort(host, strconv.Itoa(port))
+		for slot := start; slot <= end; slot++ {
+			slots[slot] = addr
+		}
+	}
+	return slots, nil
+}
+
+// clusterRedirect 解析重定向错误
+// MOVED 3999 127.0.0.1:6381, ASK 3999 127.0.0.1:6381, TRYAGAIN, CLUSTERDOWN
+func clusterRedirect(err error) (kind, addr string, ok bool) {
+	e, isRedis := err.(redis.Error)
+	if !isRedis {
+		return "", "", false
+	}
+
+	fields := strings.Fields(string(e))
+	if len(fields) == 0 {
+		return "", "", false
+	}
+	switch fields[0] {
+	case "MOVED", "ASK":
+		if len(fields) == 3 {
+			return fields[0], fields[2], true
+		}
+	case "TRYAGAIN", "CLUSTERDOWN":
+		return fields[0], "", true
+	}
+	return "", "", false
+}
+
+// clusterKey 命令用于计算槽位的 key，没有 key 的命令返回 false
+func clusterKey(cmd string, args []interface{}) (string, bool) {
+	index := 0
+	switch strings.ToUpper(cmd) {
+	case "PING", "ECHO", "AUTH", "SELECT", "QUIT", "ASKING", "READONLY", "READWRITE",
+		"MULTI", "EXEC", "DISCARD", "UNWATCH",
+		"PUBLISH", "SUBSCRIBE", "PSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE",
+		"INFO", "CLUSTER", "CLIENT", "CONFIG", "ROLE", "TIME", "DBSIZE", "FLUSHDB", "FLUSHALL",
+		"KEYS", "SCAN", "RANDOMKEY", "SCRIPT":
+		return "", false
+	case "EVAL", "EVALSHA":
+		// EVAL script numkeys key [key ...] arg [arg ...]
+		if len(args) < 3 || clusterArg(args[1]) == "0" {
+			return "", false
+		}
+		index = 2
+	case "XREAD", "XREADGROUP":
+		// STREAMS 之后的第一个参数
+		index = -1
+		for i, arg := range args {
+			if strings.ToUpper(clusterArg(arg)) == "STREAMS" {
+				index = i + 1
+				break
+			}
+		}
+	case "XGROUP", "XINFO", "OBJECT", "BITOP":
+		index = 1
+	}
+
+	if index < 0 || index >= len(args) {
+		return "", false
+	}
+	return clusterArg(args[index]), true
+}
+
+func clusterArg(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// clusterSlot 计算 key 的槽位，key 中包含 {tag} 时只使用 tag 计算
+func clusterSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % clusterSlots)
+}
+
+// crc16 CRC16-CCITT (XMODEM)
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// clusterConn 集群连接
+// 单独使用 Do 时，每条命令根据 key 选择节点，并处理重定向
+// 使用 Send 或者执行 WATCH, MULTI 之后，连接绑定到第一个带 key 的命令所在的节点，之后不再处理重定向
+type clusterConn struct {
+	p *clusterPool
+	// conn 绑定的节点连接
+	conn redis.Conn
+	// pending 绑定节点之前 Send 的命令
+	pending []clusterCommand
+	err     error
+}
+
+type clusterCommand struct {
+	name string
+	args []interface{}
+}
+
+// Close ..
+func (c *clusterConn) Close() error {
+	c.pending = nil
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// Err ..
+func (c *clusterConn) Err() error {
+	if c.conn != nil {
+		return c.conn.Err()
+	}
+	return c.err
+}
+
+// Do ..
+func (c *clusterConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return c.DoWithTimeout(0, cmd, args...)
+}
+
+// DoWithTimeout ..
+func (c *clusterConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	if c.conn == nil && len(c.pending) == 0 {
+		if cmd == "" {
+			return nil, nil
+		}
+		switch strings.ToUpper(cmd) {
+		case "WATCH", "MULTI":
+		default:
+			return c.p.do(timeout, cmd, args)
+		}
+	}
+
+	if err := c.bind(clusterCommand{cmd, args}); err != nil {
+		return nil, err
+	}
+	var (
+		reply interface{}
+		err   error
+	)
+	if timeout > 0 {
+		reply, err = redis.DoWithTimeout(c.conn, timeout, cmd, args...)
+	} else {
+		reply, err = c.conn.Do(cmd, args...)
+	}
+	c.checkRedirect(err)
+	return reply, err
+}
+
+// Send ..
+func (c *clusterConn) Send(cmd string, args ...interface{}) error {
+	if c.conn == nil {
+		c.pending = append(c.pending, clusterCommand{cmd, args})
+		return nil
+	}
+	return c.conn.Send(cmd, args...)
+}
+
+// Flush ..
+func (c *clusterConn) Flush() error {
+	if err := c.bind(clusterCommand{}); err != nil {
+		return err
+	}
+	return c.conn.Flush()
+}
+
+// Receive ..
+func (c *clusterConn) Receive() (interface{}, error) {
+	return c.ReceiveWithTimeout(0)
+}
+
+// ReceiveWithTimeout ..
+func (c *clusterConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	if c.conn == nil {
+		if err := c.Flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		reply interface{}
+		err   error
+	)
+	if timeout > 0 {
+		reply, err = redis.ReceiveWithTimeout(c.conn, timeout)
+	} else {
+		reply, err = c.conn.Receive()
+	}
+	c.checkRedirect(err)
+	return reply, err
+}
+
+// bind 绑定节点，并发送之前缓存的命令
+func (c *clusterConn) bind(next clusterCommand) error {
+	if c.conn != nil {
+		return nil
+	}
+
+	slot := -1
+	for _, cmd := range append(c.pending, next) {
+		if key, ok := clusterKey(cmd.name, cmd.args); ok && cmd.name != "" {
+			slot = clusterSlot(key)
+			break
+		}
+	}
+	addr, err := c.p.addr(slot)
+	if err != nil {
+		c.err = err
+		return err
+	}
+
+	c.conn = c.p.nodePool(addr).Get()
+	pending := c.pending
+	c.pending = nil
+	for _, cmd := range pending {
+		if err := c.conn.Send(cmd.name, cmd.args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRedirect 绑定节点之后不再处理重定向，只在后台更新槽位信息
+func (c *clusterConn) checkRedirect(err error) {
+	if kind, _, ok := clusterRedirect(err); ok && kind == "MOVED" {
+		c.p.refreshAsync()
+	}
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestClusterSlot(t *testing.T) {
+	cases := []struct {
+		key  string
+		slot int
+	}{
+		{"123456789", 12739},
+		{"foo", 12182},
+	}
+	for _, tc := range cases {
+		if slot := clusterSlot(tc.key); slot != tc.slot {
+			t.Errorf("clusterSlot(%q) = %d, want %d", tc.key, slot, tc.slot)
+		}
+	}
+
+	// 只使用 tag 计算
+	if clusterSlot("{user1000}.following") != clusterSlot("user1000") ||
+		clusterSlot("{user1000}.followers") != clusterSlot("user1000") {
+		t.Error("keys with the same hash tag should be in the same slot")
+	}
+	if clusterSlot("foo{{bar}}") != clusterSlot("{bar") {
+		t.Error("hash tag should end at the first }")
+	}
+	// 空的 tag 使用整个 key
+	if clusterSlot("foo{}{bar}") == clusterSlot("bar") {
+		t.Error("empty hash tag should use the whole key")
+	}
+}
+
+func TestClusterKey(t *testing.T) {
+	cases := []struct {
+		cmd  string
+		args []interface{}
+		key  string
+		ok   bool
+	}{
+		{"GET", []interface{}{"name"}, "name", true},
+		{"ping", nil, "", false},
+		{"MULTI", nil, "", false},
+		{"EVALSHA", []interface{}{"sha", 1, "k1", "v"}, "k1", true},
+		{"EVAL", []interface{}{"return 1", 0}, "", false},
+		{"XREADGROUP", []interface{}{"GROUP", "g", "c", "COUNT", 1, "STREAMS", "events", ">"}, "events", true},
+		{"XGROUP", []interface{}{"CREATE", "events", "g", "$"}, "events", true},
+		{"SET", []interface{}{[]byte("bytes"), 1}, "bytes", true},
+	}
+	for _, tc := range cases {
+		key, ok := clusterKey(tc.cmd, tc.args)
+		if key != tc.key || ok != tc.ok {
+			t.Errorf("clusterKey(%s, %v) = %q, %v", tc.cmd, tc.args, key, ok)
+		}
+	}
+}
+
+func TestClusterRedirect(t *testing.T) {
+	kind, addr, ok := clusterRedirect(redis.Error("MOVED 3999 127.0.0.1:6381"))
+	if !ok || kind != "MOVED" || addr != "127.0.0.1:6381" {
+		t.Fatalf("MOVED: %s %s %v", kind, addr, ok)
+	}
+	kind, addr, ok = clusterRedirect(redis.Error("ASK 3999 127.0.0.1:6382"))
+	if !ok || kind != "ASK" || addr != "127.0.0.1:6382" {
+		t.Fatalf("ASK: %s %s %v", kind, addr, ok)
+	}
+	if kind, _, ok = clusterRedirect(redis.Error("TRYAGAIN Multiple keys request during rehashing of slot")); !ok || kind != "TRYAGAIN" {
+		t.Fatalf("TRYAGAIN: %s %v", kind, ok)
+	}
+	if _, _, ok = clusterRedirect(redis.Error("ERR unknown command")); ok {
+		t.Fatal("ERR should not be a redirect")
+	}
+	if _, _, ok = clusterRedirect(nil); ok {
+		t.Fatal("nil should not be a redirect")
+	}
+}
+
+func TestParseClusterSlots(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{int64(0), int64(5460), []interface{}{[]byte("10.0.0.1"), int64(7000), []byte("id1")}},
+		[]interface{}{int64(5461), int64(16383), []interface{}{[]byte(""), int64(7001), []byte("id2")}},
+	}
+	slots, err := parseClusterSlots(reply, "10.0.0.2:7000")
+	if err != nil {
+		t.Fatalf("parseClusterSlots failed: %s", err.Error())
+	}
+	if slots[0] != "10.0.0.1:7000" || slots[5460] != "10.0.0.1:7000" {
+		t.Fatalf("slot 0: %s, slot 5460: %s", slots[0], slots[5460])
+	}
+	// host 为空时使用发送命令的节点
+	if slots[16383] != "10.0.0.2:7001" {
+		t.Fatalf("slot 16383: %s", slots[16383])
+	}
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// eg:
+// 使用请求的 context，请求被取消或者超时之后命令立即返回
+// func handler(w http.ResponseWriter, r *http.Request) {
+// 	rc := c.WithContext(r.Context())
+// 	name, err := rc.Get("name")
+// 	...
+// }
+//
+// 单个命令设置超时时间
+// ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+// defer cancel()
+// reply, err := c.DoContext(ctx, "GET", "name")
+//
+// ctx 结束时，正在执行的命令立即返回 ctx.Err()，连接在命令执行完之后才会放回连接池
+// 此时命令可能已经发送到 redis-server，写命令不一定没有执行
+// ctx 设置了截止时间时，截止时间同时作为读取回复的超时时间
+
+// Context ..
+func (c *cache) Context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+// WithContext ..
+func (c *cache) WithContext(ctx context.Context) Cache {
+	if ctx == nil {
+		panic("cache: nil context")
+	}
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// DoContext ..
+func (c *cache) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	return c.process(ctx, cmd, args, func(ctx context.Context) (interface{}, error) {
+		conn := c.pool.Get()
+		if conn == nil {
+			return nil, ErrInvalidConn
+		}
+
+		// 执行结束后，没有错误，没有关闭连接，没有超过 MaxIdle 情况下，activeConn 会放入 idle 队列
+		return runContext(ctx, func() (interface{}, error) {
+			return doContext(ctx, conn, cmd, args...)
+		}, func() {
+			conn.Close()
+		})
+	})
+}
+
+// process 调用钩子并执行 fn，ctx 已经结束时不执行
+func (c *cache) process(ctx context.Context, name string, args []interface{}, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	hooks := c.conf.hooks
+	if len(hooks) == 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return fn(ctx)
+	}
+
+	cmd := &Command{Name: name, Args: args, Start: time.Now()}
+	for _, hook := range hooks {
+		ctx = hook.Before(ctx, cmd)
+	}
+
+	var reply interface{}
+	err := ctx.Err()
+	if err == nil {
+		reply, err = fn(ctx)
+	}
+
+	cmd.Duration = time.Since(cmd.Start)
+	cmd.Err = err
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].After(ctx, cmd)
+	}
+	return reply, err
+}
+
+// runContext 执行 fn，ctx 结束时立即返回 ctx.Err()
+// fn 在后台继续执行，结束之后调用 release 释放连接，因此只能用于独占的连接
+func runContext(ctx context.Context, fn func() (interface{}, error), release func()) (interface{}, error) {
+	if ctx.Done() == nil {
+		defer release()
+		return fn()
+	}
+
+	type result struct {
+		reply interface{}
+		err   error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		defer release()
+		reply, err := fn()
+		ch <- result{reply, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.reply, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// doContext 执行命令，ctx 的截止时间作为读取超时
+func doContext(ctx context.Context, conn redis.Conn, cmd string, args ...interface{}) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return conn.Do(cmd, args...)
+	}
+	cwt, ok := conn.(redis.ConnWithTimeout)
+	if !ok {
+		return conn.Do(cmd, args...)
+	}
+
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+	return cwt.DoWithTimeout(timeout, cmd, args...)
+}
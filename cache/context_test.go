@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alex-my/ghelper/logger"
+)
+
+type hookKey struct{}
+
+// recordHook 记录执行的命令，Before 中向 ctx 写入命令名称
+type recordHook struct {
+	mu   sync.Mutex
+	cmds []Command
+}
+
+func (h *recordHook) Before(ctx context.Context, cmd *Command) context.Context {
+	return context.WithValue(ctx, hookKey{}, cmd.Name)
+}
+
+func (h *recordHook) After(ctx context.Context, cmd *Command) {
+	if ctx.Value(hookKey{}) != cmd.Name {
+		panic("context from Before not passed to After")
+	}
+	h.mu.Lock()
+	h.cmds = append(h.cmds, *cmd)
+	h.mu.Unlock()
+}
+
+func (h *recordHook) last() Command {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.cmds[len(h.cmds)-1]
+}
+
+func TestHook(t *testing.T) {
+	hook := &recordHook{}
+	c := NewCache(WithMemory(), WithHook(hook))
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Set("name", "alex")
+	if cmd := hook.last(); cmd.Name != "SET" || len(cmd.Args) != 2 || cmd.Err != nil || cmd.Start.IsZero() {
+		t.Fatalf("SET: %+v", cmd)
+	}
+
+	c.HGet("name", "field")
+	if cmd := hook.last(); cmd.Name != "HGET" || cmd.Err == nil {
+		t.Fatalf("HGET wrong type: %+v", cmd)
+	}
+
+	p := c.TxPipeline()
+	p.Get("name")
+	p.Incr("count")
+	p.Exec()
+	if cmd := hook.last(); cmd.Name != "MULTI" || len(cmd.Args) != 2 || cmd.Args[1] != "INCR" {
+		t.Fatalf("MULTI: %+v", cmd)
+	}
+
+	c.Watch(func(tx Tx) error {
+		_, err := tx.DO("GET", "count")
+		return err
+	}, "count")
+	if cmd := hook.last(); cmd.Name != "GET" || cmd.Err != nil {
+		t.Fatalf("Tx GET: %+v", cmd)
+	}
+}
+
+func TestContext(t *testing.T) {
+	hook := &recordHook{}
+	c := NewCache(WithMemory(), WithHook(hook))
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.Context() != context.Background() {
+		t.Fatal("default context should be Background")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cc := c.WithContext(ctx)
+	if cc.Context() != ctx || c.Context() != context.Background() {
+		t.Fatal("WithContext should not modify the original cache")
+	}
+	if err := cc.Set("name", "alex"); err != nil {
+		t.Fatal(err)
+	}
+
+	// ctx 结束之后不再执行命令
+	cancel()
+	if _, err := cc.Get("name"); err != context.Canceled {
+		t.Fatalf("Get with canceled context, err: %v", err)
+	}
+	if cmd := hook.last(); cmd.Name != "GET" || cmd.Err != context.Canceled {
+		t.Fatalf("hook with canceled context: %+v", cmd)
+	}
+
+	p := cc.Pipeline()
+	name := p.Get("name")
+	if err := p.Exec(); err != context.Canceled || name.Err() != context.Canceled {
+		t.Fatalf("pipeline with canceled context, err: %v", err)
+	}
+	if err := cc.Watch(func(tx Tx) error { return nil }, "name"); err != context.Canceled {
+		t.Fatalf("Watch with canceled context, err: %v", err)
+	}
+
+	// 阻塞命令在 ctx 超时之后立即返回
+	c.XGroupCreate("stream", "group", "$")
+	block := []interface{}{"GROUP", "group", "consumer", "BLOCK", 1000, "STREAMS", "stream", ">"}
+	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.DoContext(ctx, "XREADGROUP", block...); err != context.DeadlineExceeded {
+		t.Fatalf("XREADGROUP with timeout, err: %v", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("XREADGROUP returned after %s", d)
+	}
+
+	p = c.WithContext(ctx).Pipeline()
+	p.Do("XREADGROUP", block...)
+	if err := p.Exec(); err != context.DeadlineExceeded {
+		t.Fatalf("pipeline with timeout, err: %v", err)
+	}
+}
+
+func TestLogHook(t *testing.T) {
+	var buff bytes.Buffer
+	log := logger.NewWriterLogger(&buff)
+	log.SetLevel(logger.DEBUG)
+	c := NewCache(WithMemory(), WithHook(NewLogHook(log, 0)))
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// 默认不记录值以及密码
+	c.Set("user:1", "secret-value")
+	c.DO("AUTH", "secret-password")
+	out := buff.String()
+	if !strings.Contains(out, "cache: SET user:1 ..., duration") || !strings.Contains(out, "cache: AUTH, duration") ||
+		strings.Contains(out, "secret") {
+		t.Fatalf("output: %s", out)
+	}
+
+	buff.Reset()
+	c = NewCache(WithMemory(), WithHook(NewLogHook(log, 0, LogHookArgs())))
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.Set("user:1", "secret-value")
+	if out := buff.String(); !strings.Contains(out, "cache: SET [user:1 secret-value]") {
+		t.Fatalf("output with args: %s", out)
+	}
+}
@@ -312,7 +312,7 @@ func testSortedSet(c cache.Cache) error {
 		return errors.New("testSortedSet error 1")
 	}
 
-	n3, _ := c.ZCount(key, 80, 100)
+	n3, _ := c.ZCount(key, "80", "100")
 	if n3 != 2 {
 		return errors.New("testSortedSet error 2")
 	}
@@ -43,7 +43,7 @@ func (c *cache) HGetAll(key string) ([]string, error) {
 
 // HExists 查看哈希表 key 中，给定域 field 是否存在
 func (c *cache) HExists(key, field string) (bool, error) {
-	return redis.Bool(c.DO("HEXISTS", key))
+	return redis.Bool(c.DO("HEXISTS", key, field))
 }
 
 // HDel 删除哈希表 key 中的一个或多个指定域，不存在的域将被忽略
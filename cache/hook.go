@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alex-my/ghelper/logger"
+)
+
+// eg:
+// 记录出错以及超过 100ms 的命令
+// c := cache.NewCache(cache.WithHook(cache.NewLogHook(log, 100*time.Millisecond)))
+//
+// 默认只记录命令名称以及第一个 key，例如 SET user:1 ...，需要记录全部参数时 (可能包含密码等敏感数据)
+// c := cache.NewCache(cache.WithHook(cache.NewLogHook(log, 100*time.Millisecond, cache.LogHookArgs())))
+//
+// 统计命令的耗时
+// c := cache.NewCache(cache.WithHook(cache.HookFunc(func(ctx context.Context, cmd *cache.Command) {
+// 	histogram.WithLabelValues(cmd.Name).Observe(cmd.Duration.Seconds())
+// })))
+
+// Command 执行的命令
+// 管道的 Name 为 PIPELINE，事务管道的 Name 为 MULTI，Args 为管道中各个命令的名称
+type Command struct {
+	Name string
+	Args []interface{}
+	// Start 开始执行的时间
+	Start time.Time
+	// Duration, Err 执行的耗时以及错误，只在 After 中有效
+	Duration time.Duration
+	Err      error
+}
+
+// Hook 钩子，在执行命令前后调用
+// 通过 DO, DoContext 以及各个命令方法执行的命令，管道 (Exec)，事务中的 Tx.DO 都会调用钩子
+// 订阅以及通过 Conn() 获取的连接不会调用钩子
+type Hook interface {
+	// Before 执行命令之前调用，返回的 context 会用于执行命令以及传递给 After，例如用于链路追踪
+	Before(ctx context.Context, cmd *Command) context.Context
+	// After 执行命令之后调用
+	After(ctx context.Context, cmd *Command)
+}
+
+// HookFunc 只在执行命令之后调用的钩子，例如用于统计
+type HookFunc func(ctx context.Context, cmd *Command)
+
+// Before ..
+func (f HookFunc) Before(ctx context.Context, cmd *Command) context.Context {
+	return ctx
+}
+
+// After ..
+func (f HookFunc) After(ctx context.Context, cmd *Command) {
+	f(ctx, cmd)
+}
+
+// logHookConfig NewLogHook 的参数
+type logHookConfig struct {
+	args bool
+}
+
+// LogHookOption ..
+type LogHookOption func(*logHookConfig)
+
+// LogHookArgs 记录命令的全部参数，参数中可能包含值、密码等敏感数据
+func LogHookArgs() LogHookOption {
+	return func(c *logHookConfig) {
+		c.args = true
+	}
+}
+
+// NewLogHook 记录日志的钩子
+// 命令出错时使用 Error 记录，耗时超过 slow 时使用 Warn 记录，slow 为 0 时不记录慢命令
+// 其它命令在开启 DEBUG 时使用 Debug 记录
+// 默认只记录命令名称以及第一个 key，见 LogHookArgs
+func NewLogHook(l logger.Logger, slow time.Duration, opts ...LogHookOption) Hook {
+	conf := &logHookConfig{}
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return HookFunc(func(ctx context.Context, cmd *Command) {
+		switch {
+		case cmd.Err != nil:
+			l.Errorf("cache: %s failed, duration: %s, err: %s", conf.describe(cmd), cmd.Duration, cmd.Err.Error())
+		case slow > 0 && cmd.Duration >= slow:
+			l.Warnf("cache: %s slow, duration: %s", conf.describe(cmd), cmd.Duration)
+		case l.IsDebugAble():
+			l.Debugf("cache: %s, duration: %s", conf.describe(cmd), cmd.Duration)
+		}
+	})
+}
+
+// describe 日志中的命令
+// 管道以及事务的参数为命令名称，全部记录，AUTH, HELLO 的参数为密码等，不记录
+func (c *logHookConfig) describe(cmd *Command) string {
+	if c.args || cmd.Name == "PIPELINE" || cmd.Name == "MULTI" {
+		return fmt.Sprintf("%s %v", cmd.Name, cmd.Args)
+	}
+	switch strings.ToUpper(cmd.Name) {
+	case "AUTH", "HELLO":
+		return cmd.Name
+	}
+	switch len(cmd.Args) {
+	case 0:
+		return cmd.Name
+	case 1:
+		return fmt.Sprintf("%s %v", cmd.Name, cmd.Args[0])
+	}
+	return fmt.Sprintf("%s %v ...", cmd.Name, cmd.Args[0])
+}
@@ -0,0 +1,158 @@
+package loader
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/alex-my/ghelper/cache"
+	"github.com/alex-my/ghelper/codec"
+)
+
+// eg:
+// 读取用户，缓存中不存在时从数据库加载，并写入缓存
+// l := loader.New(c, codec.NewJSONCodec(),
+// 	loader.WithPrefix("user:"),
+// 	loader.WithTTL(10*time.Minute),
+// 	loader.WithNegativeTTL(time.Minute),
+// 	loader.WithJitter(0.1))
+//
+// var user User
+// err := l.Get("1001", &user, func(key string) (interface{}, error) {
+// 	user, err := db.FindUser(key)
+// 	if err == sql.ErrNoRows {
+// 		return nil, loader.ErrNotFound
+// 	}
+// 	return user, err
+// })
+
+var (
+	// ErrNotFound 数据不存在，加载函数返回该错误时，如果开启了 WithNegativeTTL 会缓存不存在的结果
+	ErrNotFound = errors.New("not found")
+)
+
+// negativeValue 表示数据不存在的缓存值
+var negativeValue = []byte("\x00loader:not-found\x00")
+
+// LoadFunc 加载数据，key 不包括前缀，数据不存在时返回 ErrNotFound
+type LoadFunc func(key string) (interface{}, error)
+
+// Loader 缓存加载器
+type Loader interface {
+	// Get 读取缓存并解码到 out，out 必须为指针
+	// 缓存不存在时调用 load 加载并写入缓存，同一个 key 同时只有一个加载，其它调用者共享结果
+	// redis 出错时直接调用 load，不写入缓存
+	Get(key string, out interface{}, load LoadFunc) error
+
+	// Set 编码之后写入缓存
+	Set(key string, value interface{}) error
+
+	// Delete 删除缓存，数据更新之后调用
+	Delete(keys ...string) error
+}
+
+type loader struct {
+	c     cache.Cache
+	codec codec.Codec
+	conf  *config
+	group group
+}
+
+// New 创建缓存加载器，cd 用于编码和解码缓存的值
+func New(c cache.Cache, cd codec.Codec, opts ...Option) Loader {
+	conf := defaultConfig()
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return &loader{c: c, codec: cd, conf: conf}
+}
+
+// Get ..
+func (l *loader) Get(key string, out interface{}, load LoadFunc) error {
+	data, err := l.c.Bytes(l.c.DO("GET", l.conf.prefix+key))
+	if err == nil {
+		return l.decode(data, out)
+	}
+
+	write := err == cache.ErrNil
+	data, err = l.group.do(key, func() ([]byte, error) {
+		return l.load(key, load, write)
+	})
+	if err != nil {
+		return err
+	}
+	return l.decode(data, out)
+}
+
+// Set ..
+func (l *loader) Set(key string, value interface{}) error {
+	data, err := l.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	return l.set(key, data, l.conf.ttl)
+}
+
+// Delete ..
+func (l *loader) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = l.conf.prefix + key
+	}
+	_, err := l.c.Del(args...)
+	return err
+}
+
+// load 调用加载函数，write 为 true 时将结果写入缓存
+func (l *loader) load(key string, load LoadFunc, write bool) ([]byte, error) {
+	value, err := load(key)
+	if err == ErrNotFound {
+		if write && l.conf.negativeTTL > 0 {
+			l.set(key, negativeValue, l.conf.negativeTTL)
+		}
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := l.codec.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	if write {
+		// 写入失败不影响本次读取
+		l.set(key, data, l.conf.ttl)
+	}
+	return data, nil
+}
+
+func (l *loader) set(key string, data []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return l.c.Set(l.conf.prefix+key, data)
+	}
+	ttl = l.jitter(ttl)
+	ms := strconv.FormatInt(int64(ttl/time.Millisecond), 10)
+	return l.c.PSetEx(l.conf.prefix+key, data, ms)
+}
+
+// jitter 随机增加 [0, jitter * ttl)
+func (l *loader) jitter(ttl time.Duration) time.Duration {
+	max := int64(float64(ttl) * l.conf.jitter)
+	if max <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Int63n(max))
+}
+
+func (l *loader) decode(data []byte, out interface{}) error {
+	if bytes.Equal(data, negativeValue) {
+		return ErrNotFound
+	}
+	return l.codec.Decode(data, out)
+}
@@ -0,0 +1,138 @@
+package loader
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alex-my/ghelper/cache"
+	"github.com/alex-my/ghelper/codec"
+)
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func newCache(t *testing.T) cache.Cache {
+	c := cache.NewCache(cache.WithMemory())
+	if err := c.Open(); err != nil {
+		t.Fatalf("open memory cache failed: %s", err.Error())
+	}
+	return c
+}
+
+func TestGet(t *testing.T) {
+	c := newCache(t)
+	defer c.Close()
+
+	l := New(c, codec.NewJSONCodec(), WithPrefix("user:"))
+
+	var loads int32
+	load := func(key string) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		time.Sleep(20 * time.Millisecond)
+		return &user{ID: key, Name: "alex"}, nil
+	}
+
+	// 并发读取同一个 key，只加载一次
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var u user
+			if err := l.Get("1", &u, load); err != nil || u.Name != "alex" {
+				t.Errorf("Get: %+v, err: %v", u, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if loads != 1 {
+		t.Fatalf("load count: %d", loads)
+	}
+
+	// 已经写入缓存
+	if v, _ := c.Get("user:1"); v != `{"id":"1","name":"alex"}` {
+		t.Fatalf("cached value: %s", v)
+	}
+	var u user
+	l.Get("1", &u, load)
+	if loads != 1 {
+		t.Fatalf("load count after cached: %d", loads)
+	}
+
+	// 删除之后重新加载
+	l.Delete("1")
+	l.Get("1", &u, load)
+	if loads != 2 {
+		t.Fatalf("load count after Delete: %d", loads)
+	}
+
+	// 加载出错不写入缓存
+	errLoad := errors.New("db error")
+	if err := l.Get("2", &u, func(string) (interface{}, error) { return nil, errLoad }); err != errLoad {
+		t.Fatalf("Get with load error, err: %v", err)
+	}
+	if exist, _ := c.Exists("user:2"); exist {
+		t.Fatal("load error should not be cached")
+	}
+}
+
+func TestNegative(t *testing.T) {
+	c := newCache(t)
+	defer c.Close()
+
+	var loads int32
+	load := func(key string) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		return nil, ErrNotFound
+	}
+
+	// 没有开启负缓存
+	l := New(c, codec.NewJSONCodec())
+	var u user
+	l.Get("none", &u, load)
+	l.Get("none", &u, load)
+	if loads != 2 {
+		t.Fatalf("load count without negative cache: %d", loads)
+	}
+
+	l = New(c, codec.NewJSONCodec(), WithNegativeTTL(30*time.Millisecond))
+	for i := 0; i < 3; i++ {
+		if err := l.Get("none", &u, load); err != ErrNotFound {
+			t.Fatalf("Get missing, err: %v", err)
+		}
+	}
+	if loads != 3 {
+		t.Fatalf("load count with negative cache: %d", loads)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	l.Get("none", &u, load)
+	if loads != 4 {
+		t.Fatalf("load count after negative cache expired: %d", loads)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	c := newCache(t)
+	defer c.Close()
+
+	l := New(c, codec.NewJSONCodec(), WithTTL(time.Second), WithJitter(0.5))
+	for i := 0; i < 10; i++ {
+		l.Set("k", &user{ID: "1"})
+		ttl, _ := c.PTTL("k")
+		if ttl <= 0 || ttl > 1500 {
+			t.Fatalf("ttl with jitter: %d", ttl)
+		}
+	}
+
+	l = New(c, codec.NewJSONCodec(), WithTTL(0))
+	l.Set("k", &user{ID: "1"})
+	if ttl, _ := c.TTL("k"); ttl != -1 {
+		t.Fatalf("ttl without expire: %d", ttl)
+	}
+}
@@ -0,0 +1,59 @@
+package loader
+
+import "time"
+
+// config 配置
+type config struct {
+	// prefix key 的前缀
+	prefix string
+	// ttl 缓存的生存时间，0 表示永久
+	ttl time.Duration
+	// negativeTTL 数据不存在时缓存的生存时间，0 表示不缓存
+	negativeTTL time.Duration
+	// jitter 生存时间随机增加 [0, jitter * ttl)，避免大量 key 同时过期
+	jitter float64
+}
+
+func defaultConfig() *config {
+	return &config{
+		ttl: 10 * time.Minute,
+	}
+}
+
+// Option ..
+type Option func(*config)
+
+// WithPrefix key 的前缀，例如 user:
+func WithPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+	}
+}
+
+// WithTTL 缓存的生存时间，默认为 10 分钟，0 表示永久
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		if ttl >= 0 {
+			c.ttl = ttl
+		}
+	}
+}
+
+// WithNegativeTTL 加载函数返回 ErrNotFound 时，缓存不存在的结果，避免缓存穿透
+// 默认为 0，表示不缓存
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		if ttl >= 0 {
+			c.negativeTTL = ttl
+		}
+	}
+}
+
+// WithJitter 生存时间随机增加 [0, jitter * ttl)，例如 0.1 表示最多增加 10%
+func WithJitter(jitter float64) Option {
+	return func(c *config) {
+		if jitter >= 0 {
+			c.jitter = jitter
+		}
+	}
+}
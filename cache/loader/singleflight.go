@@ -0,0 +1,54 @@
+package loader
+
+import (
+	"errors"
+	"sync"
+)
+
+// errLoadPanic 加载函数 panic 时，等待的调用者得到该错误
+var errLoadPanic = errors.New("loader: load function panicked")
+
+// call 正在进行的加载
+type call struct {
+	wg   sync.WaitGroup
+	data []byte
+	err  error
+}
+
+// group 同一个 key 同时只有一个加载，其它调用者等待并共享结果
+type group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+func (g *group) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, exist := g.calls[key]; exist {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.data, c.err
+	}
+
+	c := &call{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	completed := false
+	defer func() {
+		if !completed {
+			c.err = errLoadPanic
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	c.data, c.err = fn()
+	completed = true
+	return c.data, c.err
+}
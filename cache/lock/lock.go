@@ -0,0 +1,239 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alex-my/ghelper/cache"
+	"github.com/alex-my/ghelper/random"
+)
+
+// eg:
+// 分布式锁，多个副本中只有一个能执行定时任务
+// l := lock.New(c, "lock:cron:report", lock.WithTTL(30*time.Second), lock.WithWatchdog(0))
+// if err := l.TryLock(); err != nil {
+// 	return
+// }
+// defer l.Unlock()
+//
+// 阻塞获取，直到成功或 ctx 结束
+// ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// defer cancel()
+// if err := l.Lock(ctx); err != nil {
+// 	return
+// }
+// defer l.Unlock()
+
+var (
+	// ErrNotObtained 锁已经被其它持有者获取
+	ErrNotObtained = errors.New("lock not obtained")
+	// ErrNotHeld 锁不存在或者已经被其它持有者获取
+	ErrNotHeld = errors.New("lock not held")
+)
+
+// casRetries 解锁、续期时 key 被并发修改的重试次数
+const casRetries = 3
+
+// Lock 基于 cache.Cache 的分布式锁
+// 每次加锁生成唯一 token，只有持有 token 的 Lock 可以解锁、续期
+// 同一个 Lock 不可重入，不要在多个 goroutine 中同时使用
+type Lock struct {
+	c    cache.Cache
+	key  string
+	conf *config
+
+	mu    sync.Mutex
+	token string
+	stop  chan struct{}
+	lost  chan struct{}
+}
+
+// New 创建分布式锁，key 为锁在 redis 中的键
+func New(c cache.Cache, key string, opts ...Option) *Lock {
+	conf := defaultConfig()
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return &Lock{c: c, key: key, conf: conf}
+}
+
+// Key ..
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token 当前持有的 token，没有持有锁时为空
+func (l *Lock) Token() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.token
+}
+
+// Lost 开启自动续期时，续期发现锁已丢失 (例如 redis 中的 key 被删除) 会关闭该通道
+// 没有持有锁时返回 nil
+func (l *Lock) Lost() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lost
+}
+
+// TryLock 尝试获取锁，锁已被获取时返回 ErrNotObtained，包括被自己获取
+func (l *Lock) TryLock() error {
+	token := random.NewUUID()
+	reply, err := l.c.DO("SET", l.key, token, "PX", ms(l.conf.ttl), "NX")
+	if err != nil {
+		return err
+	}
+	if reply == nil {
+		return ErrNotObtained
+	}
+
+	l.mu.Lock()
+	// 之前获取的锁已经过期，停止之前的续期
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+	l.token = token
+	l.lost = make(chan struct{})
+	if l.conf.watchdog {
+		l.stop = make(chan struct{})
+		go l.watchdog(token, l.stop, l.lost)
+	}
+	l.mu.Unlock()
+	return nil
+}
+
+// Lock 阻塞获取锁，获取失败时按照指数退避重试，直到成功或者 ctx 结束
+func (l *Lock) Lock(ctx context.Context) error {
+	backoff := l.conf.minBackoff
+	for {
+		err := l.TryLock()
+		if err != ErrNotObtained {
+			return err
+		}
+
+		// 加入随机抖动，避免多个等待者同时重试
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff)/2+1))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if backoff *= 2; backoff > l.conf.maxBackoff {
+			backoff = l.conf.maxBackoff
+		}
+	}
+}
+
+// Unlock 释放锁，只有 token 与 redis 中一致时才会删除，锁已过期或被他人持有时返回 ErrNotHeld
+func (l *Lock) Unlock() error {
+	l.mu.Lock()
+	token := l.token
+	l.token = ""
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+	l.mu.Unlock()
+
+	if token == "" {
+		return ErrNotHeld
+	}
+	return l.compareAndDo(token, func(p cache.Pipeline) {
+		p.Del(l.key)
+	})
+}
+
+// Refresh 将锁的生存时间重置为 ttl，ttl <= 0 时使用配置的 ttl
+func (l *Lock) Refresh(ttl time.Duration) error {
+	token := l.Token()
+	if token == "" {
+		return ErrNotHeld
+	}
+	return l.refresh(token, ttl)
+}
+
+// TTL 锁的剩余生存时间，锁不存在或者已被他人持有时返回 ErrNotHeld
+func (l *Lock) TTL() (time.Duration, error) {
+	token := l.Token()
+	if token == "" {
+		return 0, ErrNotHeld
+	}
+
+	var ttl time.Duration
+	err := l.c.Watch(func(tx cache.Tx) error {
+		if err := l.owned(tx, token); err != nil {
+			return err
+		}
+		n, err := l.c.Int64(tx.DO("PTTL", l.key))
+		if err != nil {
+			return err
+		}
+		ttl = time.Duration(n) * time.Millisecond
+		return nil
+	}, l.key)
+	return ttl, err
+}
+
+func (l *Lock) refresh(token string, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = l.conf.ttl
+	}
+	return l.compareAndDo(token, func(p cache.Pipeline) {
+		p.PExpire(l.key, ms(ttl))
+	})
+}
+
+// compareAndDo 当 key 的值为 token 时，在事务中执行 fn 提交的命令
+func (l *Lock) compareAndDo(token string, fn func(p cache.Pipeline)) error {
+	return l.c.WatchRetry(casRetries, func(tx cache.Tx) error {
+		if err := l.owned(tx, token); err != nil {
+			return err
+		}
+		p := tx.Pipeline()
+		fn(p)
+		return p.Exec()
+	}, l.key)
+}
+
+func (l *Lock) owned(tx cache.Tx, token string) error {
+	v, err := l.c.String(tx.DO("GET", l.key))
+	if err == cache.ErrNil || (err == nil && v != token) {
+		return ErrNotHeld
+	}
+	return err
+}
+
+func (l *Lock) watchdog(token string, stop, lost chan struct{}) {
+	interval := l.conf.renewInterval
+	if interval <= 0 {
+		interval = l.conf.ttl / 3
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			// 网络错误时等待下一次续期，锁确定丢失时才停止
+			if err := l.refresh(token, 0); err == ErrNotHeld {
+				close(lost)
+				return
+			}
+		}
+	}
+}
+
+func ms(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Millisecond), 10)
+}
@@ -0,0 +1,176 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alex-my/ghelper/cache"
+)
+
+func newCache(t *testing.T) cache.Cache {
+	c := cache.NewCache(cache.WithMemory())
+	if err := c.Open(); err != nil {
+		t.Fatalf("open memory cache failed: %s", err.Error())
+	}
+	return c
+}
+
+func TestTryLock(t *testing.T) {
+	c := newCache(t)
+	defer c.Close()
+
+	l1 := New(c, "lock:test")
+	l2 := New(c, "lock:test")
+
+	if err := l1.TryLock(); err != nil {
+		t.Fatalf("TryLock failed: %s", err.Error())
+	}
+	if err := l2.TryLock(); err != ErrNotObtained {
+		t.Fatalf("TryLock on held lock, err: %v", err)
+	}
+
+	// 不持有锁，不能解锁
+	if err := l2.Unlock(); err != ErrNotHeld {
+		t.Fatalf("Unlock without lock, err: %v", err)
+	}
+	if ttl, err := l1.TTL(); err != nil || ttl <= 0 {
+		t.Fatalf("TTL: %v, err: %v", ttl, err)
+	}
+
+	if err := l1.Unlock(); err != nil {
+		t.Fatalf("Unlock failed: %s", err.Error())
+	}
+	if err := l2.TryLock(); err != nil {
+		t.Fatalf("TryLock after Unlock failed: %s", err.Error())
+	}
+
+	// 锁已被他人持有，不会误删
+	if err := l1.Unlock(); err != ErrNotHeld {
+		t.Fatalf("Unlock twice, err: %v", err)
+	}
+	if v, _ := c.Get("lock:test"); v != l2.Token() {
+		t.Fatalf("lock should be held by l2, value: %s", v)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	c := newCache(t)
+	defer c.Close()
+
+	l1 := New(c, "lock:test", WithTTL(30*time.Millisecond))
+	l2 := New(c, "lock:test")
+
+	l1.TryLock()
+	time.Sleep(50 * time.Millisecond)
+
+	if err := l2.TryLock(); err != nil {
+		t.Fatalf("TryLock after expired failed: %s", err.Error())
+	}
+	if err := l1.Refresh(0); err != ErrNotHeld {
+		t.Fatalf("Refresh expired lock, err: %v", err)
+	}
+	if err := l1.Unlock(); err != ErrNotHeld {
+		t.Fatalf("Unlock expired lock, err: %v", err)
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	c := newCache(t)
+	defer c.Close()
+
+	l := New(c, "lock:test", WithTTL(30*time.Millisecond), WithWatchdog(0))
+	if err := l.TryLock(); err != nil {
+		t.Fatalf("TryLock failed: %s", err.Error())
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if v, _ := c.Get("lock:test"); v != l.Token() {
+		t.Fatal("lock should be renewed by watchdog")
+	}
+
+	// 不可重入
+	if err := l.TryLock(); err != ErrNotObtained {
+		t.Fatalf("TryLock twice, err: %v", err)
+	}
+
+	// 锁被删除，续期时发现丢失
+	c.Del("lock:test")
+	select {
+	case <-l.Lost():
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("watchdog should report lost lock")
+	}
+
+	// 重新获取时停止之前的续期，只有新的 token 续期
+	if err := l.TryLock(); err != nil {
+		t.Fatalf("TryLock after lost failed: %s", err.Error())
+	}
+	first := l.Token()
+	c.Del("lock:test")
+	if err := l.TryLock(); err != nil {
+		t.Fatalf("TryLock again failed: %s", err.Error())
+	}
+	lost := l.Lost()
+	time.Sleep(100 * time.Millisecond)
+	if v, _ := c.Get("lock:test"); v != l.Token() || v == first {
+		t.Fatalf("lock should be renewed with new token, value: %s", v)
+	}
+	select {
+	case <-lost:
+		t.Fatal("new lock should not be lost")
+	default:
+	}
+	l.Unlock()
+}
+
+func TestLock(t *testing.T) {
+	c := newCache(t)
+	defer c.Close()
+
+	holder := New(c, "lock:test")
+	holder.TryLock()
+
+	// 锁一直被持有，ctx 超时
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := New(c, "lock:test").Lock(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Lock should timeout, err: %v", err)
+	}
+	holder.Unlock()
+
+	// 多个等待者互斥访问
+	var (
+		wg      sync.WaitGroup
+		running int
+		mu      sync.Mutex
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l := New(c, "lock:test", WithBackoff(time.Millisecond, 5*time.Millisecond))
+			if err := l.Lock(context.Background()); err != nil {
+				t.Errorf("Lock failed: %s", err.Error())
+				return
+			}
+			mu.Lock()
+			running++
+			if running > 1 {
+				t.Error("more than one holder")
+			}
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			if err := l.Unlock(); err != nil {
+				t.Errorf("Unlock failed: %s", err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,58 @@
+package lock
+
+import "time"
+
+// config 配置
+type config struct {
+	// ttl 锁的生存时间
+	ttl time.Duration
+	// watchdog true: 持有锁期间自动续期
+	watchdog bool
+	// renewInterval 自动续期的间隔，默认为 ttl 的三分之一
+	renewInterval time.Duration
+	// minBackoff, maxBackoff 阻塞获取锁时，重试间隔从 minBackoff 开始翻倍，最大为 maxBackoff
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+func defaultConfig() *config {
+	return &config{
+		ttl:        10 * time.Second,
+		minBackoff: 10 * time.Millisecond,
+		maxBackoff: 500 * time.Millisecond,
+	}
+}
+
+// Option ..
+type Option func(*config)
+
+// WithTTL 锁的生存时间，默认为 10 秒
+// 没有开启自动续期时，持有锁超过 ttl 之后锁会自动释放
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		if ttl > 0 {
+			c.ttl = ttl
+		}
+	}
+}
+
+// WithWatchdog 持有锁期间，每隔 interval 将锁的生存时间重置为 ttl
+// interval <= 0 时使用 ttl 的三分之一
+func WithWatchdog(interval time.Duration) Option {
+	return func(c *config) {
+		c.watchdog = true
+		c.renewInterval = interval
+	}
+}
+
+// WithBackoff 阻塞获取锁时的重试间隔
+func WithBackoff(min, max time.Duration) Option {
+	return func(c *config) {
+		if min > 0 {
+			c.minBackoff = min
+		}
+		if max >= c.minBackoff {
+			c.maxBackoff = max
+		}
+	}
+}
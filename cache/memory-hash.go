@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+func memoryHGet(db *memoryDB, args []string) interface{} {
+	hash, err := db.hash(args[0], false)
+	if err != nil {
+		return err
+	}
+	value, exist := hash[args[1]]
+	if !exist {
+		return nil
+	}
+	return []byte(value)
+}
+
+// memoryHSet HSET key field value [field value ...]
+func memoryHSet(db *memoryDB, args []string) interface{} {
+	if len(args)%2 == 0 {
+		return memoryArityError("hset")
+	}
+	hash, err := db.hash(args[0], true)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for i := 1; i < len(args); i += 2 {
+		if _, exist := hash[args[i]]; !exist {
+			n++
+		}
+		hash[args[i]] = args[i+1]
+	}
+	return n
+}
+
+func memoryHSetNX(db *memoryDB, args []string) interface{} {
+	hash, err := db.hash(args[0], true)
+	if err != nil {
+		return err
+	}
+	if _, exist := hash[args[1]]; exist {
+		return int64(0)
+	}
+	hash[args[1]] = args[2]
+	return int64(1)
+}
+
+func memoryHMSet(db *memoryDB, args []string) interface{} {
+	if len(args)%2 == 0 {
+		return memoryArityError("hmset")
+	}
+	if reply := memoryHSet(db, args); isMemoryError(reply) {
+		return reply
+	}
+	return "OK"
+}
+
+func memoryHMGet(db *memoryDB, args []string) interface{} {
+	hash, err := db.hash(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	reply := make([]interface{}, len(args)-1)
+	for i, field := range args[1:] {
+		if value, exist := hash[field]; exist {
+			reply[i] = []byte(value)
+		}
+	}
+	return reply
+}
+
+// memoryHashFields 返回排序后的域，保证结果稳定
+func memoryHashFields(hash memoryHashValue) []string {
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func memoryHGetAll(db *memoryDB, args []string) interface{} {
+	hash, err := db.hash(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	reply := make([]interface{}, 0, len(hash)*2)
+	for _, field := range memoryHashFields(hash) {
+		reply = append(reply, []byte(field), []byte(hash[field]))
+	}
+	return reply
+}
+
+func memoryHExists(db *memoryDB, args []string) interface{} {
+	hash, err := db.hash(args[0], false)
+	if err != nil {
+		return err
+	}
+	_, exist := hash[args[1]]
+	return memoryBool(exist)
+}
+
+func memoryHDel(db *memoryDB, args []string) interface{} {
+	hash, err := db.hash(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for _, field := range args[1:] {
+		if _, exist := hash[field]; exist {
+			delete(hash, field)
+			n++
+		}
+	}
+	db.removeEmpty(args[0])
+	return n
+}
+
+func memoryHLen(db *memoryDB, args []string) interface{} {
+	hash, err := db.hash(args[0], false)
+	if err != nil {
+		return err
+	}
+	return int64(len(hash))
+}
+
+func memoryHKeys(db *memoryDB, args []string) interface{} {
+	hash, err := db.hash(args[0], false)
+	if err != nil {
+		return err
+	}
+	return memoryBulks(memoryHashFields(hash))
+}
+
+func memoryHVals(db *memoryDB, args []string) interface{} {
+	hash, err := db.hash(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	reply := make([]interface{}, 0, len(hash))
+	for _, field := range memoryHashFields(hash) {
+		reply = append(reply, []byte(hash[field]))
+	}
+	return reply
+}
+
+func memoryHIncrby(db *memoryDB, args []string) interface{} {
+	increment, err := memoryParseInt(args[2])
+	if err != nil {
+		return err
+	}
+	hash, err := db.hash(args[0], true)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	if value, exist := hash[args[1]]; exist {
+		if n, err = memoryParseInt(value); err != nil {
+			return memoryError("ERR hash value is not an integer")
+		}
+	}
+	if (increment > 0 && n > math.MaxInt64-increment) || (increment < 0 && n < math.MinInt64-increment) {
+		return memoryError("ERR increment or decrement would overflow")
+	}
+	n += increment
+	hash[args[1]] = strconv.FormatInt(n, 10)
+	return n
+}
+
+func memoryHIncrbyFloat(db *memoryDB, args []string) interface{} {
+	increment, err := memoryParseFloat(args[2])
+	if err != nil {
+		return err
+	}
+	hash, err := db.hash(args[0], true)
+	if err != nil {
+		return err
+	}
+
+	var f float64
+	if value, exist := hash[args[1]]; exist {
+		if f, err = memoryParseFloat(value); err != nil {
+			return memoryError("ERR hash value is not a float")
+		}
+	}
+	f += increment
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return memoryError("ERR increment would produce NaN or Infinity")
+	}
+	value := memoryFormatFloat(f)
+	hash[args[1]] = value
+	return []byte(value)
+}
@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+func memoryDel(db *memoryDB, args []string) interface{} {
+	var n int64
+	for _, key := range args {
+		if db.lookup(key) != nil {
+			delete(db.items, key)
+			n++
+		}
+	}
+	return n
+}
+
+func memoryExists(db *memoryDB, args []string) interface{} {
+	var n int64
+	for _, key := range args {
+		if db.lookup(key) != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// memoryExpireAtTime 设置过期时间，过期时间早于当前时间则直接删除
+func memoryExpireAtTime(db *memoryDB, key string, t time.Time) interface{} {
+	item := db.lookup(key)
+	if item == nil {
+		return int64(0)
+	}
+	if !t.After(time.Now()) {
+		delete(db.items, key)
+		return int64(1)
+	}
+	item.expireAt = t
+	return int64(1)
+}
+
+func memoryExpire(db *memoryDB, args []string) interface{} {
+	seconds, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	return memoryExpireAtTime(db, args[0], time.Now().Add(time.Duration(seconds)*time.Second))
+}
+
+func memoryExpireAt(db *memoryDB, args []string) interface{} {
+	t, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	return memoryExpireAtTime(db, args[0], time.Unix(t, 0))
+}
+
+func memoryPExpire(db *memoryDB, args []string) interface{} {
+	milliseconds, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	return memoryExpireAtTime(db, args[0], time.Now().Add(time.Duration(milliseconds)*time.Millisecond))
+}
+
+func memoryPExpireAt(db *memoryDB, args []string) interface{} {
+	t, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	return memoryExpireAtTime(db, args[0], time.Unix(0, t*int64(time.Millisecond)))
+}
+
+// memoryTTLDuration 返回剩余生存时间，-2 表示 key 不存在，-1 表示永久
+func memoryTTLDuration(db *memoryDB, key string) (time.Duration, bool) {
+	item := db.lookup(key)
+	if item == nil {
+		return -2, false
+	}
+	if item.expireAt.IsZero() {
+		return -1, false
+	}
+	return time.Until(item.expireAt), true
+}
+
+func memoryTTL(db *memoryDB, args []string) interface{} {
+	d, ok := memoryTTLDuration(db, args[0])
+	if !ok {
+		return int64(d)
+	}
+	return int64((d + 500*time.Millisecond) / time.Second)
+}
+
+func memoryPTTL(db *memoryDB, args []string) interface{} {
+	d, ok := memoryTTLDuration(db, args[0])
+	if !ok {
+		return int64(d)
+	}
+	return int64(d / time.Millisecond)
+}
+
+func memoryPersist(db *memoryDB, args []string) interface{} {
+	item := db.lookup(args[0])
+	if item == nil || item.expireAt.IsZero() {
+		return int64(0)
+	}
+	item.expireAt = time.Time{}
+	return int64(1)
+}
+
+func memoryType(db *memoryDB, args []string) interface{} {
+	item := db.lookup(args[0])
+	if item == nil {
+		return "none"
+	}
+	switch item.value.(type) {
+	case string:
+		return "string"
+	case memoryHashValue:
+		return "hash"
+	case *memoryListValue:
+		return "list"
+	case memorySetValue:
+		return "set"
+	case memorySortedSetValue:
+		return "zset"
+	case *memoryStreamValue:
+		return "stream"
+	}
+	return "none"
+}
+
+// memoryKeyList 返回所有未过期并且匹配 pattern 的 key，按字典序排列
+func memoryKeyList(db *memoryDB, pattern string) []string {
+	keys := make([]string, 0, len(db.items))
+	for key := range db.items {
+		if db.lookup(key) == nil {
+			continue
+		}
+		if pattern == "*" || memoryMatch(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func memoryKeys(db *memoryDB, args []string) interface{} {
+	return memoryBulks(memoryKeyList(db, args[0]))
+}
+
+func memoryRename(db *memoryDB, args []string) interface{} {
+	item := db.lookup(args[0])
+	if item == nil {
+		return errMemoryNoSuchKey
+	}
+	delete(db.items, args[0])
+	db.items[args[1]] = item
+	return "OK"
+}
+
+func memoryDBSize(db *memoryDB, args []string) interface{} {
+	return int64(len(memoryKeyList(db, "*")))
+}
+
+// memoryTime 返回 unix 时间戳的秒以及微秒部分
+func memoryTime(db *memoryDB, args []string) interface{} {
+	now := time.Now()
+	return []interface{}{
+		[]byte(strconv.FormatInt(now.Unix(), 10)),
+		[]byte(strconv.Itoa(now.Nanosecond() / 1000)),
+	}
+}
+
+func memoryFlushDB(db *memoryDB, args []string) interface{} {
+	db.items = make(map[string]*memoryItem)
+	return "OK"
+}
@@ -0,0 +1,296 @@
+package cache
+
+import (
+	"strings"
+)
+
+func memoryPush(db *memoryDB, args []string, left bool) interface{} {
+	list, err := db.list(args[0], true)
+	if err != nil {
+		return err
+	}
+	for _, value := range args[1:] {
+		if left {
+			list.values = append([]string{value}, list.values...)
+		} else {
+			list.values = append(list.values, value)
+		}
+	}
+	return int64(len(list.values))
+}
+
+func memoryLPush(db *memoryDB, args []string) interface{} {
+	return memoryPush(db, args, true)
+}
+
+func memoryRPush(db *memoryDB, args []string) interface{} {
+	return memoryPush(db, args, false)
+}
+
+// memoryPop LPOP/RPOP key [count]
+func memoryPop(db *memoryDB, args []string, left bool) interface{} {
+	if len(args) > 2 {
+		return errMemorySyntax
+	}
+	count := int64(-1)
+	if len(args) == 2 {
+		n, err := memoryParseInt(args[1])
+		if err != nil || n < 0 {
+			return memoryError("ERR value is out of range, must be positive")
+		}
+		count = n
+	}
+
+	list, err := db.list(args[0], false)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		return nil
+	}
+
+	n := 1
+	if count >= 0 {
+		n = int(count)
+	}
+	if n > len(list.values) {
+		n = len(list.values)
+	}
+
+	var values []string
+	if left {
+		values = append(values, list.values[:n]...)
+		list.values = list.values[n:]
+	} else {
+		for i := 0; i < n; i++ {
+			values = append(values, list.values[len(list.values)-1-i])
+		}
+		list.values = list.values[:len(list.values)-n]
+	}
+	db.removeEmpty(args[0])
+
+	if count < 0 {
+		return []byte(values[0])
+	}
+	return memoryBulks(values)
+}
+
+func memoryLPop(db *memoryDB, args []string) interface{} {
+	return memoryPop(db, args, true)
+}
+
+func memoryRPop(db *memoryDB, args []string) interface{} {
+	return memoryPop(db, args, false)
+}
+
+func memoryRPopLPush(db *memoryDB, args []string) interface{} {
+	source, err := db.list(args[0], false)
+	if err != nil {
+		return err
+	}
+	if source == nil {
+		return nil
+	}
+	if _, err := db.list(args[1], false); err != nil {
+		return err
+	}
+
+	value := source.values[len(source.values)-1]
+	source.values = source.values[:len(source.values)-1]
+	db.removeEmpty(args[0])
+
+	destination, _ := db.list(args[1], true)
+	destination.values = append([]string{value}, destination.values...)
+	return []byte(value)
+}
+
+func memoryLTrim(db *memoryDB, args []string) interface{} {
+	start, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	stop, err := memoryParseInt(args[2])
+	if err != nil {
+		return err
+	}
+	list, err := db.list(args[0], false)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		return "OK"
+	}
+
+	if from, to, ok := memoryRange(start, stop, len(list.values)); ok {
+		list.values = append([]string{}, list.values[from:to+1]...)
+	} else {
+		list.values = nil
+	}
+	db.removeEmpty(args[0])
+	return "OK"
+}
+
+// memoryListIndex 将 redis 风格的下标转为数组下标
+func memoryListIndex(index int64, length int) (int, bool) {
+	if index < 0 {
+		index += int64(length)
+	}
+	if index < 0 || index >= int64(length) {
+		return 0, false
+	}
+	return int(index), true
+}
+
+func memoryLSet(db *memoryDB, args []string) interface{} {
+	index, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	list, err := db.list(args[0], false)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		return errMemoryNoSuchKey
+	}
+	i, ok := memoryListIndex(index, len(list.values))
+	if !ok {
+		return errMemoryOutOfRange
+	}
+	list.values[i] = args[2]
+	return "OK"
+}
+
+func memoryLRem(db *memoryDB, args []string) interface{} {
+	count, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	list, err := db.list(args[0], false)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		return int64(0)
+	}
+
+	value := args[2]
+	limit := count
+	if limit < 0 {
+		limit = -limit
+	}
+
+	var removed int64
+	remove := make([]bool, len(list.values))
+	for i := range list.values {
+		// count < 0 时从表尾开始搜索
+		j := i
+		if count < 0 {
+			j = len(list.values) - 1 - i
+		}
+		if list.values[j] == value && (limit == 0 || removed < limit) {
+			remove[j] = true
+			removed++
+		}
+	}
+
+	values := make([]string, 0, len(list.values)-int(removed))
+	for i, v := range list.values {
+		if !remove[i] {
+			values = append(values, v)
+		}
+	}
+	list.values = values
+	db.removeEmpty(args[0])
+	return removed
+}
+
+func memoryLRange(db *memoryDB, args []string) interface{} {
+	start, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	stop, err := memoryParseInt(args[2])
+	if err != nil {
+		return err
+	}
+	list, err := db.list(args[0], false)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		return []interface{}{}
+	}
+
+	from, to, ok := memoryRange(start, stop, len(list.values))
+	if !ok {
+		return []interface{}{}
+	}
+	return memoryBulks(list.values[from : to+1])
+}
+
+func memoryLLen(db *memoryDB, args []string) interface{} {
+	list, err := db.list(args[0], false)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		return int64(0)
+	}
+	return int64(len(list.values))
+}
+
+// memoryLInsert LINSERT key BEFORE|AFTER pivot value
+func memoryLInsert(db *memoryDB, args []string) interface{} {
+	var after bool
+	switch strings.ToUpper(args[1]) {
+	case "BEFORE":
+	case "AFTER":
+		after = true
+	default:
+		return errMemorySyntax
+	}
+
+	list, err := db.list(args[0], false)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		return int64(0)
+	}
+
+	for i, v := range list.values {
+		if v != args[2] {
+			continue
+		}
+		if after {
+			i++
+		}
+		values := make([]string, 0, len(list.values)+1)
+		values = append(values, list.values[:i]...)
+		values = append(values, args[3])
+		values = append(values, list.values[i:]...)
+		list.values = values
+		return int64(len(list.values))
+	}
+	return int64(-1)
+}
+
+func memoryLIndex(db *memoryDB, args []string) interface{} {
+	index, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	list, err := db.list(args[0], false)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		return nil
+	}
+	i, ok := memoryListIndex(index, len(list.values))
+	if !ok {
+		return nil
+	}
+	return []byte(list.values[i])
+}
@@ -0,0 +1,102 @@
+package cache
+
+// memoryReplies 一条命令产生多个回复，例如 SUBSCRIBE 多个频道
+type memoryReplies []interface{}
+
+// subscribe SUBSCRIBE, PSUBSCRIBE
+func (c *memoryConn) subscribe(name string, targets []string) interface{} {
+	registry, own, kind := c.store.channels, c.channels, "subscribe"
+	if name == "PSUBSCRIBE" {
+		registry, own, kind = c.store.patterns, c.patterns, "psubscribe"
+	}
+
+	replies := make(memoryReplies, 0, len(targets))
+	for _, target := range targets {
+		own[target] = struct{}{}
+		conns, exist := registry[target]
+		if !exist {
+			conns = make(map[*memoryConn]struct{})
+			registry[target] = conns
+		}
+		conns[c] = struct{}{}
+		replies = append(replies, c.subscription(kind, target))
+	}
+	return replies
+}
+
+// unsubscribe UNSUBSCRIBE, PUNSUBSCRIBE，targets 为空时取消所有订阅
+func (c *memoryConn) unsubscribe(name string, targets []string) interface{} {
+	registry, own, kind := c.store.channels, c.channels, "unsubscribe"
+	if name == "PUNSUBSCRIBE" {
+		registry, own, kind = c.store.patterns, c.patterns, "punsubscribe"
+	}
+
+	if len(targets) == 0 {
+		targets = memorySortedKeys(own)
+		if len(targets) == 0 {
+			return []interface{}{[]byte(kind), nil, int64(len(c.channels) + len(c.patterns))}
+		}
+	}
+
+	replies := make(memoryReplies, 0, len(targets))
+	for _, target := range targets {
+		delete(own, target)
+		if conns, exist := registry[target]; exist {
+			delete(conns, c)
+			if len(conns) == 0 {
+				delete(registry, target)
+			}
+		}
+		replies = append(replies, c.subscription(kind, target))
+	}
+	return replies
+}
+
+// subscription 订阅相关命令的回复，同时更新 subscribed
+func (c *memoryConn) subscription(kind, target string) interface{} {
+	count := len(c.channels) + len(c.patterns)
+
+	c.mu.Lock()
+	c.subscribed = count
+	c.mu.Unlock()
+
+	return []interface{}{[]byte(kind), []byte(target), int64(count)}
+}
+
+// publish 将消息发送给订阅了频道以及匹配模式的连接，返回接收到消息的连接数量
+func (s *memoryStore) publish(channel, message string) interface{} {
+	var count int64
+	for conn := range s.channels[channel] {
+		conn.push([]interface{}{[]byte("message"), []byte(channel), []byte(message)})
+		count++
+	}
+	for pattern, conns := range s.patterns {
+		if !memoryMatch(pattern, channel) {
+			continue
+		}
+		for conn := range conns {
+			conn.push([]interface{}{[]byte("pmessage"), []byte(pattern), []byte(channel), []byte(message)})
+			count++
+		}
+	}
+	return count
+}
+
+// killSubscribers CLIENT KILL TYPE pubsub，关闭所有处于订阅状态的连接
+func (s *memoryStore) killSubscribers() interface{} {
+	conns := make(map[*memoryConn]struct{})
+	for _, registry := range []map[string]map[*memoryConn]struct{}{s.channels, s.patterns} {
+		for _, subscribers := range registry {
+			for conn := range subscribers {
+				conns[conn] = struct{}{}
+			}
+		}
+	}
+
+	for conn := range conns {
+		conn.unsubscribe("UNSUBSCRIBE", nil)
+		conn.unsubscribe("PUNSUBSCRIBE", nil)
+		conn.kill()
+	}
+	return int64(len(conns))
+}
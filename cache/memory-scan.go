@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 元素按照 hash 值排序，游标为下一个元素的 hash 值，因此迭代期间删除元素不会影响后续的迭代
+// 与 redis 一样，迭代期间一直存在的元素都会被返回，新增的元素可能返回也可能不返回
+
+// memoryScanArgs 解析 cursor [MATCH pattern] [COUNT count] [TYPE type]
+// allowType 为 false 时不支持 TYPE，例如 HSCAN
+func memoryScanArgs(args []string, allowType bool) (cursor uint64, count int, match, typ string, err error) {
+	cursor, err = strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return 0, 0, "", "", memoryError("ERR invalid cursor")
+	}
+	count, match = 10, "*"
+
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return 0, 0, "", "", errMemorySyntax
+		}
+		switch option := strings.ToUpper(args[i]); {
+		case option == "MATCH":
+			match = args[i+1]
+		case option == "COUNT":
+			c, err := memoryParseInt(args[i+1])
+			if err != nil {
+				return 0, 0, "", "", err
+			}
+			if c < 1 {
+				return 0, 0, "", "", errMemorySyntax
+			}
+			count = int(c)
+		case option == "TYPE" && allowType:
+			typ = strings.ToLower(args[i+1])
+		default:
+			return 0, 0, "", "", errMemorySyntax
+		}
+	}
+	return cursor, count, match, typ, nil
+}
+
+// memoryScanReply 从游标 cursor 开始检查 count 个元素，返回下一个游标以及 filter 之后的元素
+// hash 值相同的元素总是在同一次返回，避免游标无法前进
+func memoryScanReply(elements []string, cursor uint64, count int, filter func(element string) []interface{}) []interface{} {
+	hashes := make(map[string]uint64, len(elements))
+	for _, element := range elements {
+		h := fnv.New32a()
+		h.Write([]byte(element))
+		hashes[element] = uint64(h.Sum32())
+	}
+	sort.Slice(elements, func(i, j int) bool {
+		if hashes[elements[i]] != hashes[elements[j]] {
+			return hashes[elements[i]] < hashes[elements[j]]
+		}
+		return elements[i] < elements[j]
+	})
+
+	start := sort.Search(len(elements), func(i int) bool {
+		return hashes[elements[i]] >= cursor
+	})
+	end := start
+	for end < len(elements) && (end-start < count || hashes[elements[end]] == hashes[elements[end-1]]) {
+		end++
+	}
+
+	items := make([]interface{}, 0)
+	for _, element := range elements[start:end] {
+		items = append(items, filter(element)...)
+	}
+	var next uint64
+	if end < len(elements) {
+		next = hashes[elements[end]]
+	}
+	return []interface{}{[]byte(strconv.FormatUint(next, 10)), items}
+}
+
+// memoryScan SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+func memoryScan(db *memoryDB, args []string) interface{} {
+	cursor, count, match, typ, err := memoryScanArgs(args, true)
+	if err != nil {
+		return err
+	}
+	keys := memoryKeyList(db, "*")
+	return memoryScanReply(keys, cursor, count, func(key string) []interface{} {
+		if !memoryMatch(match, key) {
+			return nil
+		}
+		if typ != "" && memoryType(db, []string{key}) != typ {
+			return nil
+		}
+		return []interface{}{[]byte(key)}
+	})
+}
+
+// memoryHScan HSCAN key cursor [MATCH pattern] [COUNT count]
+func memoryHScan(db *memoryDB, args []string) interface{} {
+	cursor, count, match, _, err := memoryScanArgs(args[1:], false)
+	if err != nil {
+		return err
+	}
+	hash, err := db.hash(args[0], false)
+	if err != nil {
+		return err
+	}
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+	return memoryScanReply(fields, cursor, count, func(field string) []interface{} {
+		if !memoryMatch(match, field) {
+			return nil
+		}
+		return []interface{}{[]byte(field), []byte(hash[field])}
+	})
+}
+
+// memorySScan SSCAN key cursor [MATCH pattern] [COUNT count]
+func memorySScan(db *memoryDB, args []string) interface{} {
+	cursor, count, match, _, err := memoryScanArgs(args[1:], false)
+	if err != nil {
+		return err
+	}
+	set, err := db.set(args[0], false)
+	if err != nil {
+		return err
+	}
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	return memoryScanReply(members, cursor, count, func(member string) []interface{} {
+		if !memoryMatch(match, member) {
+			return nil
+		}
+		return []interface{}{[]byte(member)}
+	})
+}
+
+// memoryZScan ZSCAN key cursor [MATCH pattern] [COUNT count]
+func memoryZScan(db *memoryDB, args []string) interface{} {
+	cursor, count, match, _, err := memoryScanArgs(args[1:], false)
+	if err != nil {
+		return err
+	}
+	zset, err := db.sortedSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	members := make([]string, 0, len(zset))
+	for member := range zset {
+		members = append(members, member)
+	}
+	return memoryScanReply(members, cursor, count, func(member string) []interface{} {
+		if !memoryMatch(match, member) {
+			return nil
+		}
+		return []interface{}{[]byte(member), []byte(memoryFormatFloat(zset[member]))}
+	})
+}
@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+)
+
+// 内存模式没有 lua 解释器，只实现了脚本缓存: SCRIPT LOAD/EXISTS/FLUSH 以及 EVALSHA 的 NOSCRIPT
+// 执行脚本时返回 errMemoryNoScripting
+
+var (
+	errMemoryNoScript    = memoryError("NOSCRIPT No matching script. Please use EVAL.")
+	errMemoryNoScripting = memoryError("ERR memory cache does not support lua scripting")
+)
+
+// memoryScriptHash 脚本的 sha1
+func memoryScriptHash(script string) string {
+	sum := sha1.Sum([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// script SCRIPT LOAD script | SCRIPT EXISTS sha1 [sha1 ...] | SCRIPT FLUSH
+func (s *memoryStore) script(cmd string, args []string) interface{} {
+	if len(args) == 0 {
+		return memoryArityError(cmd)
+	}
+	switch strings.ToUpper(args[0]) {
+	case "LOAD":
+		if len(args) != 2 {
+			return memoryArityError(cmd)
+		}
+		hash := memoryScriptHash(args[1])
+		s.scripts[hash] = args[1]
+		return []byte(hash)
+	case "EXISTS":
+		if len(args) < 2 {
+			return memoryArityError(cmd)
+		}
+		exists := make([]interface{}, len(args)-1)
+		for i, hash := range args[1:] {
+			_, exist := s.scripts[strings.ToLower(hash)]
+			exists[i] = memoryBool(exist)
+		}
+		return exists
+	case "FLUSH":
+		s.scripts = make(map[string]string)
+		return "OK"
+	}
+	return errMemorySyntax
+}
+
+// eval EVAL script numkeys key [key ...] arg [arg ...]
+// 与 redis 一致，EVAL 会缓存脚本
+func (s *memoryStore) eval(args []string) interface{} {
+	if err := memoryEvalKeys(args); err != nil {
+		return err
+	}
+	s.scripts[memoryScriptHash(args[0])] = args[0]
+	return errMemoryNoScripting
+}
+
+// evalSha EVALSHA sha1 numkeys key [key ...] arg [arg ...]
+func (s *memoryStore) evalSha(args []string) interface{} {
+	if err := memoryEvalKeys(args); err != nil {
+		return err
+	}
+	if _, exist := s.scripts[strings.ToLower(args[0])]; !exist {
+		return errMemoryNoScript
+	}
+	return errMemoryNoScripting
+}
+
+// memoryEvalKeys 检查 numkeys
+func memoryEvalKeys(args []string) error {
+	numKeys, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	if numKeys < 0 {
+		return memoryError("ERR Number of keys can't be negative")
+	}
+	if numKeys > int64(len(args)-2) {
+		return memoryError("ERR Number of keys can't be greater than number of args")
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package cache
+
+import (
+	"math/rand"
+)
+
+func memorySAdd(db *memoryDB, args []string) interface{} {
+	set, err := db.set(args[0], true)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for _, member := range args[1:] {
+		if _, exist := set[member]; !exist {
+			set[member] = struct{}{}
+			n++
+		}
+	}
+	return n
+}
+
+func memorySCard(db *memoryDB, args []string) interface{} {
+	set, err := db.set(args[0], false)
+	if err != nil {
+		return err
+	}
+	return int64(len(set))
+}
+
+// memorySetOperate 计算集合的差集(diff)，并集(union)，交集(inter)
+// 不存在的 key 被视为空集
+func memorySetOperate(db *memoryDB, keys []string, op string) (memorySetValue, error) {
+	sets := make([]memorySetValue, len(keys))
+	for i, key := range keys {
+		set, err := db.set(key, false)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	result := memorySetValue{}
+	switch op {
+	case "diff":
+		for member := range sets[0] {
+			result[member] = struct{}{}
+		}
+		for _, set := range sets[1:] {
+			for member := range set {
+				delete(result, member)
+			}
+		}
+	case "union":
+		for _, set := range sets {
+			for member := range set {
+				result[member] = struct{}{}
+			}
+		}
+	case "inter":
+		for member := range sets[0] {
+			in := true
+			for _, set := range sets[1:] {
+				if _, exist := set[member]; !exist {
+					in = false
+					break
+				}
+			}
+			if in {
+				result[member] = struct{}{}
+			}
+		}
+	}
+	return result, nil
+}
+
+func memorySetOperateReply(db *memoryDB, keys []string, op string) interface{} {
+	result, err := memorySetOperate(db, keys, op)
+	if err != nil {
+		return err
+	}
+	return memoryBulks(memorySortedKeys(result))
+}
+
+// memorySetOperateStore 结果保存到 args[0]，返回结果集中的成员数量
+func memorySetOperateStore(db *memoryDB, args []string, op string) interface{} {
+	result, err := memorySetOperate(db, args[1:], op)
+	if err != nil {
+		return err
+	}
+	delete(db.items, args[0])
+	if len(result) > 0 {
+		db.put(args[0], result)
+	}
+	return int64(len(result))
+}
+
+func memorySDiff(db *memoryDB, args []string) interface{} {
+	return memorySetOperateReply(db, args, "diff")
+}
+
+func memorySDiffStore(db *memoryDB, args []string) interface{} {
+	return memorySetOperateStore(db, args, "diff")
+}
+
+func memorySUnion(db *memoryDB, args []string) interface{} {
+	return memorySetOperateReply(db, args, "union")
+}
+
+func memorySUnionStore(db *memoryDB, args []string) interface{} {
+	return memorySetOperateStore(db, args, "union")
+}
+
+func memorySInter(db *memoryDB, args []string) interface{} {
+	return memorySetOperateReply(db, args, "inter")
+}
+
+func memorySInterStore(db *memoryDB, args []string) interface{} {
+	return memorySetOperateStore(db, args, "inter")
+}
+
+func memorySIsMember(db *memoryDB, args []string) interface{} {
+	set, err := db.set(args[0], false)
+	if err != nil {
+		return err
+	}
+	_, exist := set[args[1]]
+	return memoryBool(exist)
+}
+
+func memorySMembers(db *memoryDB, args []string) interface{} {
+	set, err := db.set(args[0], false)
+	if err != nil {
+		return err
+	}
+	return memoryBulks(memorySortedKeys(set))
+}
+
+// memorySPop SPOP key [count]
+func memorySPop(db *memoryDB, args []string) interface{} {
+	if len(args) > 2 {
+		return errMemorySyntax
+	}
+	count := int64(-1)
+	if len(args) == 2 {
+		n, err := memoryParseInt(args[1])
+		if err != nil || n < 0 {
+			return memoryError("ERR value is out of range, must be positive")
+		}
+		count = n
+	}
+
+	set, err := db.set(args[0], false)
+	if err != nil {
+		return err
+	}
+	if set == nil {
+		if count < 0 {
+			return nil
+		}
+		return []interface{}{}
+	}
+
+	members := memorySortedKeys(set)
+	rand.Shuffle(len(members), func(i, j int) {
+		members[i], members[j] = members[j], members[i]
+	})
+
+	n := 1
+	if count >= 0 {
+		n = int(count)
+	}
+	if n > len(members) {
+		n = len(members)
+	}
+	members = members[:n]
+	for _, member := range members {
+		delete(set, member)
+	}
+	db.removeEmpty(args[0])
+
+	if count < 0 {
+		return []byte(members[0])
+	}
+	return memoryBulks(members)
+}
+
+// memorySRandMember SRANDMEMBER key [count]
+// count 为正数时返回不重复的元素，为负数时元素可能重复
+func memorySRandMember(db *memoryDB, args []string) interface{} {
+	if len(args) > 2 {
+		return errMemorySyntax
+	}
+	set, err := db.set(args[0], false)
+	if err != nil {
+		return err
+	}
+	members := memorySortedKeys(set)
+
+	if len(args) == 1 {
+		if len(members) == 0 {
+			return nil
+		}
+		return []byte(members[rand.Intn(len(members))])
+	}
+
+	count, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return []interface{}{}
+	}
+
+	if count < 0 {
+		result := make([]string, -count)
+		for i := range result {
+			result[i] = members[rand.Intn(len(members))]
+		}
+		return memoryBulks(result)
+	}
+
+	rand.Shuffle(len(members), func(i, j int) {
+		members[i], members[j] = members[j], members[i]
+	})
+	if count < int64(len(members)) {
+		members = members[:count]
+	}
+	return memoryBulks(members)
+}
+
+func memorySRem(db *memoryDB, args []string) interface{} {
+	set, err := db.set(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for _, member := range args[1:] {
+		if _, exist := set[member]; exist {
+			delete(set, member)
+			n++
+		}
+	}
+	db.removeEmpty(args[0])
+	return n
+}
+
+// memorySMove SMOVE source destination member
+func memorySMove(db *memoryDB, args []string) interface{} {
+	source, err := db.set(args[0], false)
+	if err != nil {
+		return err
+	}
+	if _, err := db.set(args[1], false); err != nil {
+		return err
+	}
+	if _, exist := source[args[2]]; !exist {
+		return int64(0)
+	}
+
+	delete(source, args[2])
+	db.removeEmpty(args[0])
+
+	destination, _ := db.set(args[1], true)
+	destination[args[2]] = struct{}{}
+	return int64(1)
+}
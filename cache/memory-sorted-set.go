@@ -0,0 +1,701 @@
+package cache
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// memoryZMember 有序集合成员
+type memoryZMember struct {
+	member string
+	score  float64
+}
+
+// sorted 按 score 值递增排列，score 相同时按成员字典序排列
+func (zset memorySortedSetValue) sorted() []memoryZMember {
+	members := make([]memoryZMember, 0, len(zset))
+	for member, score := range zset {
+		members = append(members, memoryZMember{member: member, score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].score != members[j].score {
+			return members[i].score < members[j].score
+		}
+		return members[i].member < members[j].member
+	})
+	return members
+}
+
+// memoryZBound score 区间的边界，支持 (1.5 表示不包含，-inf 和 +inf 表示无穷
+type memoryZBound struct {
+	value     float64
+	exclusive bool
+}
+
+func memoryParseZBound(s string) (memoryZBound, error) {
+	var bound memoryZBound
+	if strings.HasPrefix(s, "(") {
+		bound.exclusive = true
+		s = s[1:]
+	}
+	f, err := memoryParseFloat(s)
+	if err != nil {
+		return bound, memoryError("ERR min or max is not a float")
+	}
+	bound.value = f
+	return bound, nil
+}
+
+// inRange 判断 score 是否位于 [min, max] 之间
+func memoryZInRange(score float64, min, max memoryZBound) bool {
+	if score < min.value || (min.exclusive && score == min.value) {
+		return false
+	}
+	if score > max.value || (max.exclusive && score == max.value) {
+		return false
+	}
+	return true
+}
+
+// memoryZReply 生成回复，withScores 为 true 时 member 与 score 交替出现
+func memoryZReply(members []memoryZMember, withScores bool) []interface{} {
+	reply := make([]interface{}, 0, len(members)*2)
+	for _, m := range members {
+		reply = append(reply, []byte(m.member))
+		if withScores {
+			reply = append(reply, []byte(memoryFormatFloat(m.score)))
+		}
+	}
+	return reply
+}
+
+// memoryZAdd ZADD key [NX|XX] [CH] [INCR] score member [score member ...]
+func memoryZAdd(db *memoryDB, args []string) interface{} {
+	key := args[0]
+	args = args[1:]
+
+	var nx, xx, ch, incr bool
+loop:
+	for len(args) > 0 {
+		switch strings.ToUpper(args[0]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "CH":
+			ch = true
+		case "INCR":
+			incr = true
+		default:
+			break loop
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 || len(args)%2 != 0 || (nx && xx) || (incr && len(args) != 2) {
+		return errMemorySyntax
+	}
+
+	scores := make([]float64, len(args)/2)
+	for i := range scores {
+		score, err := memoryParseFloat(args[i*2])
+		if err != nil {
+			return err
+		}
+		scores[i] = score
+	}
+
+	zset, err := db.sortedSet(key, !xx)
+	if err != nil {
+		return err
+	}
+	if zset == nil {
+		if incr {
+			return nil
+		}
+		return int64(0)
+	}
+
+	var added, changed int64
+	for i, score := range scores {
+		member := args[i*2+1]
+		old, exist := zset[member]
+		if (nx && exist) || (xx && !exist) {
+			if incr {
+				db.removeEmpty(key)
+				return nil
+			}
+			continue
+		}
+		if incr {
+			score += old
+			if math.IsNaN(score) {
+				return memoryError("ERR resulting score is not a number (NaN)")
+			}
+			zset[member] = score
+			return []byte(memoryFormatFloat(score))
+		}
+		if !exist {
+			added++
+		} else if old != score {
+			changed++
+		}
+		zset[member] = score
+	}
+	db.removeEmpty(key)
+
+	if ch {
+		return added + changed
+	}
+	return added
+}
+
+func memoryZCard(db *memoryDB, args []string) interface{} {
+	zset, err := db.sortedSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	return int64(len(zset))
+}
+
+func memoryZCount(db *memoryDB, args []string) interface{} {
+	min, err := memoryParseZBound(args[1])
+	if err != nil {
+		return err
+	}
+	max, err := memoryParseZBound(args[2])
+	if err != nil {
+		return err
+	}
+	zset, err := db.sortedSet(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for _, score := range zset {
+		if memoryZInRange(score, min, max) {
+			n++
+		}
+	}
+	return n
+}
+
+func memoryZIncrby(db *memoryDB, args []string) interface{} {
+	increment, err := memoryParseFloat(args[1])
+	if err != nil {
+		return err
+	}
+	zset, err := db.sortedSet(args[0], true)
+	if err != nil {
+		return err
+	}
+	score := zset[args[2]] + increment
+	if math.IsNaN(score) {
+		return memoryError("ERR resulting score is not a number (NaN)")
+	}
+	zset[args[2]] = score
+	return []byte(memoryFormatFloat(score))
+}
+
+// memoryZRangeByRank ZRANGE/ZREVRANGE key start stop [WITHSCORES]
+func memoryZRangeByRank(db *memoryDB, args []string, reverse bool) interface{} {
+	withScores := false
+	if len(args) == 4 && strings.ToUpper(args[3]) == "WITHSCORES" {
+		withScores = true
+	} else if len(args) != 3 {
+		return errMemorySyntax
+	}
+
+	start, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	stop, err := memoryParseInt(args[2])
+	if err != nil {
+		return err
+	}
+	zset, err := db.sortedSet(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	members := zset.sorted()
+	if reverse {
+		memoryZReverse(members)
+	}
+	from, to, ok := memoryRange(start, stop, len(members))
+	if !ok {
+		return []interface{}{}
+	}
+	return memoryZReply(members[from:to+1], withScores)
+}
+
+func memoryZReverse(members []memoryZMember) {
+	for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+		members[i], members[j] = members[j], members[i]
+	}
+}
+
+func memoryZRange(db *memoryDB, args []string) interface{} {
+	return memoryZRangeByRank(db, args, false)
+}
+
+func memoryZRevRange(db *memoryDB, args []string) interface{} {
+	return memoryZRangeByRank(db, args, true)
+}
+
+func memoryZScore(db *memoryDB, args []string) interface{} {
+	zset, err := db.sortedSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	score, exist := zset[args[1]]
+	if !exist {
+		return nil
+	}
+	return []byte(memoryFormatFloat(score))
+}
+
+func memoryZRankOf(db *memoryDB, args []string, reverse bool) interface{} {
+	zset, err := db.sortedSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	if _, exist := zset[args[1]]; !exist {
+		return nil
+	}
+
+	members := zset.sorted()
+	if reverse {
+		memoryZReverse(members)
+	}
+	for i, m := range members {
+		if m.member == args[1] {
+			return int64(i)
+		}
+	}
+	return nil
+}
+
+func memoryZRank(db *memoryDB, args []string) interface{} {
+	return memoryZRankOf(db, args, false)
+}
+
+func memoryZRevRank(db *memoryDB, args []string) interface{} {
+	return memoryZRankOf(db, args, true)
+}
+
+// memoryZRangeScore ZRANGEBYSCORE key min max [WITHSCORES] [LIMIT offset count]
+// reverse 为 true 时对应 ZREVRANGEBYSCORE key max min
+func memoryZRangeScore(db *memoryDB, args []string, reverse bool) interface{} {
+	minArg, maxArg := args[1], args[2]
+	if reverse {
+		minArg, maxArg = maxArg, minArg
+	}
+	min, err := memoryParseZBound(minArg)
+	if err != nil {
+		return err
+	}
+	max, err := memoryParseZBound(maxArg)
+	if err != nil {
+		return err
+	}
+
+	var (
+		withScores bool
+		offset     int64
+		count      int64 = -1
+	)
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "WITHSCORES":
+			withScores = true
+		case "LIMIT":
+			if i+2 >= len(args) {
+				return errMemorySyntax
+			}
+			if offset, err = memoryParseInt(args[i+1]); err != nil {
+				return err
+			}
+			if count, err = memoryParseInt(args[i+2]); err != nil {
+				return err
+			}
+			i += 2
+		default:
+			return errMemorySyntax
+		}
+	}
+
+	zset, err := db.sortedSet(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	members := zset.sorted()
+	if reverse {
+		memoryZReverse(members)
+	}
+
+	result := make([]memoryZMember, 0)
+	for _, m := range members {
+		if !memoryZInRange(m.score, min, max) {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if offset < 0 || count == 0 {
+			break
+		}
+		result = append(result, m)
+		if count > 0 {
+			count--
+		}
+	}
+	return memoryZReply(result, withScores)
+}
+
+func memoryZRangeByScore(db *memoryDB, args []string) interface{} {
+	return memoryZRangeScore(db, args, false)
+}
+
+func memoryZRevRangeByScore(db *memoryDB, args []string) interface{} {
+	return memoryZRangeScore(db, args, true)
+}
+
+func memoryZRemRangeByScore(db *memoryDB, args []string) interface{} {
+	min, err := memoryParseZBound(args[1])
+	if err != nil {
+		return err
+	}
+	max, err := memoryParseZBound(args[2])
+	if err != nil {
+		return err
+	}
+	zset, err := db.sortedSet(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for member, score := range zset {
+		if memoryZInRange(score, min, max) {
+			delete(zset, member)
+			n++
+		}
+	}
+	db.removeEmpty(args[0])
+	return n
+}
+
+func memoryZRemRangeByRank(db *memoryDB, args []string) interface{} {
+	start, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	stop, err := memoryParseInt(args[2])
+	if err != nil {
+		return err
+	}
+	zset, err := db.sortedSet(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	members := zset.sorted()
+	from, to, ok := memoryRange(start, stop, len(members))
+	if !ok {
+		return int64(0)
+	}
+	for _, m := range members[from : to+1] {
+		delete(zset, m.member)
+	}
+	db.removeEmpty(args[0])
+	return int64(to - from + 1)
+}
+
+func memoryZRem(db *memoryDB, args []string) interface{} {
+	zset, err := db.sortedSet(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for _, member := range args[1:] {
+		if _, exist := zset[member]; exist {
+			delete(zset, member)
+			n++
+		}
+	}
+	db.removeEmpty(args[0])
+	return n
+}
+
+// memoryZStore ZUNIONSTORE/ZINTERSTORE destination numkeys key [key ...] [WEIGHTS weight ...] [AGGREGATE SUM|MIN|MAX]
+// 与 redis 一致，集合也可以作为输入，成员的 score 为 1
+func memoryZStore(db *memoryDB, args []string, inter bool) interface{} {
+	numKeys, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	if numKeys < 1 {
+		return memoryError("ERR at least 1 input key is needed for ZUNIONSTORE/ZINTERSTORE")
+	}
+	if int64(len(args)-2) < numKeys {
+		return errMemorySyntax
+	}
+	keys := args[2 : 2+numKeys]
+
+	weights := make([]float64, len(keys))
+	for i := range weights {
+		weights[i] = 1
+	}
+	aggregate := "SUM"
+	for i := 2 + int(numKeys); i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "WEIGHTS":
+			if i+len(keys) >= len(args) {
+				return errMemorySyntax
+			}
+			for j := range weights {
+				w, err := memoryParseFloat(args[i+1+j])
+				if err != nil {
+					return memoryError("ERR weight value is not a float")
+				}
+				weights[j] = w
+			}
+			i += len(keys)
+		case "AGGREGATE":
+			if i+1 >= len(args) {
+				return errMemorySyntax
+			}
+			aggregate = strings.ToUpper(args[i+1])
+			if aggregate != "SUM" && aggregate != "MIN" && aggregate != "MAX" {
+				return errMemorySyntax
+			}
+			i++
+		default:
+			return errMemorySyntax
+		}
+	}
+
+	// 读取所有输入
+	inputs := make([]memorySortedSetValue, len(keys))
+	for i, key := range keys {
+		item := db.lookup(key)
+		if item == nil {
+			continue
+		}
+		switch value := item.value.(type) {
+		case memorySortedSetValue:
+			inputs[i] = value
+		case memorySetValue:
+			zset := memorySortedSetValue{}
+			for member := range value {
+				zset[member] = 1
+			}
+			inputs[i] = zset
+		default:
+			return errMemoryWrongType
+		}
+	}
+
+	result := memorySortedSetValue{}
+	for i, input := range inputs {
+		for member, score := range input {
+			if inter && i > 0 {
+				if _, exist := result[member]; !exist {
+					continue
+				}
+			}
+			score *= weights[i]
+			if math.IsNaN(score) {
+				score = 0
+			}
+			old, exist := result[member]
+			if !exist {
+				result[member] = score
+				continue
+			}
+			switch aggregate {
+			case "MIN":
+				score = math.Min(old, score)
+			case "MAX":
+				score = math.Max(old, score)
+			default:
+				score += old
+			}
+			result[member] = score
+		}
+		if inter && i > 0 {
+			// 删除当前输入中不存在的成员
+			for member := range result {
+				if _, exist := input[member]; !exist {
+					delete(result, member)
+				}
+			}
+		}
+	}
+
+	delete(db.items, args[0])
+	if len(result) > 0 {
+		db.put(args[0], result)
+	}
+	return int64(len(result))
+}
+
+func memoryZUnionStore(db *memoryDB, args []string) interface{} {
+	return memoryZStore(db, args, false)
+}
+
+func memoryZInterStore(db *memoryDB, args []string) interface{} {
+	return memoryZStore(db, args, true)
+}
+
+// memoryZPop ZPOPMIN/ZPOPMAX key [count]
+func memoryZPop(db *memoryDB, args []string, max bool) interface{} {
+	if len(args) > 2 {
+		return errMemorySyntax
+	}
+	count := int64(1)
+	if len(args) == 2 {
+		n, err := memoryParseInt(args[1])
+		if err != nil {
+			return err
+		}
+		count = n
+	}
+	zset, err := db.sortedSet(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	members := zset.sorted()
+	if max {
+		memoryZReverse(members)
+	}
+	if count < 0 {
+		count = 0
+	}
+	if int64(len(members)) > count {
+		members = members[:count]
+	}
+	for _, m := range members {
+		delete(zset, m.member)
+	}
+	db.removeEmpty(args[0])
+	return memoryZReply(members, true)
+}
+
+func memoryZPopMin(db *memoryDB, args []string) interface{} {
+	return memoryZPop(db, args, false)
+}
+
+func memoryZPopMax(db *memoryDB, args []string) interface{} {
+	return memoryZPop(db, args, true)
+}
+
+// memoryBZPop BZPOPMIN/BZPOPMAX key [key ...] timeout
+// 返回 key, member, score，所有 key 都为空时阻塞，timeout 为秒，0 表示一直阻塞
+func memoryBZPop(db *memoryDB, args []string, max bool) interface{} {
+	timeout, err := memoryParseFloat(args[len(args)-1])
+	if err != nil || timeout < 0 {
+		return memoryError("ERR timeout is not a float or out of range")
+	}
+	for _, key := range args[:len(args)-1] {
+		zset, err := db.sortedSet(key, false)
+		if err != nil {
+			return err
+		}
+		if len(zset) == 0 {
+			continue
+		}
+		reply := memoryZPop(db, []string{key}, max).([]interface{})
+		return append([]interface{}{[]byte(key)}, reply...)
+	}
+	return memoryBlock(time.Duration(timeout * float64(time.Second)))
+}
+
+func memoryBZPopMin(db *memoryDB, args []string) interface{} {
+	return memoryBZPop(db, args, false)
+}
+
+func memoryBZPopMax(db *memoryDB, args []string) interface{} {
+	return memoryBZPop(db, args, true)
+}
+
+// memoryBZPopKey 阻塞弹出时会被修改的 key，即第一个不为空的有序集合
+func memoryBZPopKey(db *memoryDB, args []string) []string {
+	for _, key := range args[:len(args)-1] {
+		if zset, err := db.sortedSet(key, false); err == nil && len(zset) > 0 {
+			return []string{key}
+		}
+	}
+	return nil
+}
+
+// memoryZLexBound 字典序区间的边界，[a 表示包含，(a 表示不包含，- 和 + 表示无穷
+type memoryZLexBound struct {
+	value     string
+	exclusive bool
+	// inf -1 表示 -，1 表示 +
+	inf int
+}
+
+func memoryParseZLexBound(s string) (memoryZLexBound, error) {
+	switch {
+	case s == "-":
+		return memoryZLexBound{inf: -1}, nil
+	case s == "+":
+		return memoryZLexBound{inf: 1}, nil
+	case strings.HasPrefix(s, "["):
+		return memoryZLexBound{value: s[1:]}, nil
+	case strings.HasPrefix(s, "("):
+		return memoryZLexBound{value: s[1:], exclusive: true}, nil
+	}
+	return memoryZLexBound{}, memoryError("ERR min or max not valid string range item")
+}
+
+// memoryZLexInRange 判断 member 是否位于 [min, max] 之间
+func memoryZLexInRange(member string, min, max memoryZLexBound) bool {
+	switch {
+	case min.inf > 0:
+		return false
+	case min.inf == 0 && (member < min.value || (min.exclusive && member == min.value)):
+		return false
+	}
+	switch {
+	case max.inf < 0:
+		return false
+	case max.inf == 0 && (member > max.value || (max.exclusive && member == max.value)):
+		return false
+	}
+	return true
+}
+
+// memoryZLexCount ZLEXCOUNT key min max
+func memoryZLexCount(db *memoryDB, args []string) interface{} {
+	min, err := memoryParseZLexBound(args[1])
+	if err != nil {
+		return err
+	}
+	max, err := memoryParseZLexBound(args[2])
+	if err != nil {
+		return err
+	}
+	zset, err := db.sortedSet(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	for member := range zset {
+		if memoryZLexInRange(member, min, max) {
+			n++
+		}
+	}
+	return n
+}
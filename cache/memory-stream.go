@@ -0,0 +1,716 @@
+package cache
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errMemoryStreamID      = memoryError("ERR Invalid stream ID specified as stream command argument")
+	errMemoryStreamSmall   = memoryError("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+	errMemoryStreamZero    = memoryError("ERR The ID specified in XADD must be greater than 0-0")
+	errMemoryBusyGroup     = memoryError("BUSYGROUP Consumer Group name already exists")
+	errMemoryGroupNoKey    = memoryError("ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+	errMemoryStreamTimeout = memoryError("ERR timeout is not an integer or out of range")
+)
+
+// memoryStreamValue 消息流
+type memoryStreamValue struct {
+	// entries 按照 id 从小到大排列
+	entries []memoryStreamEntry
+	lastID  memoryStreamID
+	groups  map[string]*memoryStreamGroup
+}
+
+type memoryStreamID struct {
+	ms, seq uint64
+}
+
+type memoryStreamEntry struct {
+	id     memoryStreamID
+	fields []string
+}
+
+// memoryStreamGroup 消费者组
+type memoryStreamGroup struct {
+	lastID memoryStreamID
+	// pending 已经投递但是没有确认的消息
+	pending   map[memoryStreamID]*memoryStreamPending
+	consumers map[string]struct{}
+}
+
+type memoryStreamPending struct {
+	consumer  string
+	delivered time.Time
+	count     int64
+}
+
+func (id memoryStreamID) String() string {
+	return strconv.FormatUint(id.ms, 10) + "-" + strconv.FormatUint(id.seq, 10)
+}
+
+func (id memoryStreamID) less(other memoryStreamID) bool {
+	return id.ms < other.ms || (id.ms == other.ms && id.seq < other.seq)
+}
+
+// memoryParseStreamID 解析 id，只有毫秒部分时，序号使用 seq
+// - 与 + 分别表示最小和最大的 id
+func memoryParseStreamID(s string, seq uint64) (memoryStreamID, error) {
+	switch s {
+	case "-":
+		return memoryStreamID{}, nil
+	case "+":
+		return memoryStreamID{math.MaxUint64, math.MaxUint64}, nil
+	}
+
+	parts := strings.SplitN(s, "-", 2)
+	ms, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return memoryStreamID{}, errMemoryStreamID
+	}
+	if len(parts) == 2 {
+		if seq, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+			return memoryStreamID{}, errMemoryStreamID
+		}
+	}
+	return memoryStreamID{ms, seq}, nil
+}
+
+// search 第一个 id 大于等于 id 的消息的下标
+func (stream *memoryStreamValue) search(id memoryStreamID) int {
+	return sort.Search(len(stream.entries), func(i int) bool {
+		return !stream.entries[i].id.less(id)
+	})
+}
+
+// entry 根据 id 获取消息，不存在时返回 nil
+func (stream *memoryStreamValue) entry(id memoryStreamID) *memoryStreamEntry {
+	i := stream.search(id)
+	if i < len(stream.entries) && stream.entries[i].id == id {
+		return &stream.entries[i]
+	}
+	return nil
+}
+
+// trim 只保留最新的 maxLen 条消息，返回删除的数量
+func (stream *memoryStreamValue) trim(maxLen int64) int64 {
+	removed := int64(len(stream.entries)) - maxLen
+	if removed <= 0 {
+		return 0
+	}
+	stream.entries = append([]memoryStreamEntry(nil), stream.entries[removed:]...)
+	return removed
+}
+
+func (entry *memoryStreamEntry) reply() interface{} {
+	return []interface{}{[]byte(entry.id.String()), memoryBulks(entry.fields)}
+}
+
+// memoryParseMaxLen 解析 MAXLEN [=|~] count，返回 count 以及使用的参数数量
+func memoryParseMaxLen(args []string) (int64, int, error) {
+	i := 1
+	if i < len(args) && (args[i] == "=" || args[i] == "~") {
+		i++
+	}
+	if i >= len(args) {
+		return 0, 0, errMemorySyntax
+	}
+	maxLen, err := memoryParseInt(args[i])
+	if err != nil || maxLen < 0 {
+		return 0, 0, errMemoryNotInteger
+	}
+	return maxLen, i + 1, nil
+}
+
+// XADD key [NOMKSTREAM] [MAXLEN [=|~] count] id field value [field value ...]
+func memoryXAdd(db *memoryDB, args []string) interface{} {
+	key := args[0]
+	create := true
+	maxLen := int64(-1)
+
+	i := 1
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NOMKSTREAM":
+			create = false
+			continue
+		case "MAXLEN":
+			n, used, err := memoryParseMaxLen(args[i:])
+			if err != nil {
+				return err
+			}
+			maxLen = n
+			i += used - 1
+			continue
+		}
+		break
+	}
+	if i >= len(args) {
+		return errMemorySyntax
+	}
+	id, fields := args[i], args[i+1:]
+	if len(fields) == 0 || len(fields)%2 != 0 {
+		return memoryArityError("XADD")
+	}
+
+	stream, err := db.stream(key, create)
+	if err != nil {
+		return err
+	}
+	if stream == nil {
+		return nil
+	}
+
+	var newID memoryStreamID
+	if id == "*" {
+		ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+		if ms > stream.lastID.ms {
+			newID = memoryStreamID{ms, 0}
+		} else {
+			newID = memoryStreamID{stream.lastID.ms, stream.lastID.seq + 1}
+		}
+	} else {
+		if newID, err = memoryParseStreamID(id, 0); err != nil {
+			return err
+		}
+		if newID == (memoryStreamID{}) {
+			return errMemoryStreamZero
+		}
+		if !stream.lastID.less(newID) {
+			return errMemoryStreamSmall
+		}
+	}
+
+	stream.entries = append(stream.entries, memoryStreamEntry{id: newID, fields: append([]string(nil), fields...)})
+	stream.lastID = newID
+	if maxLen >= 0 {
+		stream.trim(maxLen)
+	}
+	return []byte(newID.String())
+}
+
+// XLEN key
+func memoryXLen(db *memoryDB, args []string) interface{} {
+	stream, err := db.stream(args[0], false)
+	if err != nil {
+		return err
+	}
+	if stream == nil {
+		return int64(0)
+	}
+	return int64(len(stream.entries))
+}
+
+// XRANGE key start end [COUNT count]
+func memoryXRange(db *memoryDB, args []string) interface{} {
+	start, err := memoryParseStreamID(args[1], 0)
+	if err != nil {
+		return err
+	}
+	end, err := memoryParseStreamID(args[2], math.MaxUint64)
+	if err != nil {
+		return err
+	}
+	count := int64(-1)
+	if len(args) > 3 {
+		if len(args) != 5 || strings.ToUpper(args[3]) != "COUNT" {
+			return errMemorySyntax
+		}
+		if count, err = memoryParseInt(args[4]); err != nil {
+			return err
+		}
+	}
+
+	stream, err := db.stream(args[0], false)
+	if err != nil {
+		return err
+	}
+	reply := []interface{}{}
+	if stream == nil {
+		return reply
+	}
+	for i := stream.search(start); i < len(stream.entries); i++ {
+		entry := &stream.entries[i]
+		if end.less(entry.id) || (count >= 0 && int64(len(reply)) >= count) {
+			break
+		}
+		reply = append(reply, entry.reply())
+	}
+	return reply
+}
+
+// XDEL key id [id ...]
+func memoryXDel(db *memoryDB, args []string) interface{} {
+	ids := make([]memoryStreamID, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		id, err := memoryParseStreamID(arg, 0)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	stream, err := db.stream(args[0], false)
+	if err != nil {
+		return err
+	}
+	if stream == nil {
+		return int64(0)
+	}
+
+	var removed int64
+	for _, id := range ids {
+		i := stream.search(id)
+		if i < len(stream.entries) && stream.entries[i].id == id {
+			stream.entries = append(stream.entries[:i], stream.entries[i+1:]...)
+			removed++
+		}
+	}
+	return removed
+}
+
+// XTRIM key MAXLEN [=|~] count
+func memoryXTrim(db *memoryDB, args []string) interface{} {
+	if strings.ToUpper(args[1]) != "MAXLEN" {
+		return errMemorySyntax
+	}
+	maxLen, used, err := memoryParseMaxLen(args[1:])
+	if err != nil {
+		return err
+	}
+	if used != len(args)-1 {
+		return errMemorySyntax
+	}
+
+	stream, err := db.stream(args[0], false)
+	if err != nil {
+		return err
+	}
+	if stream == nil {
+		return int64(0)
+	}
+	return stream.trim(maxLen)
+}
+
+// XGROUP CREATE key group id [MKSTREAM]
+// XGROUP SETID key group id
+// XGROUP DESTROY key group
+// XGROUP CREATECONSUMER key group consumer
+// XGROUP DELCONSUMER key group consumer
+func memoryXGroup(db *memoryDB, args []string) interface{} {
+	sub := strings.ToUpper(args[0])
+	switch {
+	case sub == "CREATE" && (len(args) == 4 || len(args) == 5):
+	case sub == "SETID" && len(args) == 4:
+	case (sub == "DESTROY") && len(args) == 3:
+	case (sub == "CREATECONSUMER" || sub == "DELCONSUMER") && len(args) == 4:
+	default:
+		return memoryError(fmt.Sprintf("ERR unknown subcommand or wrong number of arguments for '%s'", args[0]))
+	}
+
+	create := sub == "CREATE" && len(args) == 5
+	if create && strings.ToUpper(args[4]) != "MKSTREAM" {
+		return errMemorySyntax
+	}
+	key, name := args[1], args[2]
+	stream, err := db.stream(key, create)
+	if err != nil {
+		return err
+	}
+	if stream == nil {
+		return errMemoryGroupNoKey
+	}
+
+	if sub == "CREATE" {
+		if _, exist := stream.groups[name]; exist {
+			return errMemoryBusyGroup
+		}
+		lastID, err := memoryStreamStart(stream, args[3])
+		if err != nil {
+			return err
+		}
+		stream.groups[name] = &memoryStreamGroup{
+			lastID:    lastID,
+			pending:   make(map[memoryStreamID]*memoryStreamPending),
+			consumers: make(map[string]struct{}),
+		}
+		return "OK"
+	}
+
+	group, exist := stream.groups[name]
+	if !exist {
+		if sub == "DESTROY" {
+			return int64(0)
+		}
+		return memoryNoGroup(key, name)
+	}
+
+	switch sub {
+	case "SETID":
+		lastID, err := memoryStreamStart(stream, args[3])
+		if err != nil {
+			return err
+		}
+		group.lastID = lastID
+		return "OK"
+	case "DESTROY":
+		delete(stream.groups, name)
+		return int64(1)
+	case "CREATECONSUMER":
+		_, exist := group.consumers[args[3]]
+		group.consumers[args[3]] = struct{}{}
+		return memoryBool(!exist)
+	}
+
+	// DELCONSUMER 返回被删除的消费者未确认的消息数量
+	var count int64
+	for id, pending := range group.pending {
+		if pending.consumer == args[3] {
+			delete(group.pending, id)
+			count++
+		}
+	}
+	delete(group.consumers, args[3])
+	return count
+}
+
+// memoryStreamStart 消费者组的起始 id，$ 表示从最新的消息之后开始
+func memoryStreamStart(stream *memoryStreamValue, id string) (memoryStreamID, error) {
+	if id == "$" {
+		return stream.lastID, nil
+	}
+	return memoryParseStreamID(id, 0)
+}
+
+func memoryNoGroup(key, group string) interface{} {
+	return memoryError(fmt.Sprintf("NOGROUP No such key '%s' or consumer group '%s'", key, group))
+}
+
+// XREADGROUP GROUP group consumer [COUNT count] [BLOCK milliseconds] [NOACK] STREAMS key [key ...] id [id ...]
+// id 为 > 时读取新的消息，否则读取当前消费者已经投递但是没有确认的消息
+func memoryXReadGroup(db *memoryDB, args []string) interface{} {
+	if strings.ToUpper(args[0]) != "GROUP" {
+		return errMemorySyntax
+	}
+	name, consumer := args[1], args[2]
+
+	count := int64(-1)
+	block := int64(-1)
+	noack := false
+	i := 3
+	for ; i < len(args); i++ {
+		option := strings.ToUpper(args[i])
+		if option == "STREAMS" {
+			break
+		}
+		switch {
+		case option == "NOACK":
+			noack = true
+		case option == "COUNT" && i+1 < len(args):
+			n, err := memoryParseInt(args[i+1])
+			if err != nil {
+				return err
+			}
+			count = n
+			i++
+		case option == "BLOCK" && i+1 < len(args):
+			n, err := memoryParseInt(args[i+1])
+			if err != nil || n < 0 {
+				return errMemoryStreamTimeout
+			}
+			block = n
+			i++
+		default:
+			return errMemorySyntax
+		}
+	}
+
+	streams := args[i+1:]
+	if i >= len(args) || len(streams) == 0 || len(streams)%2 != 0 {
+		return memoryError("ERR Unbalanced 'xreadgroup' list of streams: for each stream key an ID or '>' must be specified.")
+	}
+	keys, ids := streams[:len(streams)/2], streams[len(streams)/2:]
+
+	now := time.Now()
+	reply := []interface{}{}
+	history := false
+	for k, key := range keys {
+		stream, err := db.stream(key, false)
+		if err != nil {
+			return err
+		}
+		var group *memoryStreamGroup
+		if stream != nil {
+			group = stream.groups[name]
+		}
+		if group == nil {
+			return memoryError(fmt.Sprintf("NOGROUP No such key '%s' or consumer group '%s' in XREADGROUP with GROUP option", key, name))
+		}
+		group.consumers[consumer] = struct{}{}
+
+		entries := []interface{}{}
+		if ids[k] == ">" {
+			for j := stream.search(group.lastID); j < len(stream.entries); j++ {
+				entry := &stream.entries[j]
+				if entry.id == group.lastID {
+					continue
+				}
+				if count > 0 && int64(len(entries)) >= count {
+					break
+				}
+				group.lastID = entry.id
+				if !noack {
+					group.pending[entry.id] = &memoryStreamPending{consumer: consumer, delivered: now, count: 1}
+				}
+				entries = append(entries, entry.reply())
+			}
+			if len(entries) == 0 {
+				continue
+			}
+		} else {
+			// 读取历史消息时，即使为空也返回
+			history = true
+			start, err := memoryParseStreamID(ids[k], 0)
+			if err != nil {
+				return err
+			}
+			for _, id := range memoryPendingIDs(group, consumer) {
+				if !start.less(id) {
+					continue
+				}
+				if count > 0 && int64(len(entries)) >= count {
+					break
+				}
+				pending := group.pending[id]
+				pending.delivered = now
+				pending.count++
+				if entry := stream.entry(id); entry != nil {
+					entries = append(entries, entry.reply())
+				} else {
+					entries = append(entries, []interface{}{[]byte(id.String()), nil})
+				}
+			}
+		}
+		reply = append(reply, []interface{}{[]byte(key), entries})
+	}
+
+	if len(reply) == 0 && !history {
+		if block >= 0 {
+			return memoryBlock(time.Duration(block) * time.Millisecond)
+		}
+		return nil
+	}
+	return reply
+}
+
+// memoryPendingIDs 未确认消息的 id，按照从小到大排列，consumer 为空时返回所有消费者的消息
+func memoryPendingIDs(group *memoryStreamGroup, consumer string) []memoryStreamID {
+	ids := make([]memoryStreamID, 0, len(group.pending))
+	for id, pending := range group.pending {
+		if consumer == "" || pending.consumer == consumer {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].less(ids[j])
+	})
+	return ids
+}
+
+// memoryStreamGroupOf 获取消费者组，key 或者消费者组不存在时 group 为 nil
+func memoryStreamGroupOf(db *memoryDB, key, name string) (*memoryStreamValue, *memoryStreamGroup, error) {
+	stream, err := db.stream(key, false)
+	if err != nil || stream == nil {
+		return nil, nil, err
+	}
+	return stream, stream.groups[name], nil
+}
+
+// XACK key group id [id ...]
+func memoryXAck(db *memoryDB, args []string) interface{} {
+	ids := make([]memoryStreamID, 0, len(args)-2)
+	for _, arg := range args[2:] {
+		id, err := memoryParseStreamID(arg, 0)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	_, group, err := memoryStreamGroupOf(db, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return int64(0)
+	}
+
+	var count int64
+	for _, id := range ids {
+		if _, exist := group.pending[id]; exist {
+			delete(group.pending, id)
+			count++
+		}
+	}
+	return count
+}
+
+// XPENDING key group
+// XPENDING key group [IDLE min-idle-time] start end count [consumer]
+func memoryXPending(db *memoryDB, args []string) interface{} {
+	_, group, err := memoryStreamGroupOf(db, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return memoryNoGroup(args[0], args[1])
+	}
+
+	// 概要: 数量, 最小 id, 最大 id, 每个消费者的数量
+	if len(args) == 2 {
+		ids := memoryPendingIDs(group, "")
+		if len(ids) == 0 {
+			return []interface{}{int64(0), nil, nil, nil}
+		}
+		counts := make(map[string]int64)
+		for _, pending := range group.pending {
+			counts[pending.consumer]++
+		}
+		consumers := make(map[string]struct{}, len(counts))
+		for consumer := range counts {
+			consumers[consumer] = struct{}{}
+		}
+		reply := make([]interface{}, 0, len(counts))
+		for _, consumer := range memorySortedKeys(consumers) {
+			reply = append(reply, []interface{}{[]byte(consumer), []byte(strconv.FormatInt(counts[consumer], 10))})
+		}
+		return []interface{}{int64(len(ids)), []byte(ids[0].String()), []byte(ids[len(ids)-1].String()), reply}
+	}
+
+	rest := args[2:]
+	var minIdle time.Duration
+	if len(rest) > 0 && strings.ToUpper(rest[0]) == "IDLE" {
+		if len(rest) < 2 {
+			return errMemorySyntax
+		}
+		n, err := memoryParseInt(rest[1])
+		if err != nil {
+			return err
+		}
+		minIdle = time.Duration(n) * time.Millisecond
+		rest = rest[2:]
+	}
+	if len(rest) != 3 && len(rest) != 4 {
+		return errMemorySyntax
+	}
+	start, err := memoryParseStreamID(rest[0], 0)
+	if err != nil {
+		return err
+	}
+	end, err := memoryParseStreamID(rest[1], math.MaxUint64)
+	if err != nil {
+		return err
+	}
+	count, err := memoryParseInt(rest[2])
+	if err != nil {
+		return err
+	}
+	consumer := ""
+	if len(rest) == 4 {
+		consumer = rest[3]
+	}
+
+	now := time.Now()
+	reply := []interface{}{}
+	for _, id := range memoryPendingIDs(group, consumer) {
+		if int64(len(reply)) >= count {
+			break
+		}
+		pending := group.pending[id]
+		idle := now.Sub(pending.delivered)
+		if id.less(start) || end.less(id) || idle < minIdle {
+			continue
+		}
+		reply = append(reply, []interface{}{
+			[]byte(id.String()),
+			[]byte(pending.consumer),
+			int64(idle / time.Millisecond),
+			pending.count,
+		})
+	}
+	return reply
+}
+
+// XCLAIM key group consumer min-idle-time id [id ...] [JUSTID]
+// 将空闲时间超过 min-idle-time 的未确认消息转移给 consumer
+func memoryXClaim(db *memoryDB, args []string) interface{} {
+	key, name, consumer := args[0], args[1], args[2]
+	n, err := memoryParseInt(args[3])
+	if err != nil {
+		return err
+	}
+	minIdle := time.Duration(n) * time.Millisecond
+
+	justID := false
+	ids := make([]memoryStreamID, 0, len(args)-4)
+	for _, arg := range args[4:] {
+		if strings.ToUpper(arg) == "JUSTID" {
+			justID = true
+			continue
+		}
+		if justID {
+			return errMemorySyntax
+		}
+		id, err := memoryParseStreamID(arg, 0)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	stream, group, err := memoryStreamGroupOf(db, key, name)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return memoryNoGroup(key, name)
+	}
+	group.consumers[consumer] = struct{}{}
+
+	now := time.Now()
+	reply := []interface{}{}
+	for _, id := range ids {
+		pending, exist := group.pending[id]
+		if !exist || now.Sub(pending.delivered) < minIdle {
+			continue
+		}
+		// 消息已经被删除，从未确认列表中移除
+		entry := stream.entry(id)
+		if entry == nil {
+			delete(group.pending, id)
+			continue
+		}
+
+		pending.consumer = consumer
+		pending.delivered = now
+		if justID {
+			reply = append(reply, []byte(id.String()))
+		} else {
+			pending.count++
+			reply = append(reply, entry.reply())
+		}
+	}
+	return reply
+}
+
+// memorySecondKey 第二个参数为 key，例如 XGROUP
+func memorySecondKey(db *memoryDB, args []string) []string {
+	if len(args) < 2 {
+		return nil
+	}
+	return args[1:2]
+}
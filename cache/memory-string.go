@@ -0,0 +1,232 @@
+package cache
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func memoryGet(db *memoryDB, args []string) interface{} {
+	value, exist, err := db.str(args[0])
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	return []byte(value)
+}
+
+// memorySet SET key value [EX seconds|PX milliseconds] [NX|XX] [KEEPTTL]
+func memorySet(db *memoryDB, args []string) interface{} {
+	key, value := args[0], args[1]
+
+	var (
+		nx, xx, keepTTL bool
+		expireAt        time.Time
+	)
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "EX", "PX":
+			if i+1 >= len(args) || !expireAt.IsZero() {
+				return errMemorySyntax
+			}
+			n, err := memoryParseInt(args[i+1])
+			if err != nil {
+				return err
+			}
+			if n <= 0 {
+				return memoryInvalidExpire("set")
+			}
+			unit := time.Second
+			if strings.ToUpper(args[i]) == "PX" {
+				unit = time.Millisecond
+			}
+			expireAt = time.Now().Add(time.Duration(n) * unit)
+			i++
+		default:
+			return errMemorySyntax
+		}
+	}
+	if (nx && xx) || (keepTTL && !expireAt.IsZero()) {
+		return errMemorySyntax
+	}
+
+	item := db.lookup(key)
+	if (nx && item != nil) || (xx && item == nil) {
+		return nil
+	}
+
+	if keepTTL && item != nil {
+		expireAt = item.expireAt
+	}
+	db.items[key] = &memoryItem{value: value, expireAt: expireAt}
+	return "OK"
+}
+
+func memoryInvalidExpire(cmd string) interface{} {
+	return memoryError("ERR invalid expire time in '" + cmd + "' command")
+}
+
+func memorySetNX(db *memoryDB, args []string) interface{} {
+	if db.lookup(args[0]) != nil {
+		return int64(0)
+	}
+	db.put(args[0], args[1])
+	return int64(1)
+}
+
+func memorySetEx(db *memoryDB, args []string) interface{} {
+	return memorySet(db, []string{args[0], args[2], "EX", args[1]})
+}
+
+func memoryPSetEx(db *memoryDB, args []string) interface{} {
+	return memorySet(db, []string{args[0], args[2], "PX", args[1]})
+}
+
+func memoryMGet(db *memoryDB, args []string) interface{} {
+	reply := make([]interface{}, len(args))
+	for i, key := range args {
+		value, exist, err := db.str(key)
+		if err == nil && exist {
+			reply[i] = []byte(value)
+		}
+	}
+	return reply
+}
+
+func memoryMSet(db *memoryDB, args []string) interface{} {
+	if len(args)%2 != 0 {
+		return memoryArityError("mset")
+	}
+	for i := 0; i < len(args); i += 2 {
+		db.put(args[i], args[i+1])
+	}
+	return "OK"
+}
+
+func memoryAppend(db *memoryDB, args []string) interface{} {
+	value, exist, err := db.str(args[0])
+	if err != nil {
+		return err
+	}
+	value += args[1]
+	if exist {
+		db.items[args[0]].value = value
+	} else {
+		db.put(args[0], value)
+	}
+	return int64(len(value))
+}
+
+func memoryStrlen(db *memoryDB, args []string) interface{} {
+	value, _, err := db.str(args[0])
+	if err != nil {
+		return err
+	}
+	return int64(len(value))
+}
+
+// memoryIncrbyInt 整数自增，保留 key 的生存时间
+func memoryIncrbyInt(db *memoryDB, key string, increment int64) interface{} {
+	value, exist, err := db.str(key)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	if exist {
+		if n, err = memoryParseInt(value); err != nil {
+			return err
+		}
+	}
+	if (increment > 0 && n > math.MaxInt64-increment) || (increment < 0 && n < math.MinInt64-increment) {
+		return memoryError("ERR increment or decrement would overflow")
+	}
+	n += increment
+
+	if exist {
+		db.items[key].value = strconv.FormatInt(n, 10)
+	} else {
+		db.put(key, strconv.FormatInt(n, 10))
+	}
+	return n
+}
+
+func memoryIncr(db *memoryDB, args []string) interface{} {
+	return memoryIncrbyInt(db, args[0], 1)
+}
+
+func memoryIncrby(db *memoryDB, args []string) interface{} {
+	increment, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	return memoryIncrbyInt(db, args[0], increment)
+}
+
+func memoryDecr(db *memoryDB, args []string) interface{} {
+	return memoryIncrbyInt(db, args[0], -1)
+}
+
+func memoryDecrby(db *memoryDB, args []string) interface{} {
+	decrement, err := memoryParseInt(args[1])
+	if err != nil {
+		return err
+	}
+	if decrement == math.MinInt64 {
+		return memoryError("ERR decrement would overflow")
+	}
+	return memoryIncrbyInt(db, args[0], -decrement)
+}
+
+func memoryIncrbyFloat(db *memoryDB, args []string) interface{} {
+	key := args[0]
+	increment, err := memoryParseFloat(args[1])
+	if err != nil {
+		return err
+	}
+
+	value, exist, err := db.str(key)
+	if err != nil {
+		return err
+	}
+
+	var f float64
+	if exist {
+		if f, err = memoryParseFloat(value); err != nil {
+			return err
+		}
+	}
+	f += increment
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return memoryError("ERR increment would produce NaN or Infinity")
+	}
+
+	value = memoryFormatFloat(f)
+	if exist {
+		db.items[key].value = value
+	} else {
+		db.put(key, value)
+	}
+	return []byte(value)
+}
+
+func memoryGetSet(db *memoryDB, args []string) interface{} {
+	value, exist, err := db.str(args[0])
+	if err != nil {
+		return err
+	}
+	db.put(args[0], args[1])
+	if !exist {
+		return nil
+	}
+	return []byte(value)
+}
@@ -0,0 +1,1062 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// 基于进程内存的 redis 命令实现，通过 WithMemory 启用
+// 实现了 redis.Conn 接口，Cache 的各个方法以及 Convert 的行为与连接 redis-server 时一致
+// 与 redis 一样，过期的 key 在被访问时才会删除
+
+var (
+	errMemoryWrongType  = redis.Error("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errMemoryNotInteger = redis.Error("ERR value is not an integer or out of range")
+	errMemoryNotFloat   = redis.Error("ERR value is not a valid float")
+	errMemorySyntax     = redis.Error("ERR syntax error")
+	errMemoryNoSuchKey  = redis.Error("ERR no such key")
+	errMemoryOutOfRange = redis.Error("ERR index out of range")
+	errMemoryDBIndex    = redis.Error("ERR DB index is out of range")
+	errMemoryExecAbort  = redis.Error("EXECABORT Transaction discarded because of previous errors.")
+
+	errMemoryConnClosed = errors.New("cache: memory conn closed")
+	errMemoryNoReply    = errors.New("cache: memory conn has no pending reply")
+	errMemoryTimeout    = errors.New("cache: memory conn receive timeout")
+)
+
+// memoryCommand 内存命令
+type memoryCommand struct {
+	// arity 参数数量(不包括命令名称)，负数表示至少需要 -arity 个参数
+	arity int
+	fn    func(db *memoryDB, args []string) interface{}
+	// touch 返回写命令会修改的 key，用于 WATCH，只读命令为 nil
+	touch func(db *memoryDB, args []string) []string
+}
+
+var memoryCommands = map[string]*memoryCommand{
+	// Key
+	"DEL":       {-1, memoryDel, memoryAllKeys},
+	"UNLINK":    {-1, memoryDel, memoryAllKeys},
+	"EXISTS":    {-1, memoryExists, nil},
+	"EXPIRE":    {2, memoryExpire, memoryFirstKey},
+	"EXPIREAT":  {2, memoryExpireAt, memoryFirstKey},
+	"PEXPIRE":   {2, memoryPExpire, memoryFirstKey},
+	"PEXPIREAT": {2, memoryPExpireAt, memoryFirstKey},
+	"TTL":       {1, memoryTTL, nil},
+	"PTTL":      {1, memoryPTTL, nil},
+	"PERSIST":   {1, memoryPersist, memoryFirstKey},
+	"TYPE":      {1, memoryType, nil},
+	"KEYS":      {1, memoryKeys, nil},
+	"RENAME":    {2, memoryRename, memoryTwoKeys},
+	"DBSIZE":    {0, memoryDBSize, nil},
+	"FLUSHDB":   {0, memoryFlushDB, memoryDBKeys},
+	"TIME":      {0, memoryTime, nil},
+	"SCAN":      {-1, memoryScan, nil},
+
+	// String
+	"GET":         {1, memoryGet, nil},
+	"SET":         {-2, memorySet, memoryFirstKey},
+	"SETNX":       {2, memorySetNX, memoryFirstKey},
+	"SETEX":       {3, memorySetEx, memoryFirstKey},
+	"PSETEX":      {3, memoryPSetEx, memoryFirstKey},
+	"MGET":        {-1, memoryMGet, nil},
+	"MSET":        {-2, memoryMSet, memoryPairKeys},
+	"APPEND":      {2, memoryAppend, memoryFirstKey},
+	"STRLEN":      {1, memoryStrlen, nil},
+	"INCR":        {1, memoryIncr, memoryFirstKey},
+	"INCRBY":      {2, memoryIncrby, memoryFirstKey},
+	"INCRBYFLOAT": {2, memoryIncrbyFloat, memoryFirstKey},
+	"DECR":        {1, memoryDecr, memoryFirstKey},
+	"DECRBY":      {2, memoryDecrby, memoryFirstKey},
+	"GETSET":      {2, memoryGetSet, memoryFirstKey},
+
+	// Hash
+	"HGET":         {2, memoryHGet, nil},
+	"HSET":         {-3, memoryHSet, memoryFirstKey},
+	"HSETNX":       {3, memoryHSetNX, memoryFirstKey},
+	"HMSET":        {-3, memoryHMSet, memoryFirstKey},
+	"HMGET":        {-2, memoryHMGet, nil},
+	"HGETALL":      {1, memoryHGetAll, nil},
+	"HSCAN":        {-2, memoryHScan, nil},
+	"HEXISTS":      {2, memoryHExists, nil},
+	"HDEL":         {-2, memoryHDel, memoryFirstKey},
+	"HLEN":         {1, memoryHLen, nil},
+	"HKEYS":        {1, memoryHKeys, nil},
+	"HVALS":        {1, memoryHVals, nil},
+	"HINCRBY":      {3, memoryHIncrby, memoryFirstKey},
+	"HINCRBYFLOAT": {3, memoryHIncrbyFloat, memoryFirstKey},
+
+	// List
+	"LPUSH":     {-2, memoryLPush, memoryFirstKey},
+	"RPUSH":     {-2, memoryRPush, memoryFirstKey},
+	"LPOP":      {-1, memoryLPop, memoryFirstKey},
+	"RPOP":      {-1, memoryRPop, memoryFirstKey},
+	"RPOPLPUSH": {2, memoryRPopLPush, memoryTwoKeys},
+	"LTRIM":     {3, memoryLTrim, memoryFirstKey},
+	"LSET":      {3, memoryLSet, memoryFirstKey},
+	"LREM":      {3, memoryLRem, memoryFirstKey},
+	"LRANGE":    {3, memoryLRange, nil},
+	"LLEN":      {1, memoryLLen, nil},
+	"LINSERT":   {4, memoryLInsert, memoryFirstKey},
+	"LINDEX":    {2, memoryLIndex, nil},
+
+	// Set
+	"SADD":        {-2, memorySAdd, memoryFirstKey},
+	"SCARD":       {1, memorySCard, nil},
+	"SDIFF":       {-1, memorySDiff, nil},
+	"SDIFFSTORE":  {-2, memorySDiffStore, memoryFirstKey},
+	"SUNION":      {-1, memorySUnion, nil},
+	"SUNIONSTORE": {-2, memorySUnionStore, memoryFirstKey},
+	"SINTER":      {-1, memorySInter, nil},
+	"SINTERSTORE": {-2, memorySInterStore, memoryFirstKey},
+	"SISMEMBER":   {2, memorySIsMember, nil},
+	"SMEMBERS":    {1, memorySMembers, nil},
+	"SPOP":        {-1, memorySPop, memoryFirstKey},
+	"SRANDMEMBER": {-1, memorySRandMember, nil},
+	"SREM":        {-2, memorySRem, memoryFirstKey},
+	"SSCAN":       {-2, memorySScan, nil},
+	"SMOVE":       {3, memorySMove, memoryTwoKeys},
+
+	// SortedSet
+	"ZADD":             {-3, memoryZAdd, memoryFirstKey},
+	"ZCARD":            {1, memoryZCard, nil},
+	"ZCOUNT":           {3, memoryZCount, nil},
+	"ZINCRBY":          {3, memoryZIncrby, memoryFirstKey},
+	"ZRANGE":           {-3, memoryZRange, nil},
+	"ZREVRANGE":        {-3, memoryZRevRange, nil},
+	"ZSCORE":           {2, memoryZScore, nil},
+	"ZRANK":            {2, memoryZRank, nil},
+	"ZREVRANK":         {2, memoryZRevRank, nil},
+	"ZRANGEBYSCORE":    {-3, memoryZRangeByScore, nil},
+	"ZREVRANGEBYSCORE": {-3, memoryZRevRangeByScore, nil},
+	"ZREMRANGEBYSCORE": {3, memoryZRemRangeByScore, memoryFirstKey},
+	"ZREMRANGEBYRANK":  {3, memoryZRemRangeByRank, memoryFirstKey},
+	"ZREM":             {-2, memoryZRem, memoryFirstKey},
+	"ZUNIONSTORE":      {-3, memoryZUnionStore, memoryFirstKey},
+	"ZINTERSTORE":      {-3, memoryZInterStore, memoryFirstKey},
+	"ZPOPMIN":          {-1, memoryZPopMin, memoryFirstKey},
+	"ZPOPMAX":          {-1, memoryZPopMax, memoryFirstKey},
+	"BZPOPMIN":         {-2, memoryBZPopMin, memoryBZPopKey},
+	"BZPOPMAX":         {-2, memoryBZPopMax, memoryBZPopKey},
+	"ZLEXCOUNT":        {3, memoryZLexCount, nil},
+	"ZSCAN":            {-2, memoryZScan, nil},
+
+	// Stream
+	"XADD":   {-4, memoryXAdd, memoryFirstKey},
+	"XLEN":   {1, memoryXLen, nil},
+	"XRANGE": {-3, memoryXRange, nil},
+	"XDEL":   {-2, memoryXDel, memoryFirstKey},
+	"XTRIM":  {-3, memoryXTrim, memoryFirstKey},
+	"XGROUP": {-3, memoryXGroup, memorySecondKey},
+	// XREADGROUP 会修改未确认列表，但是不更新 key 的版本，避免多个阻塞的读取互相唤醒
+	"XREADGROUP": {-6, memoryXReadGroup, nil},
+	"XACK":       {-3, memoryXAck, memoryFirstKey},
+	"XPENDING":   {-2, memoryXPending, nil},
+	"XCLAIM":     {-5, memoryXClaim, memoryFirstKey},
+}
+
+// memoryStore 内存数据，所有连接共享
+type memoryStore struct {
+	mu  sync.Mutex
+	dbs map[int]*memoryDB
+	// signal 有写命令执行时关闭并重新创建，用于唤醒阻塞的命令
+	signal chan struct{}
+
+	// channels, patterns 订阅了频道、模式的连接
+	channels map[string]map[*memoryConn]struct{}
+	patterns map[string]map[*memoryConn]struct{}
+
+	// scripts SCRIPT LOAD 以及 EVAL 缓存的脚本，key 为 sha1
+	scripts map[string]string
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		dbs:      make(map[int]*memoryDB),
+		signal:   make(chan struct{}),
+		channels: make(map[string]map[*memoryConn]struct{}),
+		patterns: make(map[string]map[*memoryConn]struct{}),
+		scripts:  make(map[string]string),
+	}
+}
+
+// notify 唤醒所有阻塞的命令
+func (s *memoryStore) notify() {
+	close(s.signal)
+	s.signal = make(chan struct{})
+}
+
+func (s *memoryStore) db(index int) *memoryDB {
+	db, exist := s.dbs[index]
+	if !exist {
+		db = &memoryDB{
+			items:    make(map[string]*memoryItem),
+			versions: make(map[string]uint64),
+		}
+		s.dbs[index] = db
+	}
+	return db
+}
+
+// memoryDB 对应 redis 中的一个数据库
+type memoryDB struct {
+	items map[string]*memoryItem
+	// versions key 被写命令修改的次数，用于 WATCH
+	versions map[string]uint64
+}
+
+// memoryItem 存储的值
+// value 的类型为 string, memoryHashValue, *memoryListValue, memorySetValue, memorySortedSetValue, *memoryStreamValue 之一
+type memoryItem struct {
+	value interface{}
+	// expireAt 过期时间，零值表示永久
+	expireAt time.Time
+}
+
+type memoryHashValue map[string]string
+
+type memoryListValue struct {
+	values []string
+}
+
+type memorySetValue map[string]struct{}
+
+type memorySortedSetValue map[string]float64
+
+// lookup 获取未过期的 key，已经过期的 key 会被删除
+func (db *memoryDB) lookup(key string) *memoryItem {
+	item, exist := db.items[key]
+	if !exist {
+		return nil
+	}
+	if !item.expireAt.IsZero() && !time.Now().Before(item.expireAt) {
+		delete(db.items, key)
+		return nil
+	}
+	return item
+}
+
+// put 设置 key 的值，同时清除生存时间
+func (db *memoryDB) put(key string, value interface{}) {
+	db.items[key] = &memoryItem{value: value}
+}
+
+// str 获取字符串，exist 为 false 表示 key 不存在
+func (db *memoryDB) str(key string) (value string, exist bool, err error) {
+	item := db.lookup(key)
+	if item == nil {
+		return "", false, nil
+	}
+	value, ok := item.value.(string)
+	if !ok {
+		return "", false, errMemoryWrongType
+	}
+	return value, true, nil
+}
+
+// hash 获取哈希表，key 不存在时，create 为 true 则创建，否则返回 nil
+func (db *memoryDB) hash(key string, create bool) (memoryHashValue, error) {
+	item := db.lookup(key)
+	if item == nil {
+		if !create {
+			return nil, nil
+		}
+		hash := memoryHashValue{}
+		db.put(key, hash)
+		return hash, nil
+	}
+	hash, ok := item.value.(memoryHashValue)
+	if !ok {
+		return nil, errMemoryWrongType
+	}
+	return hash, nil
+}
+
+// list 获取列表，key 不存在时，create 为 true 则创建，否则返回 nil
+func (db *memoryDB) list(key string, create bool) (*memoryListValue, error) {
+	item := db.lookup(key)
+	if item == nil {
+		if !create {
+			return nil, nil
+		}
+		list := &memoryListValue{}
+		db.put(key, list)
+		return list, nil
+	}
+	list, ok := item.value.(*memoryListValue)
+	if !ok {
+		return nil, errMemoryWrongType
+	}
+	return list, nil
+}
+
+// set 获取集合，key 不存在时，create 为 true 则创建，否则返回 nil
+func (db *memoryDB) set(key string, create bool) (memorySetValue, error) {
+	item := db.lookup(key)
+	if item == nil {
+		if !create {
+			return nil, nil
+		}
+		set := memorySetValue{}
+		db.put(key, set)
+		return set, nil
+	}
+	set, ok := item.value.(memorySetValue)
+	if !ok {
+		return nil, errMemoryWrongType
+	}
+	return set, nil
+}
+
+// sortedSet 获取有序集合，key 不存在时，create 为 true 则创建，否则返回 nil
+func (db *memoryDB) sortedSet(key string, create bool) (memorySortedSetValue, error) {
+	item := db.lookup(key)
+	if item == nil {
+		if !create {
+			return nil, nil
+		}
+		zset := memorySortedSetValue{}
+		db.put(key, zset)
+		return zset, nil
+	}
+	zset, ok := item.value.(memorySortedSetValue)
+	if !ok {
+		return nil, errMemoryWrongType
+	}
+	return zset, nil
+}
+
+// stream 获取消息流，key 不存在时，create 为 true 则创建，否则返回 nil
+func (db *memoryDB) stream(key string, create bool) (*memoryStreamValue, error) {
+	item := db.lookup(key)
+	if item == nil {
+		if !create {
+			return nil, nil
+		}
+		stream := &memoryStreamValue{groups: make(map[string]*memoryStreamGroup)}
+		db.put(key, stream)
+		return stream, nil
+	}
+	stream, ok := item.value.(*memoryStreamValue)
+	if !ok {
+		return nil, errMemoryWrongType
+	}
+	return stream, nil
+}
+
+// touch 标记 key 已被修改，监视这些 key 的事务将会失败
+func (db *memoryDB) touch(keys ...string) {
+	for _, key := range keys {
+		db.versions[key]++
+	}
+}
+
+// removeEmpty 与 redis 一致，容器类型的 key 为空时删除
+func (db *memoryDB) removeEmpty(key string) {
+	item, exist := db.items[key]
+	if !exist {
+		return
+	}
+
+	var size int
+	switch v := item.value.(type) {
+	case memoryHashValue:
+		size = len(v)
+	case *memoryListValue:
+		size = len(v.values)
+	case memorySetValue:
+		size = len(v)
+	case memorySortedSetValue:
+		size = len(v)
+	default:
+		return
+	}
+	if size == 0 {
+		delete(db.items, key)
+	}
+}
+
+// memoryConn 内存连接，实现 redis.Conn 以及 redis.ConnWithTimeout
+// 与 redigo 一致，允许一个 goroutine 调用 Receive 的同时，另一个 goroutine 调用 Send, Flush
+type memoryConn struct {
+	store *memoryStore
+	db    int
+
+	// mu 保护 pending, closed, subscribed
+	mu sync.Mutex
+	// pending Send 之后尚未 Receive 的回复，以及订阅收到的消息
+	pending []interface{}
+	closed  bool
+	// subscribed 订阅的频道和模式的数量，大于 0 时 Receive 会阻塞等待消息
+	subscribed int
+	// ready 有新的回复或者连接关闭时唤醒 Receive
+	ready chan struct{}
+	// done 连接关闭时关闭，唤醒阻塞的命令
+	done chan struct{}
+
+	// 以下字段由 store.mu 保护
+	// multi 处于 MULTI 状态时，命令进入 queued 队列，EXEC 时一起执行
+	multi  bool
+	queued []memoryQueued
+	// aborted 入队时出现错误，EXEC 时放弃执行
+	aborted bool
+	// watched WATCH 时 key 的版本
+	watched map[memoryWatchKey]uint64
+	// channels, patterns 当前连接订阅的频道和模式
+	channels map[string]struct{}
+	patterns map[string]struct{}
+}
+
+type memoryQueued struct {
+	command *memoryCommand
+	args    []string
+}
+
+type memoryWatchKey struct {
+	db  int
+	key string
+}
+
+// memoryBlock 阻塞命令没有数据时返回，表示需要等待的时间，0 表示一直等待
+type memoryBlock time.Duration
+
+func newMemoryConn(store *memoryStore) *memoryConn {
+	return &memoryConn{
+		store:    store,
+		ready:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+	}
+}
+
+// Close ..
+func (c *memoryConn) Close() error {
+	c.store.mu.Lock()
+	c.reset()
+	c.unsubscribe("UNSUBSCRIBE", nil)
+	c.unsubscribe("PUNSUBSCRIBE", nil)
+	c.store.mu.Unlock()
+
+	c.kill()
+	return nil
+}
+
+// kill 将连接标记为关闭，唤醒阻塞的 Receive 以及命令
+func (c *memoryConn) kill() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		c.pending = nil
+		close(c.done)
+		c.wake()
+	}
+}
+
+// Err ..
+func (c *memoryConn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errMemoryConnClosed
+	}
+	return nil
+}
+
+// Do 与 redigo 一致，cmd 为空时返回所有未读取的回复
+func (c *memoryConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return c.DoWithTimeout(0, cmd, args...)
+}
+
+// DoWithTimeout 阻塞命令的等待时间由命令自身的参数决定，忽略 timeout
+func (c *memoryConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, errMemoryConnClosed
+	}
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	if cmd == "" {
+		if len(pending) == 0 {
+			return nil, nil
+		}
+		return pending, nil
+	}
+
+	var err error
+	for _, reply := range pending {
+		if e, ok := reply.(redis.Error); ok && err == nil {
+			err = e
+		}
+	}
+
+	reply := c.exec(cmd, args, false)
+	if reply == errMemoryConnClosed {
+		return nil, errMemoryConnClosed
+	}
+	if replies, ok := reply.(memoryReplies); ok {
+		reply = replies[len(replies)-1]
+	}
+	if e, ok := reply.(redis.Error); ok && err == nil {
+		err = e
+	}
+	return reply, err
+}
+
+// Send 命令立即执行，回复通过 Receive 读取
+func (c *memoryConn) Send(cmd string, args ...interface{}) error {
+	if err := c.Err(); err != nil {
+		return err
+	}
+	c.exec(cmd, args, true)
+	return nil
+}
+
+// Flush ..
+func (c *memoryConn) Flush() error {
+	return c.Err()
+}
+
+// Receive ..
+func (c *memoryConn) Receive() (interface{}, error) {
+	return c.ReceiveWithTimeout(0)
+}
+
+// ReceiveWithTimeout 处于订阅状态并且没有回复时阻塞等待，timeout 为 0 表示一直等待
+func (c *memoryConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return nil, errMemoryConnClosed
+		}
+		if len(c.pending) > 0 {
+			reply := c.pending[0]
+			c.pending = c.pending[1:]
+			c.mu.Unlock()
+			if err, ok := reply.(redis.Error); ok {
+				return nil, err
+			}
+			return reply, nil
+		}
+		subscribed := c.subscribed > 0
+		c.mu.Unlock()
+
+		if !subscribed {
+			return nil, errMemoryNoReply
+		}
+		select {
+		case <-c.ready:
+		case <-expired:
+			return nil, errMemoryTimeout
+		}
+	}
+}
+
+// push 将回复加入 pending 并唤醒 Receive
+func (c *memoryConn) push(reply interface{}) {
+	c.mu.Lock()
+	if !c.closed {
+		if replies, ok := reply.(memoryReplies); ok {
+			c.pending = append(c.pending, replies...)
+		} else {
+			c.pending = append(c.pending, reply)
+		}
+		c.wake()
+	}
+	c.mu.Unlock()
+}
+
+func (c *memoryConn) wake() {
+	select {
+	case c.ready <- struct{}{}:
+	default:
+	}
+}
+
+// exec 执行命令，返回值的类型与 redigo 解析 redis-server 回复的类型一致
+// queue 为 true 时，在释放 store.mu 之前将回复加入 pending，保证与订阅消息的顺序一致
+func (c *memoryConn) exec(cmd string, args []interface{}, queue bool) interface{} {
+	name := strings.ToUpper(cmd)
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = memoryArg(arg)
+	}
+
+	s := c.store
+	var deadline time.Time
+	for {
+		s.mu.Lock()
+		reply := c.execLocked(cmd, name, values)
+		signal := s.signal
+
+		block, blocked := reply.(memoryBlock)
+		if blocked && block > 0 {
+			if deadline.IsZero() {
+				deadline = time.Now().Add(time.Duration(block))
+			} else if !time.Now().Before(deadline) {
+				reply, blocked = nil, false
+			}
+		}
+		if !blocked {
+			if queue {
+				c.push(reply)
+			}
+			s.mu.Unlock()
+			return reply
+		}
+		s.mu.Unlock()
+
+		// 等待写命令执行之后重试
+		if !c.wait(signal, deadline) {
+			return errMemoryConnClosed
+		}
+	}
+}
+
+// wait 等待 signal 被关闭或者到达 deadline，deadline 为零值时一直等待
+// 连接被关闭时返回 false
+func (c *memoryConn) wait(signal chan struct{}, deadline time.Time) bool {
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-signal:
+	case <-expired:
+	case <-c.done:
+		return false
+	}
+	return true
+}
+
+func (c *memoryConn) execLocked(cmd, name string, values []string) interface{} {
+	s := c.store
+
+	// 订阅状态下只允许执行订阅相关的命令
+	if len(c.channels)+len(c.patterns) > 0 {
+		switch name {
+		case "SUBSCRIBE", "PSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE", "PING":
+		default:
+			return memoryError(fmt.Sprintf("ERR Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context", strings.ToLower(cmd)))
+		}
+	}
+
+	var command *memoryCommand
+
+	// 与连接、事务以及订阅相关的命令
+	switch name {
+	case "PING":
+		if len(c.channels)+len(c.patterns) > 0 {
+			data := ""
+			if len(values) > 0 {
+				data = values[0]
+			}
+			return []interface{}{[]byte("pong"), []byte(data)}
+		}
+		if len(values) == 0 {
+			return "PONG"
+		}
+		return []byte(values[0])
+	case "ECHO":
+		if len(values) != 1 {
+			return memoryArityError(cmd)
+		}
+		return []byte(values[0])
+	case "AUTH":
+		return "OK"
+	case "SELECT":
+		if len(values) != 1 {
+			return memoryArityError(cmd)
+		}
+		index, err := strconv.Atoi(values[0])
+		if err != nil || index < 0 {
+			return errMemoryDBIndex
+		}
+		c.db = index
+		return "OK"
+	case "FLUSHALL":
+		for _, db := range s.dbs {
+			db.touch(memoryDBKeys(db, nil)...)
+			db.items = make(map[string]*memoryItem)
+		}
+		return "OK"
+	case "MULTI":
+		if c.multi {
+			return memoryError("ERR MULTI calls can not be nested")
+		}
+		c.multi = true
+		return "OK"
+	case "EXEC":
+		return c.execMulti()
+	case "DISCARD":
+		if !c.multi {
+			return memoryError("ERR DISCARD without MULTI")
+		}
+		c.reset()
+		return "OK"
+	case "WATCH":
+		if c.multi {
+			return memoryError("ERR WATCH inside MULTI is not allowed")
+		}
+		if len(values) == 0 {
+			return memoryArityError(cmd)
+		}
+		if c.watched == nil {
+			c.watched = make(map[memoryWatchKey]uint64)
+		}
+		db := s.db(c.db)
+		for _, key := range values {
+			watchKey := memoryWatchKey{db: c.db, key: key}
+			if _, exist := c.watched[watchKey]; !exist {
+				c.watched[watchKey] = db.versions[key]
+			}
+		}
+		return "OK"
+	case "UNWATCH":
+		c.watched = nil
+		return "OK"
+	case "SUBSCRIBE", "PSUBSCRIBE":
+		if len(values) == 0 {
+			return memoryArityError(cmd)
+		}
+		return c.subscribe(name, values)
+	case "UNSUBSCRIBE", "PUNSUBSCRIBE":
+		return c.unsubscribe(name, values)
+	case "CLIENT":
+		// 只支持 CLIENT KILL TYPE pubsub，用于模拟订阅连接断开
+		if len(values) != 3 || strings.ToUpper(values[0]) != "KILL" ||
+			strings.ToUpper(values[1]) != "TYPE" || strings.ToLower(values[2]) != "pubsub" {
+			return errMemorySyntax
+		}
+		return s.killSubscribers()
+	case "SCRIPT":
+		return s.script(cmd, values)
+	case "EVAL":
+		command = &memoryCommand{-2, func(db *memoryDB, args []string) interface{} {
+			return s.eval(args)
+		}, nil}
+	case "EVALSHA":
+		command = &memoryCommand{-2, func(db *memoryDB, args []string) interface{} {
+			return s.evalSha(args)
+		}, nil}
+	case "PUBLISH":
+		// PUBLISH 与数据库无关，但是可以在事务中执行
+		command = &memoryCommand{2, func(db *memoryDB, args []string) interface{} {
+			return s.publish(args[0], args[1])
+		}, nil}
+	}
+
+	if command == nil {
+		var exist bool
+		if command, exist = memoryCommands[name]; !exist {
+			c.aborted = c.multi
+			return memoryError(fmt.Sprintf("ERR unknown command '%s'", cmd))
+		}
+	}
+	if (command.arity >= 0 && len(values) != command.arity) ||
+		(command.arity < 0 && len(values) < -command.arity) {
+		c.aborted = c.multi
+		return memoryArityError(cmd)
+	}
+
+	if c.multi {
+		c.queued = append(c.queued, memoryQueued{command: command, args: values})
+		return "QUEUED"
+	}
+	return c.call(command, values)
+}
+
+// call 执行命令表中的命令，写命令会更新 key 的版本，并唤醒阻塞的命令
+func (c *memoryConn) call(command *memoryCommand, args []string) interface{} {
+	db := c.store.db(c.db)
+	// 没有修改任何 key 时不通知，避免阻塞命令之间互相唤醒
+	if command.touch != nil {
+		if keys := command.touch(db, args); len(keys) > 0 {
+			db.touch(keys...)
+			defer c.store.notify()
+		}
+	}
+	return command.fn(db, args)
+}
+
+// execMulti 执行 MULTI 之后入队的命令
+// 被 WATCH 的 key 发生变化时返回 nil，与 redis 一致
+func (c *memoryConn) execMulti() interface{} {
+	if !c.multi {
+		return memoryError("ERR EXEC without MULTI")
+	}
+	defer c.reset()
+
+	if c.aborted {
+		return errMemoryExecAbort
+	}
+	for watchKey, version := range c.watched {
+		if c.store.db(watchKey.db).versions[watchKey.key] != version {
+			return nil
+		}
+	}
+
+	replies := make([]interface{}, len(c.queued))
+	for i, queued := range c.queued {
+		replies[i] = c.call(queued.command, queued.args)
+		// 事务中的阻塞命令不会阻塞
+		if _, ok := replies[i].(memoryBlock); ok {
+			replies[i] = nil
+		}
+	}
+	return replies
+}
+
+// reset 退出 MULTI 状态，并取消 WATCH
+func (c *memoryConn) reset() {
+	c.multi = false
+	c.queued = nil
+	c.aborted = false
+	c.watched = nil
+}
+
+// memoryFirstKey 写命令只修改第一个 key
+func memoryFirstKey(db *memoryDB, args []string) []string {
+	return args[:1]
+}
+
+// memoryTwoKeys 写命令修改前两个 key，例如 RENAME, SMOVE
+func memoryTwoKeys(db *memoryDB, args []string) []string {
+	return args[:2]
+}
+
+// memoryAllKeys 所有参数都是 key，例如 DEL
+func memoryAllKeys(db *memoryDB, args []string) []string {
+	return args
+}
+
+// memoryPairKeys key value 交替出现，例如 MSET
+func memoryPairKeys(db *memoryDB, args []string) []string {
+	keys := make([]string, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		keys = append(keys, args[i])
+	}
+	return keys
+}
+
+// memoryDBKeys 数据库中所有的 key，例如 FLUSHDB
+func memoryDBKeys(db *memoryDB, args []string) []string {
+	keys := make([]string, 0, len(db.items))
+	for key := range db.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func memoryError(msg string) redis.Error {
+	return redis.Error(msg)
+}
+
+func isMemoryError(reply interface{}) bool {
+	_, ok := reply.(redis.Error)
+	return ok
+}
+
+func memoryArityError(cmd string) redis.Error {
+	return memoryError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
+
+// memoryArg 将参数转为字符串，规则与 redigo 写入参数时一致
+func memoryArg(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case nil:
+		return ""
+	case redis.Argument:
+		return memoryArg(v.RedisArg())
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// memoryBulks 将字符串数组转为 redis 多条批量回复
+func memoryBulks(values []string) []interface{} {
+	reply := make([]interface{}, len(values))
+	for i, value := range values {
+		reply[i] = []byte(value)
+	}
+	return reply
+}
+
+func memoryBool(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func memoryFormatFloat(f float64) string {
+	return formatFloat(f)
+}
+
+func memoryParseInt(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errMemoryNotInteger
+	}
+	return n, nil
+}
+
+func memoryParseFloat(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, errMemoryNotFloat
+	}
+	return f, nil
+}
+
+// memoryRange 将 redis 风格的下标区间 (可以为负数) 转为 [start, stop]
+// ok 为 false 表示区间为空
+func memoryRange(start, stop int64, length int) (int, int, bool) {
+	size := int64(length)
+	if start < 0 {
+		start += size
+	}
+	if stop < 0 {
+		stop += size
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= size {
+		stop = size - 1
+	}
+	if start > stop || start >= size {
+		return 0, 0, false
+	}
+	return int(start), int(stop), true
+}
+
+// memorySortedKeys 返回排序后的 key，保证结果稳定
+func memorySortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// memoryMatch glob 风格的匹配，与 redis KEYS 命令一致
+// 支持 *, ?, [abc], [^a], [a-z] 以及 \ 转义
+func memoryMatch(pattern, str string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(str); i++ {
+				if memoryMatch(pattern[1:], str[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(str) == 0 {
+				return false
+			}
+			str = str[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(str) == 0 {
+				return false
+			}
+			pattern = pattern[1:]
+			not := len(pattern) > 0 && pattern[0] == '^'
+			if not {
+				pattern = pattern[1:]
+			}
+			match := false
+			for len(pattern) > 0 && pattern[0] != ']' {
+				switch {
+				case pattern[0] == '\\' && len(pattern) >= 2:
+					if pattern[1] == str[0] {
+						match = true
+					}
+					pattern = pattern[2:]
+				case len(pattern) >= 3 && pattern[1] == '-' && pattern[2] != ']':
+					low, high := pattern[0], pattern[2]
+					if low > high {
+						low, high = high, low
+					}
+					if str[0] >= low && str[0] <= high {
+						match = true
+					}
+					pattern = pattern[3:]
+				default:
+					if pattern[0] == str[0] {
+						match = true
+					}
+					pattern = pattern[1:]
+				}
+			}
+			if not {
+				match = !match
+			}
+			if !match {
+				return false
+			}
+			str = str[1:]
+			if len(pattern) > 0 {
+				// 跳过 ]
+				pattern = pattern[1:]
+			}
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(str) == 0 || pattern[0] != str[0] {
+				return false
+			}
+			str = str[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(str) == 0
+}
@@ -0,0 +1,278 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func newMemoryCache(t *testing.T) Cache {
+	c := NewCache(WithMemory())
+	if err := c.Open(); err != nil {
+		t.Fatalf("open memory cache failed: %s", err.Error())
+	}
+	return c
+}
+
+func TestMemoryString(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	if _, err := c.Get("hello"); err != ErrNil {
+		t.Fatalf("Get missing key, err: %v", err)
+	}
+
+	if err := c.Set("hello", "world"); err != nil {
+		t.Fatalf("Set failed: %s", err.Error())
+	}
+	if v, err := c.Get("hello"); err != nil || v != "world" {
+		t.Fatalf("Get failed, v: %s, err: %v", v, err)
+	}
+
+	if n, err := c.Append("hello", "!"); err != nil || n != 6 {
+		t.Fatalf("Append failed, n: %d, err: %v", n, err)
+	}
+
+	c.Set("num", 3)
+	if n, err := c.Incrby("num", 7); err != nil || n != 10 {
+		t.Fatalf("Incrby failed, n: %d, err: %v", n, err)
+	}
+	if n, err := c.Decr("num"); err != nil || n != 9 {
+		t.Fatalf("Decr failed, n: %d, err: %v", n, err)
+	}
+	if _, err := c.Incr("hello"); err == nil {
+		t.Fatal("Incr on non integer should fail")
+	}
+
+	if err := c.MSet("k1", "v1", "k2", "v2"); err != nil {
+		t.Fatalf("MSet failed: %s", err.Error())
+	}
+	if vs, err := c.MGet("k1", "k2"); err != nil || len(vs) != 2 || vs[1] != "v2" {
+		t.Fatalf("MGet failed, vs: %v, err: %v", vs, err)
+	}
+
+	if old, err := c.GetSet("k1", "v3"); err != nil || old != "v1" {
+		t.Fatalf("GetSet failed, old: %s, err: %v", old, err)
+	}
+
+	if n, err := c.Del("k1", "k2", "k3"); err != nil || n != 2 {
+		t.Fatalf("Del failed, n: %d, err: %v", n, err)
+	}
+}
+
+func TestMemoryExpire(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	if ttl, _ := c.TTL("hello"); ttl != -2 {
+		t.Fatalf("TTL of missing key: %d", ttl)
+	}
+
+	c.Set("hello", "world")
+	if ttl, _ := c.TTL("hello"); ttl != -1 {
+		t.Fatalf("TTL of persistent key: %d", ttl)
+	}
+
+	c.SetEx("hello", "world", "10")
+	if ttl, _ := c.TTL("hello"); ttl <= 0 || ttl > 10 {
+		t.Fatalf("TTL after SetEx: %d", ttl)
+	}
+
+	c.PSetEx("hello", "world", "20")
+	if ttl, _ := c.PTTL("hello"); ttl <= 0 || ttl > 20 {
+		t.Fatalf("PTTL after PSetEx: %d", ttl)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if exist, _ := c.Exists("hello"); exist {
+		t.Fatal("key should be expired")
+	}
+
+	c.Set("hello", "world")
+	if ok, _ := c.PExpire("hello", "20"); !ok {
+		t.Fatal("PExpire failed")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := c.Get("hello"); err != ErrNil {
+		t.Fatalf("Get expired key, err: %v", err)
+	}
+}
+
+func TestMemoryHash(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	if err := c.HMSet("user", "name", "alex", "age", 18); err != nil {
+		t.Fatalf("HMSet failed: %s", err.Error())
+	}
+	c.HSet("user", "city", "shenzhen")
+
+	if n, _ := c.HLen("user"); n != 3 {
+		t.Fatalf("HLen: %d", n)
+	}
+	if v, _ := c.HGet("user", "name"); v != "alex" {
+		t.Fatalf("HGet: %s", v)
+	}
+	if _, err := c.HGet("user", "none"); err != ErrNil {
+		t.Fatalf("HGet missing field, err: %v", err)
+	}
+	if exist, _ := c.HExists("user", "city"); !exist {
+		t.Fatal("HExists failed")
+	}
+	if n, _ := c.HIncrby("user", "age", 2); n != 20 {
+		t.Fatalf("HIncrby: %d", n)
+	}
+	if n, _ := c.HDel("user", "name", "none"); n != 1 {
+		t.Fatalf("HDel: %d", n)
+	}
+	if vs, _ := c.HGetAll("user"); len(vs) != 4 {
+		t.Fatalf("HGetAll: %v", vs)
+	}
+
+	if _, err := c.Get("user"); err == nil {
+		t.Fatal("Get on hash should fail with WRONGTYPE")
+	}
+}
+
+func TestMemoryList(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	c.RPush("list", "b", "c")
+	c.LPush("list", "a")
+
+	if vs, _ := c.LRange("list", 0, -1); len(vs) != 3 || vs[0] != "a" || vs[2] != "c" {
+		t.Fatalf("LRange: %v", vs)
+	}
+	if n, _ := c.LInsertAfter("list", "a", "x"); n != 4 {
+		t.Fatalf("LInsertAfter: %d", n)
+	}
+	if v, _ := c.LIndex("list", 1); v != "x" {
+		t.Fatalf("LIndex: %s", v)
+	}
+	if n, _ := c.LRem("list", 0, "x"); n != 1 {
+		t.Fatalf("LRem: %d", n)
+	}
+	if v, _ := c.RPopLPush("list", "other"); v != "c" {
+		t.Fatalf("RPopLPush: %s", v)
+	}
+	if v, _ := c.LPop("list"); v != "a" {
+		t.Fatalf("LPop: %s", v)
+	}
+	if v, _ := c.RPop("list"); v != "b" {
+		t.Fatalf("RPop: %s", v)
+	}
+	if _, err := c.LPop("list"); err != ErrNil {
+		t.Fatalf("LPop on empty list, err: %v", err)
+	}
+	if exist, _ := c.Exists("list"); exist {
+		t.Fatal("empty list should be removed")
+	}
+}
+
+func TestMemorySet(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	if n, _ := c.SAdd("s1", "a", "b", "c", "a"); n != 3 {
+		t.Fatalf("SAdd: %d", n)
+	}
+	c.SAdd("s2", "b", "c", "d")
+
+	if vs, _ := c.SInter("s1", "s2"); len(vs) != 2 || vs[0] != "b" {
+		t.Fatalf("SInter: %v", vs)
+	}
+	if vs, _ := c.SDiff("s1", "s2"); len(vs) != 1 || vs[0] != "a" {
+		t.Fatalf("SDiff: %v", vs)
+	}
+	if n, _ := c.SUnionStore("s3", "s1", "s2"); n != 4 {
+		t.Fatalf("SUnionStore: %d", n)
+	}
+	if ok, _ := c.SIsMember("s3", "d"); !ok {
+		t.Fatal("SIsMember failed")
+	}
+	if n, _ := c.SRem("s3", "a", "z"); n != 1 {
+		t.Fatalf("SRem: %d", n)
+	}
+	if vs, _ := c.SRandMember("s3", 10); len(vs) != 3 {
+		t.Fatalf("SRandMember: %v", vs)
+	}
+}
+
+func TestMemorySortedSet(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	if n, _ := c.ZAdd("rank", 99, "m1", 60, "m2", 88, "m3"); n != 3 {
+		t.Fatalf("ZAdd: %d", n)
+	}
+	if n, _ := c.ZCard("rank"); n != 3 {
+		t.Fatalf("ZCard: %d", n)
+	}
+	if n, _ := c.ZCount("rank", "80", "100"); n != 2 {
+		t.Fatalf("ZCount: %d", n)
+	}
+	if vs, _ := c.ZRange("rank", 0, -1); len(vs) != 3 || vs[0] != "m2" || vs[2] != "m1" {
+		t.Fatalf("ZRange: %v", vs)
+	}
+	if n, _ := c.ZRank("rank", "m3"); n != 1 {
+		t.Fatalf("ZRank: %d", n)
+	}
+	if n, _ := c.ZRevRank("rank", "m3"); n != 1 {
+		t.Fatalf("ZRevRank: %d", n)
+	}
+	if n, _ := c.ZIncrby("rank", "m2", 40); n != 100 {
+		t.Fatalf("ZIncrby: %v", n)
+	}
+	if n, _ := c.ZRemRangeByScore("rank", "0", "90"); n != 1 {
+		t.Fatalf("ZRemRangeByScore: %d", n)
+	}
+	if _, err := c.ZRank("rank", "none"); err != ErrNil {
+		t.Fatalf("ZRank missing member, err: %v", err)
+	}
+}
+
+func TestMemoryConn(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	conn := c.Conn()
+	defer conn.Close()
+
+	conn.Send("SET", "a", 1)
+	conn.Send("INCR", "a")
+	conn.Send("GET", "a")
+	conn.Flush()
+
+	conn.Receive()
+	conn.Receive()
+	v, err := c.String(conn.Receive())
+	if err != nil || v != "2" {
+		t.Fatalf("pipeline reply: %s, err: %v", v, err)
+	}
+
+	if _, err := c.DO("NOSUCHCOMMAND"); err == nil {
+		t.Fatal("unknown command should fail")
+	}
+}
+
+func TestMemoryMatch(t *testing.T) {
+	cases := []struct {
+		pattern, str string
+		match        bool
+	}{
+		{"*", "anything", true},
+		{"user:*", "user:1", true},
+		{"user:*", "order:1", false},
+		{"h?llo", "hello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h\\*llo", "h*llo", true},
+		{"a/*/c", "a/b/c", true},
+	}
+	for _, tc := range cases {
+		if memoryMatch(tc.pattern, tc.str) != tc.match {
+			t.Errorf("memoryMatch(%q, %q) != %v", tc.pattern, tc.str, tc.match)
+		}
+	}
+}
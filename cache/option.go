@@ -15,6 +15,16 @@ type config struct {
 	wait bool
 	// 空闲的连接一定时间后会被关闭，默认为0，表示不会关闭空闲连接
 	idleTimeout time.Duration
+	// memory true: 使用进程内存代替 redis-server
+	memory bool
+	// sentinelMaster, sentinelAddrs 哨兵模式，主节点名称以及哨兵地址
+	sentinelMaster   string
+	sentinelAddrs    []string
+	sentinelPassword string
+	// clusterAddrs 集群模式，部分节点的地址
+	clusterAddrs []string
+	// hooks 执行命令前后调用
+	hooks []Hook
 }
 
 func defaultConfig() *config {
@@ -87,3 +97,44 @@ func WithIdleTimeout(timeout time.Duration) Option {
 		c.config().idleTimeout = timeout
 	}
 }
+
+// WithMemory 使用进程内存代替 redis-server，用于单元测试和单机开发环境
+// 每个 Cache 实例拥有独立的数据，Close 之后再次 Open 数据仍然保留
+func WithMemory() Option {
+	return func(c Cache) {
+		c.config().memory = true
+	}
+}
+
+// WithSentinel 哨兵模式，masterName 为哨兵监控的主节点名称，addrs 为哨兵地址，例如 127.0.0.1:26379
+// 通过哨兵获取主节点地址，主从切换之后自动连接新的主节点
+// 设置之后忽略 WithHost, WithPort，主节点的密码使用 WithPassword
+func WithSentinel(masterName string, addrs ...string) Option {
+	return func(c Cache) {
+		c.config().sentinelMaster = masterName
+		c.config().sentinelAddrs = addrs
+	}
+}
+
+// WithSentinelPassword 哨兵的密码，哨兵没有设置密码时不需要
+func WithSentinelPassword(password string) Option {
+	return func(c Cache) {
+		c.config().sentinelPassword = password
+	}
+}
+
+// WithCluster 集群模式，addrs 为集群中部分节点的地址，用于获取槽位信息
+// 设置之后忽略 WithHost, WithPort, WithDB
+func WithCluster(addrs ...string) Option {
+	return func(c Cache) {
+		c.config().clusterAddrs = addrs
+	}
+}
+
+// WithHook 添加钩子，在执行命令前后调用，用于日志、监控以及链路追踪
+// 多个钩子时，Before 按照添加的顺序调用，After 按照相反的顺序调用
+func WithHook(hooks ...Hook) Option {
+	return func(c Cache) {
+		c.config().hooks = append(c.config().hooks, hooks...)
+	}
+}
@@ -0,0 +1,591 @@
+package cache
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// eg:
+// p := c.Pipeline()
+// name := p.Get("name")
+// count := p.Incr("count")
+// if err := p.Exec(); err != nil {
+// 	return
+// }
+//
+// 命令的返回值在 Exec 之后才可以读取
+// v, err := name.Result()
+// n, err := count.Result()
+
+var (
+	// ErrPipelineNotExec 管道中的命令尚未执行
+	ErrPipelineNotExec = errors.New("pipeline not exec")
+)
+
+// Pipeline 管道，命令先缓存在本地，Exec 时一次性发送给 redis-server，只需要一次网络往返
+// 非线程安全
+type Pipeline interface {
+	// Do 添加任意命令
+	Do(cmd string, args ...interface{}) *Reply
+
+	// Exec 发送所有命令并读取回复，返回第一个出错命令的错误
+	// 执行之后管道被清空，可以继续使用
+	Exec() error
+
+	// Discard 丢弃所有尚未发送的命令
+	Discard()
+
+	PipelineKey
+	PipelineString
+	PipelineHash
+	PipelineSortedSet
+}
+
+// PipelineKey 键
+type PipelineKey interface {
+	Del(key ...interface{}) *IntReply
+	Exists(key string) *BoolReply
+	Expire(key, ex string) *BoolReply
+	PExpire(key, ex string) *BoolReply
+	TTL(key string) *IntReply
+	PTTL(key string) *IntReply
+}
+
+// PipelineString 字符串
+type PipelineString interface {
+	Get(key string) *StringReply
+	Set(key string, value interface{}) *StatusReply
+	SetEx(key string, value interface{}, seconds string) *StatusReply
+	PSetEx(key string, value interface{}, milliseconds string) *StatusReply
+	MGet(key ...interface{}) *StringsReply
+	MSet(v ...interface{}) *StatusReply
+	Append(key string, value interface{}) *IntReply
+	Strlen(key string) *IntReply
+	Incr(key string) *Int64Reply
+	Incrby(key string, increment int64) *Int64Reply
+	Decr(key string) *Int64Reply
+	Decrby(key string, decrement int64) *Int64Reply
+	GetSet(key string, value interface{}) *StringReply
+}
+
+// PipelineHash 哈希表
+type PipelineHash interface {
+	HGet(key, field string) *StringReply
+	HSet(key, field string, value interface{}) *StatusReply
+	HMGet(v ...interface{}) *StringsReply
+	HMSet(v ...interface{}) *StatusReply
+	HGetAll(key string) *StringsReply
+	HExists(key, field string) *BoolReply
+	HDel(v ...interface{}) *IntReply
+	HLen(key string) *IntReply
+	HIncrby(key, field string, increment int) *IntReply
+	HIncrbyFloat(key, field string, increment float64) *Float64Reply
+}
+
+// PipelineSortedSet 有序集合
+type PipelineSortedSet interface {
+	ZAdd(v ...interface{}) *IntReply
+	ZCard(key string) *IntReply
+	ZCount(key, min, max string) *IntReply
+	ZIncrby(key, member string, increment float64) *Float64Reply
+	ZRange(key string, start, stop int) *StringsReply
+	ZRevRange(key string, start, stop int) *StringsReply
+	ZScore(key, member string) *Float64Reply
+	ZRank(key, member string) *IntReply
+	ZRevRank(key, member string) *IntReply
+	ZRem(v ...interface{}) *IntReply
+}
+
+// Reply 管道中命令的回复
+type Reply struct {
+	value interface{}
+	err   error
+	done  bool
+}
+
+func (r *Reply) set(value interface{}, err error) {
+	r.value = value
+	r.err = err
+	r.done = true
+}
+
+// Result 返回原始回复，可以使用 Convert 进行转换
+func (r *Reply) Result() (interface{}, error) {
+	if !r.done {
+		return nil, ErrPipelineNotExec
+	}
+	return r.value, r.err
+}
+
+// Err 命令执行的错误
+func (r *Reply) Err() error {
+	_, err := r.Result()
+	return err
+}
+
+// StatusReply 只关心是否执行成功的回复
+type StatusReply struct{ *Reply }
+
+// StringReply ..
+type StringReply struct{ *Reply }
+
+// Result ..
+func (r *StringReply) Result() (string, error) {
+	return redis.String(r.Reply.Result())
+}
+
+// StringsReply ..
+type StringsReply struct{ *Reply }
+
+// Result ..
+func (r *StringsReply) Result() ([]string, error) {
+	return redis.Strings(r.Reply.Result())
+}
+
+// IntReply ..
+type IntReply struct{ *Reply }
+
+// Result ..
+func (r *IntReply) Result() (int, error) {
+	return redis.Int(r.Reply.Result())
+}
+
+// Int64Reply ..
+type Int64Reply struct{ *Reply }
+
+// Result ..
+func (r *Int64Reply) Result() (int64, error) {
+	return redis.Int64(r.Reply.Result())
+}
+
+// Float64Reply ..
+type Float64Reply struct{ *Reply }
+
+// Result ..
+func (r *Float64Reply) Result() (float64, error) {
+	return redis.Float64(r.Reply.Result())
+}
+
+// BoolReply ..
+type BoolReply struct{ *Reply }
+
+// Result ..
+func (r *BoolReply) Result() (bool, error) {
+	return redis.Bool(r.Reply.Result())
+}
+
+type pipelineCmd struct {
+	name  string
+	args  []interface{}
+	reply *Reply
+	// invalid 参数错误，不会发送
+	invalid bool
+}
+
+type pipeline struct {
+	c *cache
+	// conn 不为 nil 时使用该连接，并且不负责关闭，用于 Watch
+	conn redis.Conn
+	// tx true: 使用 MULTI/EXEC 包裹所有命令
+	tx   bool
+	cmds []*pipelineCmd
+}
+
+// Pipeline 创建管道
+func (c *cache) Pipeline() Pipeline {
+	return &pipeline{c: c}
+}
+
+// TxPipeline 创建事务管道，Exec 时使用 MULTI/EXEC 包裹所有命令，保证原子性
+func (c *cache) TxPipeline() Pipeline {
+	return &pipeline{c: c, tx: true}
+}
+
+// Do 添加任意命令
+func (p *pipeline) Do(cmd string, args ...interface{}) *Reply {
+	reply := &Reply{}
+	p.cmds = append(p.cmds, &pipelineCmd{name: cmd, args: args, reply: reply})
+	return reply
+}
+
+// invalid 参数错误的命令不会发送，直接返回错误，Exec 时同样返回该错误
+func (p *pipeline) invalid(err error) *Reply {
+	reply := &Reply{}
+	reply.set(nil, err)
+	p.cmds = append(p.cmds, &pipelineCmd{reply: reply, invalid: true})
+	return reply
+}
+
+// Discard 丢弃所有尚未发送的命令
+func (p *pipeline) Discard() {
+	p.cmds = nil
+}
+
+// Exec 发送所有命令并读取回复，返回第一个出错的命令的错误
+// 有参数错误的命令时，与 redis 入队出错相同，事务管道中的命令都不会执行，普通管道中的其它命令正常执行
+func (p *pipeline) Exec() error {
+	all := p.cmds
+	p.cmds = nil
+
+	var invalid error
+	cmds := make([]*pipelineCmd, 0, len(all))
+	for _, cmd := range all {
+		if !cmd.invalid {
+			cmds = append(cmds, cmd)
+		} else if invalid == nil {
+			invalid = cmd.reply.err
+		}
+	}
+	if invalid == nil {
+		return p.execute(cmds)
+	}
+	if p.tx {
+		return pipelineFail(cmds, invalid)
+	}
+
+	p.execute(cmds)
+	for _, cmd := range all {
+		if cmd.reply.err != nil {
+			return cmd.reply.err
+		}
+	}
+	return invalid
+}
+
+// execute 发送 cmds 并读取回复
+func (p *pipeline) execute(cmds []*pipelineCmd) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	name := "PIPELINE"
+	if p.tx {
+		name = "MULTI"
+	}
+	names := make([]interface{}, len(cmds))
+	for i, cmd := range cmds {
+		names[i] = cmd.name
+	}
+
+	// done 所有命令的回复都已经设置
+	done := false
+	_, err := p.c.process(p.c.Context(), name, names, func(ctx context.Context) (interface{}, error) {
+		if p.conn != nil {
+			done = true
+			return nil, p.exec(p.conn, cmds)
+		}
+
+		conn := p.c.pool.Get()
+		if conn == nil {
+			return nil, ErrInvalidConn
+		}
+
+		// ctx 结束时后台仍在读取回复，使用新的 Reply 避免并发修改
+		shadow := make([]*pipelineCmd, len(cmds))
+		for i, cmd := range cmds {
+			shadow[i] = &pipelineCmd{name: cmd.name, args: cmd.args, reply: &Reply{}}
+		}
+		_, err := runContext(ctx, func() (interface{}, error) {
+			return nil, p.exec(conn, shadow)
+		}, func() {
+			conn.Close()
+		})
+		if err != nil && err == ctx.Err() {
+			return nil, err
+		}
+
+		for i, cmd := range cmds {
+			cmd.reply.set(shadow[i].reply.value, shadow[i].reply.err)
+		}
+		done = true
+		return nil, err
+	})
+	if !done {
+		return pipelineFail(cmds, err)
+	}
+	return err
+}
+
+// exec 在 conn 上发送 cmds 并读取回复
+func (p *pipeline) exec(conn redis.Conn, cmds []*pipelineCmd) error {
+	if p.tx {
+		return p.execTx(conn, cmds)
+	}
+
+	for _, cmd := range cmds {
+		if err := conn.Send(cmd.name, cmd.args...); err != nil {
+			return pipelineFail(cmds, err)
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return pipelineFail(cmds, err)
+	}
+
+	var first error
+	for i, cmd := range cmds {
+		value, err := conn.Receive()
+		if err != nil {
+			if _, ok := err.(redis.Error); !ok {
+				// 连接出错，之后的回复都无法读取
+				return pipelineFail(cmds[i:], err)
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		cmd.reply.set(value, err)
+	}
+	return first
+}
+
+// execTx MULTI cmd ... EXEC
+func (p *pipeline) execTx(conn redis.Conn, cmds []*pipelineCmd) error {
+	if err := conn.Send("MULTI"); err != nil {
+		return pipelineFail(cmds, err)
+	}
+	for _, cmd := range cmds {
+		if err := conn.Send(cmd.name, cmd.args...); err != nil {
+			return pipelineFail(cmds, err)
+		}
+	}
+
+	// 入队时出错 (例如参数数量错误)，EXEC 会返回 EXECABORT
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err == redis.ErrNil {
+		err = ErrTxFailed
+	}
+	if err != nil {
+		return pipelineFail(cmds, err)
+	}
+	if len(replies) != len(cmds) {
+		return pipelineFail(cmds, errors.New("transaction reply count mismatch"))
+	}
+
+	var first error
+	for i, cmd := range cmds {
+		value := replies[i]
+		var err error
+		if e, ok := value.(redis.Error); ok {
+			value, err = nil, e
+			if first == nil {
+				first = e
+			}
+		}
+		cmd.reply.set(value, err)
+	}
+	return first
+}
+
+// pipelineFail 所有命令都返回 err
+func pipelineFail(cmds []*pipelineCmd, err error) error {
+	for _, cmd := range cmds {
+		cmd.reply.set(nil, err)
+	}
+	return err
+}
+
+// Del ..
+func (p *pipeline) Del(key ...interface{}) *IntReply {
+	return &IntReply{p.Do("DEL", key...)}
+}
+
+// Exists ..
+func (p *pipeline) Exists(key string) *BoolReply {
+	return &BoolReply{p.Do("EXISTS", key)}
+}
+
+// Expire ..
+func (p *pipeline) Expire(key, ex string) *BoolReply {
+	return &BoolReply{p.Do("EXPIRE", key, ex)}
+}
+
+// PExpire ..
+func (p *pipeline) PExpire(key, ex string) *BoolReply {
+	return &BoolReply{p.Do("PEXPIRE", key, ex)}
+}
+
+// TTL ..
+func (p *pipeline) TTL(key string) *IntReply {
+	return &IntReply{p.Do("TTL", key)}
+}
+
+// PTTL ..
+func (p *pipeline) PTTL(key string) *IntReply {
+	return &IntReply{p.Do("PTTL", key)}
+}
+
+// Get ..
+func (p *pipeline) Get(key string) *StringReply {
+	return &StringReply{p.Do("GET", key)}
+}
+
+// Set ..
+func (p *pipeline) Set(key string, value interface{}) *StatusReply {
+	return &StatusReply{p.Do("SET", key, value)}
+}
+
+// SetEx ..
+func (p *pipeline) SetEx(key string, value interface{}, seconds string) *StatusReply {
+	return &StatusReply{p.Do("SET", key, value, "EX", seconds)}
+}
+
+// PSetEx ..
+func (p *pipeline) PSetEx(key string, value interface{}, milliseconds string) *StatusReply {
+	return &StatusReply{p.Do("SET", key, value, "PX", milliseconds)}
+}
+
+// MGet ..
+func (p *pipeline) MGet(key ...interface{}) *StringsReply {
+	return &StringsReply{p.Do("MGET", key...)}
+}
+
+// MSet ..
+func (p *pipeline) MSet(v ...interface{}) *StatusReply {
+	if len(v) == 0 || len(v)%2 != 0 {
+		return &StatusReply{p.invalid(ErrInvalidParamCount)}
+	}
+	return &StatusReply{p.Do("MSET", v...)}
+}
+
+// Append ..
+func (p *pipeline) Append(key string, value interface{}) *IntReply {
+	return &IntReply{p.Do("APPEND", key, value)}
+}
+
+// Strlen ..
+func (p *pipeline) Strlen(key string) *IntReply {
+	return &IntReply{p.Do("STRLEN", key)}
+}
+
+// Incr ..
+func (p *pipeline) Incr(key string) *Int64Reply {
+	return &Int64Reply{p.Do("INCR", key)}
+}
+
+// Incrby ..
+func (p *pipeline) Incrby(key string, increment int64) *Int64Reply {
+	return &Int64Reply{p.Do("INCRBY", key, increment)}
+}
+
+// Decr ..
+func (p *pipeline) Decr(key string) *Int64Reply {
+	return &Int64Reply{p.Do("DECR", key)}
+}
+
+// Decrby ..
+func (p *pipeline) Decrby(key string, decrement int64) *Int64Reply {
+	return &Int64Reply{p.Do("DECRBY", key, decrement)}
+}
+
+// GetSet ..
+func (p *pipeline) GetSet(key string, value interface{}) *StringReply {
+	return &StringReply{p.Do("GETSET", key, value)}
+}
+
+// HGet ..
+func (p *pipeline) HGet(key, field string) *StringReply {
+	return &StringReply{p.Do("HGET", key, field)}
+}
+
+// HSet ..
+func (p *pipeline) HSet(key, field string, value interface{}) *StatusReply {
+	return &StatusReply{p.Do("HSET", key, field, value)}
+}
+
+// HMGet ..
+func (p *pipeline) HMGet(v ...interface{}) *StringsReply {
+	return &StringsReply{p.Do("HMGET", v...)}
+}
+
+// HMSet ..
+func (p *pipeline) HMSet(v ...interface{}) *StatusReply {
+	if len(v) == 0 || len(v)%2 == 0 {
+		return &StatusReply{p.invalid(ErrInvalidParamCount)}
+	}
+	return &StatusReply{p.Do("HMSET", v...)}
+}
+
+// HGetAll ..
+func (p *pipeline) HGetAll(key string) *StringsReply {
+	return &StringsReply{p.Do("HGETALL", key)}
+}
+
+// HExists ..
+func (p *pipeline) HExists(key, field string) *BoolReply {
+	return &BoolReply{p.Do("HEXISTS", key, field)}
+}
+
+// HDel ..
+func (p *pipeline) HDel(v ...interface{}) *IntReply {
+	return &IntReply{p.Do("HDEL", v...)}
+}
+
+// HLen ..
+func (p *pipeline) HLen(key string) *IntReply {
+	return &IntReply{p.Do("HLEN", key)}
+}
+
+// HIncrby ..
+func (p *pipeline) HIncrby(key, field string, increment int) *IntReply {
+	return &IntReply{p.Do("HINCRBY", key, field, increment)}
+}
+
+// HIncrbyFloat ..
+func (p *pipeline) HIncrbyFloat(key, field string, increment float64) *Float64Reply {
+	return &Float64Reply{p.Do("HINCRBYFLOAT", key, field, increment)}
+}
+
+// ZAdd ..
+func (p *pipeline) ZAdd(v ...interface{}) *IntReply {
+	if len(v) == 0 || len(v)%2 == 0 {
+		return &IntReply{p.invalid(ErrInvalidParamCount)}
+	}
+	return &IntReply{p.Do("ZADD", v...)}
+}
+
+// ZCard ..
+func (p *pipeline) ZCard(key string) *IntReply {
+	return &IntReply{p.Do("ZCARD", key)}
+}
+
+// ZCount ..
+func (p *pipeline) ZCount(key, min, max string) *IntReply {
+	return &IntReply{p.Do("ZCOUNT", key, min, max)}
+}
+
+// ZIncrby ..
+func (p *pipeline) ZIncrby(key, member string, increment float64) *Float64Reply {
+	return &Float64Reply{p.Do("ZINCRBY", key, increment, member)}
+}
+
+// ZRange ..
+func (p *pipeline) ZRange(key string, start, stop int) *StringsReply {
+	return &StringsReply{p.Do("ZRANGE", key, start, stop)}
+}
+
+// ZRevRange ..
+func (p *pipeline) ZRevRange(key string, start, stop int) *StringsReply {
+	return &StringsReply{p.Do("ZREVRANGE", key, start, stop)}
+}
+
+// ZScore ..
+func (p *pipeline) ZScore(key, member string) *Float64Reply {
+	return &Float64Reply{p.Do("ZSCORE", key, member)}
+}
+
+// ZRank ..
+func (p *pipeline) ZRank(key, member string) *IntReply {
+	return &IntReply{p.Do("ZRANK", key, member)}
+}
+
+// ZRevRank ..
+func (p *pipeline) ZRevRank(key, member string) *IntReply {
+	return &IntReply{p.Do("ZREVRANK", key, member)}
+}
+
+// ZRem ..
+func (p *pipeline) ZRem(v ...interface{}) *IntReply {
+	return &IntReply{p.Do("ZREM", v...)}
+}
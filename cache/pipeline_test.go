@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	p := c.Pipeline()
+	set := p.Set("name", "alex")
+	get := p.Get("name")
+	incr := p.Incrby("count", 5)
+	missing := p.Get("none")
+	hset := p.HMSet("user", "age", 18, "city", "shenzhen")
+	hlen := p.HLen("user")
+
+	if _, err := get.Result(); err != ErrPipelineNotExec {
+		t.Fatalf("reply before Exec, err: %v", err)
+	}
+
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Exec failed: %s", err.Error())
+	}
+
+	if err := set.Err(); err != nil {
+		t.Fatalf("Set failed: %s", err.Error())
+	}
+	if v, err := get.Result(); err != nil || v != "alex" {
+		t.Fatalf("Get: %s, err: %v", v, err)
+	}
+	if n, err := incr.Result(); err != nil || n != 5 {
+		t.Fatalf("Incrby: %d, err: %v", n, err)
+	}
+	if _, err := missing.Result(); err != ErrNil {
+		t.Fatalf("Get missing key, err: %v", err)
+	}
+	if err := hset.Err(); err != nil {
+		t.Fatalf("HMSet failed: %s", err.Error())
+	}
+	if n, _ := hlen.Result(); n != 2 {
+		t.Fatalf("HLen: %d", n)
+	}
+
+	// 出错的命令不影响其它命令
+	bad := p.Incr("name")
+	good := p.Get("name")
+	if err := p.Exec(); err == nil {
+		t.Fatal("Exec should return error of Incr")
+	}
+	if bad.Err() == nil {
+		t.Fatal("Incr on string should fail")
+	}
+	if v, _ := good.Result(); v != "alex" {
+		t.Fatalf("Get after error: %s", v)
+	}
+}
+
+func TestTxPipeline(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	p := c.TxPipeline()
+	p.Set("a", 1)
+	incr := p.Incr("a")
+	if err := p.Exec(); err != nil {
+		t.Fatalf("Exec failed: %s", err.Error())
+	}
+	if n, _ := incr.Result(); n != 2 {
+		t.Fatalf("Incr: %d", n)
+	}
+
+	// 入队时出错，整个事务不执行
+	p.Set("a", 10)
+	p.Do("SET", "a")
+	if err := p.Exec(); err == nil {
+		t.Fatal("Exec should fail")
+	}
+	if v, _ := c.Get("a"); v != "2" {
+		t.Fatalf("transaction should be discarded, a: %s", v)
+	}
+
+	// 参数错误与入队出错相同
+	p.Set("a", 10)
+	p.MSet("b")
+	if err := p.Exec(); err != ErrInvalidParamCount {
+		t.Fatalf("Exec with invalid args, err: %v", err)
+	}
+	if v, _ := c.Get("a"); v != "2" {
+		t.Fatalf("transaction with invalid args should be discarded, a: %s", v)
+	}
+}
+
+func TestPipelineInvalid(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	// 参数错误的命令不发送，其它命令正常执行，Exec 返回参数错误
+	p := c.Pipeline()
+	set := p.Set("a", 1)
+	mset := p.MSet("b", 2, "c")
+	get := p.Get("a")
+	if err := p.Exec(); err != ErrInvalidParamCount {
+		t.Fatalf("Exec with odd MSet, err: %v", err)
+	}
+	if err := mset.Err(); err != ErrInvalidParamCount {
+		t.Fatalf("MSet, err: %v", err)
+	}
+	if err := set.Err(); err != nil {
+		t.Fatalf("Set failed: %s", err.Error())
+	}
+	if v, err := get.Result(); err != nil || v != "1" {
+		t.Fatalf("Get: %s, err: %v", v, err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	c.Set("count", 1)
+
+	// 在 WATCH 与 EXEC 之间修改 key，事务失败
+	err := c.Watch(func(tx Tx) error {
+		c.Set("count", 100)
+
+		p := tx.Pipeline()
+		p.Set("count", 2)
+		return p.Exec()
+	}, "count")
+	if err != ErrTxFailed {
+		t.Fatalf("Watch should fail, err: %v", err)
+	}
+	if v, _ := c.Get("count"); v != "100" {
+		t.Fatalf("count: %s", v)
+	}
+
+	// 并发自增，使用 WatchRetry 保证结果正确
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := c.WatchRetry(100, func(tx Tx) error {
+				n, err := c.Int(tx.DO("GET", "count"))
+				if err != nil {
+					return err
+				}
+				p := tx.Pipeline()
+				p.Set("count", n+1)
+				return p.Exec()
+			}, "count")
+			if err != nil {
+				t.Errorf("WatchRetry failed: %s", err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := c.Get("count"); v != "110" {
+		t.Fatalf("count: %s", v)
+	}
+}
@@ -0,0 +1,389 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// eg:
+// sub, err := c.Subscribe("news")
+// if err != nil {
+// 	return err
+// }
+// defer sub.Close()
+//
+// for msg := range sub.Channel() {
+// 	fmt.Println(msg.Channel, string(msg.Data))
+// }
+
+const (
+	// pubsubPingInterval 订阅连接的心跳间隔，超过两个间隔没有收到任何回复则重连
+	pubsubPingInterval = 30 * time.Second
+	// pubsubMinBackoff, pubsubMaxBackoff 重连的间隔从 pubsubMinBackoff 开始翻倍，最大为 pubsubMaxBackoff
+	pubsubMinBackoff = 100 * time.Millisecond
+	pubsubMaxBackoff = 5 * time.Second
+	// pubsubChannelSize 消息通道的缓冲大小
+	pubsubChannelSize = 100
+)
+
+var (
+	// ErrSubscriptionClosed 订阅已经关闭
+	ErrSubscriptionClosed = errors.New("subscription closed")
+)
+
+// PubSub 发布订阅
+type PubSub interface {
+	// Publish 将消息发送到指定的频道，返回接收到消息的订阅者数量
+	Publish(channel string, message interface{}) (int, error)
+
+	// Subscribe 订阅频道
+	Subscribe(channels ...string) (Subscription, error)
+
+	// PSubscribe 订阅模式，例如 news.*
+	PSubscribe(patterns ...string) (Subscription, error)
+}
+
+// Subscription 订阅
+// 连接断开时会自动重连并重新订阅，断开期间发布的消息会丢失
+type Subscription interface {
+	// Channel 接收消息的通道，Close 之后会被关闭
+	// 需要及时读取，否则会阻塞后续消息的接收
+	Channel() <-chan *Message
+
+	// Subscribe 增加订阅的频道
+	Subscribe(channels ...string) error
+
+	// PSubscribe 增加订阅的模式
+	PSubscribe(patterns ...string) error
+
+	// Unsubscribe 取消订阅频道，为空时取消所有频道
+	Unsubscribe(channels ...string) error
+
+	// PUnsubscribe 取消订阅模式，为空时取消所有模式
+	PUnsubscribe(patterns ...string) error
+
+	// Close 取消所有订阅，并关闭 Channel
+	Close() error
+}
+
+// Message 订阅收到的消息
+type Message struct {
+	// Channel 频道
+	Channel string
+	// Pattern 匹配的模式，通过 Subscribe 订阅时为空
+	Pattern string
+	// Data 消息内容
+	Data []byte
+}
+
+// Publish 将消息发送到指定的频道
+func (c *cache) Publish(channel string, message interface{}) (int, error) {
+	return c.Int(c.DO("PUBLISH", channel, message))
+}
+
+// Subscribe 订阅频道
+func (c *cache) Subscribe(channels ...string) (Subscription, error) {
+	return c.subscribe("SUBSCRIBE", channels)
+}
+
+// PSubscribe 订阅模式
+func (c *cache) PSubscribe(patterns ...string) (Subscription, error) {
+	return c.subscribe("PSUBSCRIBE", patterns)
+}
+
+func (c *cache) subscribe(cmd string, targets []string) (Subscription, error) {
+	if len(targets) == 0 {
+		return nil, ErrInvalidParamCount
+	}
+
+	s := &subscription{
+		c:        c,
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+		messages: make(chan *Message, pubsubChannelSize),
+		wakeup:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	s.targets(cmd).add(targets)
+
+	conn, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	go s.run(conn)
+	return s, nil
+}
+
+type subscription struct {
+	c *cache
+
+	// mu 保护以下字段，同时保证同一时间只有一个 goroutine 向连接发送命令
+	mu sync.Mutex
+	// conn 当前的订阅连接，为 nil 表示没有连接
+	conn     redis.Conn
+	channels subscriptionTargets
+	patterns subscriptionTargets
+	closed   bool
+
+	messages chan *Message
+	// wakeup 没有连接时增加了订阅
+	wakeup chan struct{}
+	done   chan struct{}
+}
+
+type subscriptionTargets map[string]struct{}
+
+func (t subscriptionTargets) add(targets []string) {
+	for _, target := range targets {
+		t[target] = struct{}{}
+	}
+}
+
+func (t subscriptionTargets) remove(targets []string) {
+	if len(targets) == 0 {
+		for target := range t {
+			delete(t, target)
+		}
+		return
+	}
+	for _, target := range targets {
+		delete(t, target)
+	}
+}
+
+func (t subscriptionTargets) args() []interface{} {
+	args := make([]interface{}, 0, len(t))
+	for target := range t {
+		args = append(args, target)
+	}
+	return args
+}
+
+// Channel ..
+func (s *subscription) Channel() <-chan *Message {
+	return s.messages
+}
+
+// Subscribe ..
+func (s *subscription) Subscribe(channels ...string) error {
+	return s.change("SUBSCRIBE", channels)
+}
+
+// PSubscribe ..
+func (s *subscription) PSubscribe(patterns ...string) error {
+	return s.change("PSUBSCRIBE", patterns)
+}
+
+// Unsubscribe ..
+func (s *subscription) Unsubscribe(channels ...string) error {
+	return s.change("UNSUBSCRIBE", channels)
+}
+
+// PUnsubscribe ..
+func (s *subscription) PUnsubscribe(patterns ...string) error {
+	return s.change("PUNSUBSCRIBE", patterns)
+}
+
+// Close ..
+func (s *subscription) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+
+	// 取消所有订阅之后，接收消息的 goroutine 会断开连接并退出
+	if s.conn != nil {
+		s.conn.Send("UNSUBSCRIBE")
+		s.conn.Send("PUNSUBSCRIBE")
+		s.conn.Flush()
+	}
+	return nil
+}
+
+func (s *subscription) targets(cmd string) subscriptionTargets {
+	if cmd == "PSUBSCRIBE" || cmd == "PUNSUBSCRIBE" {
+		return s.patterns
+	}
+	return s.channels
+}
+
+// change 修改订阅，已连接时立即发送给 redis-server，否则在连接时订阅
+func (s *subscription) change(cmd string, targets []string) error {
+	subscribe := cmd == "SUBSCRIBE" || cmd == "PSUBSCRIBE"
+	if subscribe && len(targets) == 0 {
+		return ErrInvalidParamCount
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSubscriptionClosed
+	}
+	if subscribe {
+		s.targets(cmd).add(targets)
+	} else {
+		s.targets(cmd).remove(targets)
+	}
+
+	if s.conn == nil {
+		select {
+		case s.wakeup <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+
+	// 发送失败时连接会在接收时出错，重连之后会按照最新的订阅重新订阅
+	args := make([]interface{}, len(targets))
+	for i, target := range targets {
+		args[i] = target
+	}
+	s.conn.Send(cmd, args...)
+	s.conn.Flush()
+	return nil
+}
+
+// connect 连接并订阅所有的频道和模式，没有任何订阅时返回 nil
+func (s *subscription) connect() (redis.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrSubscriptionClosed
+	}
+	if len(s.channels)+len(s.patterns) == 0 {
+		return nil, nil
+	}
+
+	conn := s.c.pool.Get()
+	if len(s.channels) > 0 {
+		conn.Send("SUBSCRIBE", s.channels.args()...)
+	}
+	if len(s.patterns) > 0 {
+		conn.Send("PSUBSCRIBE", s.patterns.args()...)
+	}
+	if err := conn.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	s.conn = conn
+	return conn, nil
+}
+
+func (s *subscription) disconnect(conn redis.Conn) {
+	s.mu.Lock()
+	if s.conn == conn {
+		s.conn = nil
+	}
+	s.mu.Unlock()
+
+	conn.Close()
+}
+
+// run 接收消息，连接出错时重连
+func (s *subscription) run(conn redis.Conn) {
+	defer close(s.messages)
+
+	for {
+		failed := false
+		if conn == nil {
+			// 所有订阅都已取消，等待新的订阅
+			select {
+			case <-s.wakeup:
+			case <-s.done:
+				return
+			}
+		} else {
+			failed = s.receive(conn) != nil
+			s.disconnect(conn)
+		}
+
+		var ok bool
+		if conn, ok = s.reconnect(failed); !ok {
+			return
+		}
+	}
+}
+
+// receive 接收消息，直到连接出错或者所有订阅都被取消
+func (s *subscription) receive(conn redis.Conn) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.ping(conn, stop)
+
+	psc := redis.PubSubConn{Conn: conn}
+	for {
+		switch v := psc.ReceiveWithTimeout(2 * pubsubPingInterval).(type) {
+		case redis.Message:
+			msg := &Message{Channel: v.Channel, Pattern: v.Pattern, Data: v.Data}
+			select {
+			case s.messages <- msg:
+			case <-s.done:
+				return nil
+			}
+		case redis.Subscription:
+			if v.Count == 0 {
+				return nil
+			}
+		case error:
+			return v
+		}
+	}
+}
+
+// ping 定时发送心跳，配合 receive 的超时检测连接是否可用
+func (s *subscription) ping(conn redis.Conn, stop chan struct{}) {
+	ticker := time.NewTicker(pubsubPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.conn == conn {
+				redis.PubSubConn{Conn: conn}.Ping("")
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// reconnect 重新连接并订阅，连接失败时按照指数退避重试
+// 没有任何订阅时返回的 conn 为 nil，订阅被关闭时 ok 为 false
+func (s *subscription) reconnect(delay bool) (conn redis.Conn, ok bool) {
+	backoff := pubsubMinBackoff
+	for {
+		if delay {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-s.done:
+				timer.Stop()
+				return nil, false
+			}
+			if backoff *= 2; backoff > pubsubMaxBackoff {
+				backoff = pubsubMaxBackoff
+			}
+		}
+
+		conn, err := s.connect()
+		if err == nil {
+			return conn, true
+		}
+		if err == ErrSubscriptionClosed {
+			return nil, false
+		}
+		delay = true
+	}
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func receiveMessage(t *testing.T, sub Subscription) *Message {
+	select {
+	case msg := <-sub.Channel():
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("receive message timeout")
+	}
+	return nil
+}
+
+func TestPubSub(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	sub, err := c.Subscribe("news")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %s", err.Error())
+	}
+	if err := sub.PSubscribe("user.*"); err != nil {
+		t.Fatalf("PSubscribe failed: %s", err.Error())
+	}
+
+	// 等待订阅生效
+	for i := 0; i < 100; i++ {
+		if n, _ := c.Publish("user.1", "login"); n == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if msg := receiveMessage(t, sub); msg.Pattern != "user.*" || msg.Channel != "user.1" || string(msg.Data) != "login" {
+		t.Fatalf("pmessage: %+v", msg)
+	}
+
+	if n, err := c.Publish("news", "hello"); err != nil || n != 1 {
+		t.Fatalf("Publish: %d, err: %v", n, err)
+	}
+	if msg := receiveMessage(t, sub); msg.Channel != "news" || string(msg.Data) != "hello" {
+		t.Fatalf("message: %+v", msg)
+	}
+
+	sub.Unsubscribe("news")
+	sub.PUnsubscribe()
+	for i := 0; i < 100; i++ {
+		if n, _ := c.Publish("news", "bye"); n == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n, _ := c.Publish("news", "bye"); n != 0 {
+		t.Fatalf("Publish after Unsubscribe: %d", n)
+	}
+
+	sub.Close()
+	select {
+	case _, ok := <-sub.Channel():
+		if ok {
+			t.Fatal("channel should be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel should be closed after Close")
+	}
+	if err := sub.Subscribe("news"); err != ErrSubscriptionClosed {
+		t.Fatalf("Subscribe after Close, err: %v", err)
+	}
+}
+
+func TestPubSubReconnect(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	sub, err := c.Subscribe("news")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %s", err.Error())
+	}
+	defer sub.Close()
+
+	// 断开订阅连接，自动重连之后重新订阅
+	if n, _ := c.Int(c.DO("CLIENT", "KILL", "TYPE", "pubsub")); n != 1 {
+		t.Fatalf("CLIENT KILL: %d", n)
+	}
+	for i := 0; i < 100; i++ {
+		if n, _ := c.Publish("news", "hello"); n == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if msg := receiveMessage(t, sub); string(msg.Data) != "hello" {
+		t.Fatalf("message after reconnect: %+v", msg)
+	}
+}
@@ -0,0 +1,431 @@
+package cache
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// eg:
+// 遍历所有以 user: 开头的 key，代替 KEYS
+// it := c.Scan(cache.ScanMatch("user:*"), cache.ScanCount(1000))
+// for it.Next() {
+// 	fmt.Println(it.Key())
+// }
+// if err := it.Err(); err != nil {
+// 	return err
+// }
+//
+// 遍历哈希表
+// it := c.HScan("user:1001")
+// for it.Next() {
+// 	fmt.Println(it.Field(), it.Value())
+// }
+//
+// 批量删除，每批 500 个
+// n, err := c.DelByPattern("session:*", cache.ScanBatch(500), cache.ScanProgress(func(scanned, affected int) {
+// 	log.Infof("scanned: %d, deleted: %d", scanned, affected)
+// }))
+
+// 与 redis 一致，迭代期间一直存在的元素都会被返回，但是同一个元素可能返回多次
+// 集群模式下 Scan 依次遍历所有的主节点
+
+// Scanner 增量迭代
+type Scanner interface {
+	// Scan 迭代数据库中的 key
+	Scan(opts ...ScanOption) KeyIterator
+	// HScan 迭代哈希表中的字段以及值
+	HScan(key string, opts ...ScanOption) HashIterator
+	// SScan 迭代集合中的成员
+	SScan(key string, opts ...ScanOption) MemberIterator
+	// ZScan 迭代有序集合中的成员以及 score
+	ZScan(key string, opts ...ScanOption) ZIterator
+
+	// DelByPattern 删除所有匹配 pattern 的 key，使用 UNLINK 分批删除，redis-server 不支持 UNLINK 时使用 DEL
+	// 返回删除的数量
+	DelByPattern(pattern string, opts ...ScanOption) (int, error)
+	// ExpireByPattern 将所有匹配 pattern 的 key 的生存时间设置为 ttl，ttl <= 0 时 key 会被删除
+	// 返回设置成功的数量
+	ExpireByPattern(pattern string, ttl time.Duration, opts ...ScanOption) (int, error)
+}
+
+// KeyIterator key 迭代器
+type KeyIterator interface {
+	// Next 移动到下一个元素，没有更多元素或者出错时返回 false
+	Next() bool
+	Key() string
+	// Err 迭代过程中的错误
+	Err() error
+}
+
+// HashIterator 哈希表迭代器
+type HashIterator interface {
+	Next() bool
+	Field() string
+	Value() string
+	Err() error
+}
+
+// MemberIterator 集合迭代器
+type MemberIterator interface {
+	Next() bool
+	Member() string
+	Err() error
+}
+
+// ZIterator 有序集合迭代器
+type ZIterator interface {
+	Next() bool
+	Z() Z
+	Err() error
+}
+
+// scanConfig 参数
+type scanConfig struct {
+	match string
+	count int
+	typ   string
+	// batch, progress 只用于批量操作
+	batch    int
+	progress func(scanned, affected int)
+}
+
+// ScanOption ..
+type ScanOption func(*scanConfig)
+
+// ScanMatch 只返回匹配 pattern 的元素，glob 风格，例如 user:*
+func ScanMatch(pattern string) ScanOption {
+	return func(c *scanConfig) {
+		c.match = pattern
+	}
+}
+
+// ScanCount 每次迭代检查的元素数量，只是建议值，redis 默认为 10
+func ScanCount(count int) ScanOption {
+	return func(c *scanConfig) {
+		c.count = count
+	}
+}
+
+// ScanType 只返回该类型的 key，例如 string, hash，只对 Scan 有效，需要 redis-server 6.0 以上
+func ScanType(typ string) ScanOption {
+	return func(c *scanConfig) {
+		c.typ = typ
+	}
+}
+
+// ScanBatch 批量操作时每批处理的 key 数量，默认为 100
+func ScanBatch(size int) ScanOption {
+	return func(c *scanConfig) {
+		if size > 0 {
+			c.batch = size
+		}
+	}
+}
+
+// ScanProgress 批量操作时每批处理之后调用，scanned 为已经遍历的 key 数量，affected 为已经删除或者设置成功的数量
+func ScanProgress(fn func(scanned, affected int)) ScanOption {
+	return func(c *scanConfig) {
+		c.progress = fn
+	}
+}
+
+func newScanConfig(opts []ScanOption) *scanConfig {
+	conf := &scanConfig{batch: 100}
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return conf
+}
+
+// scanner 通过游标迭代，step 为每个元素在回复中占用的数量，HSCAN, ZSCAN 为 2
+type scanner struct {
+	c    *cache
+	cmd  string
+	key  string
+	step int
+	conf *scanConfig
+
+	// nodes 集群模式下 SCAN 需要遍历的主节点，第一个为当前节点
+	nodes  []string
+	cursor string
+	done   bool
+
+	// values 最近一次返回的元素，from 为返回这些元素的节点
+	values  []string
+	from    string
+	pos     int
+	current []string
+	err     error
+}
+
+func newScanner(c *cache, cmd, key string, step int, opts []ScanOption) *scanner {
+	s := &scanner{
+		c:      c,
+		cmd:    cmd,
+		key:    key,
+		step:   step,
+		conf:   newScanConfig(opts),
+		cursor: "0",
+	}
+	if pool, ok := c.pool.(*clusterPool); ok && cmd == "SCAN" {
+		s.nodes, s.err = pool.masters()
+	}
+	return s
+}
+
+// Next ..
+func (s *scanner) Next() bool {
+	for {
+		if s.pos+s.step <= len(s.values) {
+			s.current = s.values[s.pos : s.pos+s.step]
+			s.pos += s.step
+			return true
+		}
+		if s.done || s.err != nil {
+			s.current = nil
+			return false
+		}
+		s.fetch()
+	}
+}
+
+// Err ..
+func (s *scanner) Err() error {
+	return s.err
+}
+
+func (s *scanner) fetch() {
+	var args []interface{}
+	if s.key != "" {
+		args = append(args, s.key)
+	}
+	args = append(args, s.cursor)
+	if s.conf.match != "" {
+		args = append(args, "MATCH", s.conf.match)
+	}
+	if s.conf.count > 0 {
+		args = append(args, "COUNT", s.conf.count)
+	}
+	if s.conf.typ != "" && s.cmd == "SCAN" {
+		args = append(args, "TYPE", s.conf.typ)
+	}
+
+	var reply interface{}
+	var err error
+	node := ""
+	if len(s.nodes) > 0 {
+		node = s.nodes[0]
+		reply, err = s.c.doNode(node, s.cmd, args...)
+	} else {
+		reply, err = s.c.DO(s.cmd, args...)
+	}
+
+	values, err := redis.Values(reply, err)
+	if err == nil && len(values) != 2 {
+		err = ErrInvalidParamCount
+	}
+	if err != nil {
+		s.err = err
+		return
+	}
+	cursor, err := redis.String(values[0], nil)
+	if err != nil {
+		s.err = err
+		return
+	}
+	items, err := redis.Strings(values[1], nil)
+	if err == nil && len(items)%s.step != 0 {
+		err = ErrInvalidParamCount
+	}
+	if err != nil {
+		s.err = err
+		return
+	}
+
+	s.values, s.from, s.pos = items, node, 0
+	s.cursor = cursor
+	if cursor == "0" {
+		if len(s.nodes) > 1 {
+			s.nodes = s.nodes[1:]
+		} else {
+			s.done = true
+		}
+	}
+}
+
+// keyIterator ..
+type keyIterator struct{ *scanner }
+
+// Key ..
+func (it keyIterator) Key() string {
+	if it.current == nil {
+		return ""
+	}
+	return it.current[0]
+}
+
+// hashIterator ..
+type hashIterator struct{ *scanner }
+
+// Field ..
+func (it hashIterator) Field() string {
+	if it.current == nil {
+		return ""
+	}
+	return it.current[0]
+}
+
+// Value ..
+func (it hashIterator) Value() string {
+	if it.current == nil {
+		return ""
+	}
+	return it.current[1]
+}
+
+// memberIterator ..
+type memberIterator struct{ *scanner }
+
+// Member ..
+func (it memberIterator) Member() string {
+	if it.current == nil {
+		return ""
+	}
+	return it.current[0]
+}
+
+// zIterator ..
+type zIterator struct{ *scanner }
+
+// Z ..
+func (it zIterator) Z() Z {
+	if it.current == nil {
+		return Z{}
+	}
+	score, err := parseFloat(it.current[1])
+	if err != nil {
+		it.err = err
+	}
+	return Z{Member: it.current[0], Score: score}
+}
+
+// Scan SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+func (c *cache) Scan(opts ...ScanOption) KeyIterator {
+	return keyIterator{newScanner(c, "SCAN", "", 1, opts)}
+}
+
+// HScan HSCAN key cursor [MATCH pattern] [COUNT count]
+func (c *cache) HScan(key string, opts ...ScanOption) HashIterator {
+	return hashIterator{newScanner(c, "HSCAN", key, 2, opts)}
+}
+
+// SScan SSCAN key cursor [MATCH pattern] [COUNT count]
+func (c *cache) SScan(key string, opts ...ScanOption) MemberIterator {
+	return memberIterator{newScanner(c, "SSCAN", key, 1, opts)}
+}
+
+// ZScan ZSCAN key cursor [MATCH pattern] [COUNT count]
+func (c *cache) ZScan(key string, opts ...ScanOption) ZIterator {
+	return zIterator{newScanner(c, "ZSCAN", key, 2, opts)}
+}
+
+// DelByPattern ..
+func (c *cache) DelByPattern(pattern string, opts ...ScanOption) (int, error) {
+	return c.bulkByPattern(pattern, opts, "UNLINK")
+}
+
+// ExpireByPattern ..
+func (c *cache) ExpireByPattern(pattern string, ttl time.Duration, opts ...ScanOption) (int, error) {
+	return c.bulkByPattern(pattern, opts, "PEXPIRE", int64(ttl/time.Millisecond))
+}
+
+// bulkByPattern 遍历匹配 pattern 的 key，每 batch 个执行一次 cmd
+// 集群模式下同一批的 key 来自同一个节点
+func (c *cache) bulkByPattern(pattern string, opts []ScanOption, cmd string, args ...interface{}) (int, error) {
+	s := newScanner(c, "SCAN", "", 1, append(opts, ScanMatch(pattern)))
+	if s.conf.count == 0 {
+		s.conf.count = s.conf.batch
+	}
+
+	var scanned, affected int
+	keys := make([]string, 0, s.conf.batch)
+	flush := func(node string) error {
+		if len(keys) == 0 {
+			return nil
+		}
+		n, err := c.bulk(node, keys, cmd, args...)
+		scanned += len(keys)
+		affected += n
+		keys = keys[:0]
+		if err != nil {
+			return err
+		}
+		if s.conf.progress != nil {
+			s.conf.progress(scanned, affected)
+		}
+		return nil
+	}
+
+	// node 当前这一批 key 所在的节点
+	node := ""
+	for s.Next() {
+		if len(keys) > 0 && s.from != node {
+			if err := flush(node); err != nil {
+				return affected, err
+			}
+		}
+		node = s.from
+		keys = append(keys, s.current[0])
+		if len(keys) >= s.conf.batch {
+			if err := flush(node); err != nil {
+				return affected, err
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return affected, err
+	}
+	return affected, flush(node)
+}
+
+// bulk 批量执行 cmd，返回执行成功的数量
+// UNLINK, DEL 在非集群模式下一次删除所有 key，其它情况下使用管道对每个 key 执行一次
+func (c *cache) bulk(node string, keys []string, cmd string, args ...interface{}) (int, error) {
+	p := &pipeline{c: c}
+	if node != "" {
+		conn := c.pool.(*clusterPool).nodePool(node).Get()
+		defer conn.Close()
+		p.conn = conn
+	}
+
+	var replies []*IntReply
+	if node == "" && (cmd == "UNLINK" || cmd == "DEL") {
+		all := make([]interface{}, len(keys))
+		for i, key := range keys {
+			all[i] = key
+		}
+		replies = append(replies, &IntReply{p.Do(cmd, all...)})
+	} else {
+		for _, key := range keys {
+			replies = append(replies, &IntReply{p.Do(cmd, append([]interface{}{key}, args...)...)})
+		}
+	}
+
+	err := p.Exec()
+	if err != nil && cmd == "UNLINK" && strings.HasPrefix(err.Error(), "ERR unknown command") {
+		// redis-server 4.0 之前不支持 UNLINK
+		return c.bulk(node, keys, "DEL", args...)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, reply := range replies {
+		v, _ := reply.Result()
+		n += v
+	}
+	return n, nil
+}
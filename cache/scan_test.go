@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestScan(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	for i := 0; i < 50; i++ {
+		c.Set(fmt.Sprintf("user:%d", i), i)
+	}
+	c.Set("other", 1)
+	c.HSet("user:hash", "name", "alex")
+
+	seen := make(map[string]bool)
+	it := c.Scan(ScanMatch("user:*"), ScanCount(7))
+	for it.Next() {
+		seen[it.Key()] = true
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 51 || seen["other"] {
+		t.Fatalf("Scan: %d keys", len(seen))
+	}
+
+	var keys []string
+	it = c.Scan(ScanMatch("user:*"), ScanType("hash"))
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	if len(keys) != 1 || keys[0] != "user:hash" {
+		t.Fatalf("Scan with type: %v", keys)
+	}
+
+	c.HSet("hash", "a", "1")
+	c.HSet("hash", "b", "2")
+	hit := c.HScan("hash", ScanMatch("a"))
+	if !hit.Next() || hit.Field() != "a" || hit.Value() != "1" || hit.Next() {
+		t.Fatal("HScan")
+	}
+
+	c.SAdd("set", "m1", "m2", "m3")
+	var members []string
+	mit := c.SScan("set", ScanCount(1))
+	for mit.Next() {
+		members = append(members, mit.Member())
+	}
+	sort.Strings(members)
+	if len(members) != 3 || members[0] != "m1" || members[2] != "m3" {
+		t.Fatalf("SScan: %v", members)
+	}
+
+	c.ZAddZ("rank", Z{"alex", 1.5}, Z{"bob", 2})
+	zs := make(map[string]float64)
+	zit := c.ZScan("rank")
+	for zit.Next() {
+		z := zit.Z()
+		zs[z.Member] = z.Score
+	}
+	if len(zs) != 2 || zs["alex"] != 1.5 || zs["bob"] != 2 {
+		t.Fatalf("ZScan: %v", zs)
+	}
+
+	if mit := c.SScan("hash"); mit.Next() || mit.Err() == nil {
+		t.Fatal("SScan wrong type should fail")
+	}
+}
+
+func TestDelByPattern(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	for i := 0; i < 250; i++ {
+		c.Set(fmt.Sprintf("session:%d", i), i)
+	}
+	c.Set("user:1", 1)
+
+	var calls, lastScanned, lastAffected int
+	n, err := c.DelByPattern("session:*", ScanBatch(100), ScanProgress(func(scanned, affected int) {
+		if scanned-lastScanned > 100 {
+			t.Errorf("batch too large: %d", scanned-lastScanned)
+		}
+		calls++
+		lastScanned, lastAffected = scanned, affected
+	}))
+	if err != nil || n != 250 {
+		t.Fatalf("DelByPattern: %d, err: %v", n, err)
+	}
+	if calls < 3 || lastScanned != 250 || lastAffected != 250 {
+		t.Fatalf("progress: %d calls, scanned: %d, affected: %d", calls, lastScanned, lastAffected)
+	}
+	if ok, _ := c.Exists("session:1"); ok {
+		t.Fatal("session:1 should be deleted")
+	}
+	if ok, _ := c.Exists("user:1"); !ok {
+		t.Fatal("user:1 should not be deleted")
+	}
+}
+
+func TestExpireByPattern(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	for i := 0; i < 20; i++ {
+		c.Set(fmt.Sprintf("session:%d", i), i)
+	}
+	c.Set("user:1", 1)
+
+	n, err := c.ExpireByPattern("session:*", time.Minute, ScanBatch(8))
+	if err != nil || n != 20 {
+		t.Fatalf("ExpireByPattern: %d, err: %v", n, err)
+	}
+	if ttl, _ := c.PTTL("session:3"); ttl <= 0 || ttl > 60000 {
+		t.Fatalf("PTTL: %d", ttl)
+	}
+	if ttl, _ := c.PTTL("user:1"); ttl != -1 {
+		t.Fatalf("user:1 PTTL: %d", ttl)
+	}
+}
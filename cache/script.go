@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// eg:
+// 脚本通常定义为包级变量，NewScript 会将脚本加入注册表
+// var incrLimit = cache.NewScript(`
+// local n = redis.call("INCR", KEYS[1])
+// if n == 1 then
+// 	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+// end
+// return n
+// `)
+//
+// 启动时加载所有注册的脚本，可选，没有加载时第一次执行会使用 EVAL
+// if err := cache.LoadScripts(c); err != nil {
+// 	return err
+// }
+//
+// 使用 Convert 转换回复
+// n, err := c.Int(incrLimit.Run(c, []string{"quota:1001"}, 60000))
+
+// Scripting lua 脚本
+type Scripting interface {
+	// Eval EVAL script numkeys key [key ...] arg [arg ...]
+	Eval(script string, keys []string, args ...interface{}) (interface{}, error)
+	// EvalSha EVALSHA sha1 numkeys key [key ...] arg [arg ...]
+	EvalSha(hash string, keys []string, args ...interface{}) (interface{}, error)
+	// ScriptLoad 将脚本加入缓存但不执行，返回脚本的 sha1
+	// 集群模式下加载到所有的主节点
+	ScriptLoad(script string) (string, error)
+	// ScriptExists 脚本是否已经缓存，集群模式下所有的主节点都缓存了才返回 true
+	ScriptExists(hashes ...string) ([]bool, error)
+	// ScriptFlush 清除所有缓存的脚本，集群模式下清除所有的主节点
+	ScriptFlush() error
+}
+
+// Doer 可以执行命令，Cache 以及 Tx 都实现了该接口
+type Doer interface {
+	DO(cmd string, args ...interface{}) (interface{}, error)
+}
+
+var (
+	// scripts 通过 NewScript 创建的脚本
+	scripts   []*Script
+	scriptsMu sync.Mutex
+)
+
+// Script lua 脚本，使用 EVALSHA 执行，redis-server 没有缓存该脚本时自动使用 EVAL
+type Script struct {
+	src  string
+	hash string
+}
+
+// NewScript 创建脚本，并加入注册表，见 LoadScripts
+func NewScript(src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	s := &Script{
+		src:  src,
+		hash: hex.EncodeToString(sum[:]),
+	}
+
+	scriptsMu.Lock()
+	scripts = append(scripts, s)
+	scriptsMu.Unlock()
+	return s
+}
+
+// LoadScripts 加载所有通过 NewScript 创建的脚本
+func LoadScripts(c Cache) error {
+	scriptsMu.Lock()
+	list := make([]*Script, len(scripts))
+	copy(list, scripts)
+	scriptsMu.Unlock()
+
+	for _, s := range list {
+		if err := s.Load(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Hash 脚本的 sha1
+func (s *Script) Hash() string {
+	return s.hash
+}
+
+// String 脚本内容
+func (s *Script) String() string {
+	return s.src
+}
+
+// Load 加载脚本
+func (s *Script) Load(c Cache) error {
+	_, err := c.ScriptLoad(s.src)
+	return err
+}
+
+// Exists 脚本是否已经加载
+func (s *Script) Exists(c Cache) (bool, error) {
+	exists, err := c.ScriptExists(s.hash)
+	if err != nil {
+		return false, err
+	}
+	return exists[0], nil
+}
+
+// Eval 使用 EVAL 执行脚本
+func (s *Script) Eval(d Doer, keys []string, args ...interface{}) (interface{}, error) {
+	return d.DO("EVAL", scriptArgs(s.src, keys, args)...)
+}
+
+// EvalSha 使用 EVALSHA 执行脚本
+func (s *Script) EvalSha(d Doer, keys []string, args ...interface{}) (interface{}, error) {
+	return d.DO("EVALSHA", scriptArgs(s.hash, keys, args)...)
+}
+
+// Run 使用 EVALSHA 执行脚本，返回 NOSCRIPT 错误时使用 EVAL 重新执行
+// EVAL 执行之后 redis-server 会缓存该脚本，之后的 EVALSHA 不再需要重试
+func (s *Script) Run(d Doer, keys []string, args ...interface{}) (interface{}, error) {
+	reply, err := s.EvalSha(d, keys, args...)
+	if isNoScript(err) {
+		return s.Eval(d, keys, args...)
+	}
+	return reply, err
+}
+
+// Eval ..
+func (c *cache) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.DO("EVAL", scriptArgs(script, keys, args)...)
+}
+
+// EvalSha ..
+func (c *cache) EvalSha(hash string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.DO("EVALSHA", scriptArgs(hash, keys, args)...)
+}
+
+// ScriptLoad ..
+func (c *cache) ScriptLoad(script string) (string, error) {
+	replies, err := c.scriptAll("LOAD", script)
+	if err != nil {
+		return "", err
+	}
+	return redis.String(replies[0], nil)
+}
+
+// ScriptExists ..
+func (c *cache) ScriptExists(hashes ...string) ([]bool, error) {
+	if len(hashes) == 0 {
+		return nil, ErrInvalidParamCount
+	}
+	args := make([]interface{}, len(hashes))
+	for i, hash := range hashes {
+		args[i] = hash
+	}
+
+	replies, err := c.scriptAll("EXISTS", args...)
+	if err != nil {
+		return nil, err
+	}
+	exists := make([]bool, len(hashes))
+	for i := range exists {
+		exists[i] = true
+	}
+	for _, reply := range replies {
+		values, err := redis.Ints(reply, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != len(exists) {
+			return nil, ErrInvalidParamCount
+		}
+		for i, v := range values {
+			exists[i] = exists[i] && v == 1
+		}
+	}
+	return exists, nil
+}
+
+// ScriptFlush ..
+func (c *cache) ScriptFlush() error {
+	_, err := c.scriptAll("FLUSH")
+	return err
+}
+
+// scriptAll 执行 SCRIPT 命令，集群模式下在所有的主节点上执行
+func (c *cache) scriptAll(sub string, args ...interface{}) ([]interface{}, error) {
+	args = append([]interface{}{sub}, args...)
+	pool, ok := c.pool.(*clusterPool)
+	if !ok {
+		reply, err := c.DO("SCRIPT", args...)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{reply}, nil
+	}
+
+	nodes, err := pool.masters()
+	if err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		reply, err := c.doNode(node, "SCRIPT", args...)
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, reply)
+	}
+	return replies, nil
+}
+
+// scriptArgs script numkeys key [key ...] arg [arg ...]
+func scriptArgs(script string, keys []string, args []interface{}) []interface{} {
+	all := make([]interface{}, 0, 2+len(keys)+len(args))
+	all = append(all, script, len(keys))
+	for _, key := range keys {
+		all = append(all, key)
+	}
+	return append(all, args...)
+}
+
+// isNoScript redis-server 没有缓存该脚本
+func isNoScript(err error) bool {
+	e, ok := err.(redis.Error)
+	return ok && strings.HasPrefix(string(e), "NOSCRIPT")
+}
+
+// IsNoScripting 是否因为不支持 lua 脚本而执行失败，例如内存模式 (WithMemory)，调用方可以改用其它方式实现
+func IsNoScripting(err error) bool {
+	return err == errMemoryNoScripting
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	hook := &recordHook{}
+	c := NewCache(WithMemory(), WithHook(hook))
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	s := NewScript("return redis.call('GET', KEYS[1])")
+	if s.Hash() != "d3c21d0c2b9ca22f82737626a27bcaf5d288f99f" {
+		t.Fatalf("Hash: %s", s.Hash())
+	}
+	if ok, _ := s.Exists(c); ok {
+		t.Fatal("script should not exist before load")
+	}
+	if err := LoadScripts(c); err != nil {
+		t.Fatal(err)
+	}
+	exists, err := c.ScriptExists(s.Hash(), "none")
+	if err != nil || len(exists) != 2 || !exists[0] || exists[1] {
+		t.Fatalf("ScriptExists: %v, err: %v", exists, err)
+	}
+
+	// 已经加载时只执行 EVALSHA
+	s.Run(c, []string{"name"})
+	if cmd := hook.last(); cmd.Name != "EVALSHA" || cmd.Args[0] != s.Hash() || cmd.Args[1] != 1 {
+		t.Fatalf("Run loaded: %+v", cmd)
+	}
+
+	// NOSCRIPT 时使用 EVAL，并且 EVAL 之后脚本被缓存
+	if err := c.ScriptFlush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.EvalSha(c, []string{"name"}); !isNoScript(err) {
+		t.Fatalf("EvalSha after flush, err: %v", err)
+	}
+	s.Run(c, []string{"name"}, "arg")
+	if cmd := hook.last(); cmd.Name != "EVAL" || cmd.Args[0] != s.String() || cmd.Args[3] != "arg" {
+		t.Fatalf("Run fallback: %+v", cmd)
+	}
+	if ok, _ := s.Exists(c); !ok {
+		t.Fatal("script should be cached after EVAL")
+	}
+
+	if _, err := c.Eval("return 1", []string{"a", "b"}); err == nil || isNoScript(err) {
+		t.Fatalf("Eval on memory cache, err: %v", err)
+	}
+}
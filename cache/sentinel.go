@@ -0,0 +1,272 @@
+package cache
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// 哨兵模式
+// 创建连接时通过哨兵获取主节点地址，并订阅哨兵的 +switch-master 事件
+// 主从切换之后，连接池中连接旧主节点的空闲连接会被丢弃
+//
+// eg:
+// c := cache.NewCache(
+// 	cache.WithSentinel("mymaster", "10.0.0.1:26379", "10.0.0.2:26379", "10.0.0.3:26379"),
+// 	cache.WithPassword("password"),
+// )
+
+const (
+	// sentinelTimeout 连接哨兵以及查询的超时时间
+	sentinelTimeout = time.Second
+	// sentinelRetryInterval 订阅哨兵失败之后的重试间隔
+	sentinelRetryInterval = time.Second
+)
+
+var (
+	// ErrMasterNotFound 无法从任何一个哨兵获取主节点地址
+	ErrMasterNotFound = errors.New("sentinel: master not found")
+
+	errMasterChanged = errors.New("sentinel: master changed")
+	errNotMaster     = errors.New("sentinel: node is not master")
+)
+
+type sentinelPool struct {
+	*redis.Pool
+	c *cache
+
+	mu sync.Mutex
+	// addrs 哨兵地址，最近一次可用的哨兵排在最前面
+	addrs []string
+	// master 当前主节点地址
+	master string
+	// generation 主节点发生变化的次数
+	generation uint64
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// sentinelConn 记录创建连接时主节点的版本
+type sentinelConn struct {
+	redis.Conn
+	generation uint64
+}
+
+// DoWithTimeout 连接池只对实现了 redis.ConnWithTimeout 的连接支持超时，用于 DoContext 等
+func (c *sentinelConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+}
+
+// ReceiveWithTimeout 用于 Subscribe 等
+func (c *sentinelConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+func newSentinelPool(c *cache) *sentinelPool {
+	s := &sentinelPool{
+		c:     c,
+		addrs: append([]string(nil), c.conf.sentinelAddrs...),
+		done:  make(chan struct{}),
+	}
+	s.Pool = c.newPool(s.dial)
+	s.Pool.TestOnBorrow = s.testOnBorrow
+
+	go s.watch()
+	return s
+}
+
+// Close ..
+func (s *sentinelPool) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	return s.Pool.Close()
+}
+
+// dial 连接主节点
+func (s *sentinelPool) dial() (redis.Conn, error) {
+	generation := atomic.LoadUint64(&s.generation)
+
+	addr, err := s.masterAddr()
+	if err != nil {
+		return nil, err
+	}
+	s.setMaster(addr)
+
+	conn, err := s.c.dialAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// 主从切换过程中，哨兵返回的节点可能尚未成为主节点
+	role, err := redis.Values(conn.Do("ROLE"))
+	if err == nil && (len(role) == 0 || !isMasterRole(role[0])) {
+		err = errNotMaster
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &sentinelConn{Conn: conn, generation: generation}, nil
+}
+
+func isMasterRole(role interface{}) bool {
+	s, err := redis.String(role, nil)
+	return err == nil && s == "master"
+}
+
+// testOnBorrow 主节点发生变化之后创建的连接不再可用
+func (s *sentinelPool) testOnBorrow(conn redis.Conn, t time.Time) error {
+	if sc, ok := conn.(*sentinelConn); ok && sc.generation != atomic.LoadUint64(&s.generation) {
+		return errMasterChanged
+	}
+	return nil
+}
+
+// setMaster 更新主节点地址，地址发生变化时增加版本号
+func (s *sentinelPool) setMaster(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.master != "" && s.master != addr {
+		atomic.AddUint64(&s.generation, 1)
+	}
+	s.master = addr
+}
+
+func (s *sentinelPool) sentinels() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.addrs...)
+}
+
+// masterAddr 依次询问哨兵，返回主节点地址
+func (s *sentinelPool) masterAddr() (string, error) {
+	for i, addr := range s.sentinels() {
+		master, err := s.queryMaster(addr)
+		if err != nil {
+			continue
+		}
+		if i > 0 {
+			s.promote(addr)
+		}
+		return master, nil
+	}
+	return "", ErrMasterNotFound
+}
+
+// promote 将可用的哨兵移到最前面
+func (s *sentinelPool) promote(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, a := range s.addrs {
+		if a == addr {
+			copy(s.addrs[1:i+1], s.addrs[:i])
+			s.addrs[0] = addr
+			return
+		}
+	}
+}
+
+// queryMaster SENTINEL get-master-addr-by-name
+func (s *sentinelPool) queryMaster(addr string) (string, error) {
+	conn, err := s.dialSentinel(addr, true)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	values, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", s.c.conf.sentinelMaster))
+	if err != nil {
+		return "", err
+	}
+	if len(values) != 2 {
+		return "", ErrMasterNotFound
+	}
+	return net.JoinHostPort(values[0], values[1]), nil
+}
+
+// dialSentinel 连接哨兵，timeout 为 false 时不设置读超时，用于订阅
+func (s *sentinelPool) dialSentinel(addr string, timeout bool) (redis.Conn, error) {
+	options := []redis.DialOption{
+		redis.DialConnectTimeout(sentinelTimeout),
+		redis.DialWriteTimeout(sentinelTimeout),
+	}
+	if timeout {
+		options = append(options, redis.DialReadTimeout(sentinelTimeout))
+	}
+	if password := s.c.conf.sentinelPassword; password != "" {
+		options = append(options, redis.DialPassword(password))
+	}
+	return redis.Dial("tcp", addr, options...)
+}
+
+// watch 订阅哨兵的 +switch-master 事件，直到连接池关闭
+func (s *sentinelPool) watch() {
+	for {
+		s.subscribe()
+
+		select {
+		case <-s.done:
+			return
+		case <-time.After(sentinelRetryInterval):
+		}
+	}
+}
+
+// subscribe 订阅其中一个哨兵，直到连接出错或者连接池关闭
+func (s *sentinelPool) subscribe() {
+	var conn redis.Conn
+	for _, addr := range s.sentinels() {
+		var err error
+		if conn, err = s.dialSentinel(addr, false); err == nil {
+			break
+		}
+	}
+	if conn == nil {
+		return
+	}
+	defer conn.Close()
+
+	// 连接池关闭时关闭连接，结束 Receive
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-s.done:
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	psc := redis.PubSubConn{Conn: conn}
+	if err := psc.Subscribe("+switch-master"); err != nil {
+		return
+	}
+
+	// 订阅之前可能已经发生了切换
+	if addr, err := s.masterAddr(); err == nil {
+		s.setMaster(addr)
+	}
+
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			// <master name> <old ip> <old port> <new ip> <new port>
+			fields := strings.Fields(string(v.Data))
+			if len(fields) == 5 && fields[0] == s.c.conf.sentinelMaster {
+				s.setMaster(net.JoinHostPort(fields[3], fields[4]))
+			}
+		case error:
+			return
+		}
+	}
+}
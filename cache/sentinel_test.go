@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestSentinelMasterChanged(t *testing.T) {
+	c := NewCache(WithSentinel("mymaster", "s1:26379", "s2:26379", "s3:26379")).(*cache)
+	s := &sentinelPool{c: c, addrs: c.conf.sentinelAddrs}
+
+	s.setMaster("10.0.0.1:6379")
+	conn := &sentinelConn{generation: s.generation}
+	if err := s.testOnBorrow(conn, time.Time{}); err != nil {
+		t.Fatalf("testOnBorrow before failover: %v", err)
+	}
+
+	s.setMaster("10.0.0.1:6379")
+	if err := s.testOnBorrow(conn, time.Time{}); err != nil {
+		t.Fatalf("testOnBorrow with same master: %v", err)
+	}
+
+	s.setMaster("10.0.0.2:6379")
+	if err := s.testOnBorrow(conn, time.Time{}); err != errMasterChanged {
+		t.Fatalf("testOnBorrow after failover: %v", err)
+	}
+
+	s.promote("s3:26379")
+	if addrs := s.sentinels(); addrs[0] != "s3:26379" || addrs[1] != "s1:26379" || addrs[2] != "s2:26379" {
+		t.Fatalf("promote: %v", addrs)
+	}
+}
+
+// timeoutConn 记录超时时间
+type timeoutConn struct {
+	redis.Conn
+	timeout time.Duration
+}
+
+func (c *timeoutConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return c.DoWithTimeout(0, cmd, args...)
+}
+
+func (c *timeoutConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	c.timeout = timeout
+	return "PONG", nil
+}
+
+func (c *timeoutConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	c.timeout = timeout
+	return "message", nil
+}
+
+func (c *timeoutConn) Err() error {
+	return nil
+}
+
+func (c *timeoutConn) Close() error {
+	return nil
+}
+
+func TestSentinelConnTimeout(t *testing.T) {
+	raw := &timeoutConn{}
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return &sentinelConn{Conn: raw}, nil
+		},
+	}
+	defer pool.Close()
+
+	conn := pool.Get()
+	defer conn.Close()
+	if _, ok := conn.(redis.ConnWithTimeout); !ok {
+		t.Fatal("pool conn does not support timeout")
+	}
+	if reply, err := redis.DoWithTimeout(conn, time.Second, "PING"); err != nil || reply != "PONG" || raw.timeout != time.Second {
+		t.Fatalf("DoWithTimeout: %v %v %v", reply, err, raw.timeout)
+	}
+	if reply, err := redis.ReceiveWithTimeout(conn, 2*time.Second); err != nil || reply != "message" || raw.timeout != 2*time.Second {
+		t.Fatalf("ReceiveWithTimeout: %v %v %v", reply, err, raw.timeout)
+	}
+}
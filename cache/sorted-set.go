@@ -1,9 +1,77 @@
 package cache
 
 import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
 	"github.com/gomodule/redigo/redis"
 )
 
+// eg:
+// 排行榜，score 相同时按照完成时间排序，使用小数部分区分先后
+// c.ZAddZ("rank", cache.Z{Member: "alex", Score: 100.0001}, cache.Z{Member: "bob", Score: 100.0002})
+// top, err := c.ZRevRangeWithScores("rank", 0, 9)
+// for _, z := range top {
+// 	fmt.Println(z.Member, z.Score)
+// }
+//
+// 分数在 (60, +inf) 之间的成员数量
+// n, err := c.ZCount("rank", cache.ExclusiveScore(60), cache.MaxScore)
+
+const (
+	// MinScore, MaxScore 分数区间的无穷边界
+	MinScore = "-inf"
+	MaxScore = "+inf"
+	// MinLex, MaxLex 字典序区间的无穷边界
+	MinLex = "-"
+	MaxLex = "+"
+)
+
+var errSortedSetReply = errors.New("cache: unexpected sorted set reply")
+
+// Score 分数区间的边界，包含 score
+func Score(score float64) string {
+	return formatFloat(score)
+}
+
+// ExclusiveScore 分数区间的边界，不包含 score
+func ExclusiveScore(score float64) string {
+	return "(" + formatFloat(score)
+}
+
+// Lex 字典序区间的边界，包含 member
+func Lex(member string) string {
+	return "[" + member
+}
+
+// ExclusiveLex 字典序区间的边界，不包含 member
+func ExclusiveLex(member string) string {
+	return "(" + member
+}
+
+// Z 有序集合的成员以及 score
+type Z struct {
+	Member string
+	Score  float64
+}
+
+// ZWithKey 阻塞弹出的结果，Key 为成员所在的有序集合
+type ZWithKey struct {
+	Key string
+	Z
+}
+
+// ZStore ZUNIONSTORE/ZINTERSTORE 的参数
+type ZStore struct {
+	Keys []string
+	// Weights 每个 key 的 score 的乘数，为空时都为 1
+	Weights []float64
+	// Aggregate 相同成员的 score 的聚合方式: SUM, MIN, MAX，为空时为 SUM
+	Aggregate string
+}
+
 // ZAdd 将一个或多个 member 元素及其 score 值加入到有序集 key 当中
 // 返回被成功添加的新成员的数量，不包括那些被更新的、已经存在的成员
 // 第一个v 是 key
@@ -19,21 +87,41 @@ func (c *cache) ZAdd(v ...interface{}) (int, error) {
 	return redis.Int(c.DO("ZADD", v...))
 }
 
+// ZAddZ 与 ZAdd 相同，使用 Z 指定成员以及 score
+func (c *cache) ZAddZ(key string, members ...Z) (int, error) {
+	if len(members) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, 0, 1+len(members)*2)
+	args = append(args, key)
+	for _, z := range members {
+		args = append(args, z.Score, z.Member)
+	}
+	return redis.Int(c.DO("ZADD", args...))
+}
+
 // ZCard 返回有序集 key 的基数
 func (c *cache) ZCard(key string) (int, error) {
 	return redis.Int(c.DO("ZCARD", key))
 }
 
-// ZCount 返回有序集 key 中， score 值在 min 和 max 之间(默认包括 score 值等于 min 或 max )的成员的数量
-func (c *cache) ZCount(key string, min int, max int) (int, error) {
+// ZCount 返回有序集 key 中， score 值在 min 和 max 之间的成员的数量
+// min, max 可以使用 Score, ExclusiveScore, MinScore, MaxScore
+func (c *cache) ZCount(key, min, max string) (int, error) {
 	return redis.Int(c.DO("ZCOUNT", key, min, max))
 }
 
+// ZLexCount 所有成员的 score 相同时，返回有序集 key 中成员按照字典序介于 min 和 max 之间的数量
+// min, max 可以使用 Lex, ExclusiveLex, MinLex, MaxLex
+func (c *cache) ZLexCount(key, min, max string) (int, error) {
+	return redis.Int(c.DO("ZLEXCOUNT", key, min, max))
+}
+
 // ZIncrby 为有序集 key 的成员 member 的 score 值加上增量 increment
 // increment 可以为负值
 // 返回 member 成员的新 score 值
-func (c *cache) ZIncrby(key, member string, incrment int) (int, error) {
-	return redis.Int(c.DO("ZINCRBY", key, incrment, member))
+func (c *cache) ZIncrby(key, member string, increment float64) (float64, error) {
+	return redis.Float64(c.DO("ZINCRBY", key, increment, member))
 }
 
 // ZRange 返回有序集 key 中，指定下标区间内的成员
@@ -42,13 +130,13 @@ func (c *cache) ZRange(key string, start, stop int) ([]string, error) {
 }
 
 // ZRangeWithScores 返回有序集 key 中，指定下标区间内的成员和 score值
-func (c *cache) ZRangeWithScores(key string, start, stop int) ([]string, error) {
-	return redis.Strings(c.DO("ZRANGE", key, start, stop, "WITHSCORES"))
+func (c *cache) ZRangeWithScores(key string, start, stop int) ([]Z, error) {
+	return zSlice(c.DO("ZRANGE", key, start, stop, "WITHSCORES"))
 }
 
-// ZScore 返回有序集 key 中，成员 member 的 score 值
-func (c *cache) ZScore(key, member string) (int, error) {
-	return redis.Int(c.DO("ZSCORE", key, member))
+// ZScore 返回有序集 key 中，成员 member 的 score 值，成员不存在时返回 ErrNil
+func (c *cache) ZScore(key, member string) (float64, error) {
+	return redis.Float64(c.DO("ZSCORE", key, member))
 }
 
 // ZRank 返回有序集 key 中成员 member 的下标
@@ -57,10 +145,11 @@ func (c *cache) ZRank(key, member string) (int, error) {
 	return redis.Int(c.DO("ZRANK", key, member))
 }
 
-// ZRangeByScore 返回有序集 key 中，所有 score 值介于 min 和 max 之间(包括等于 min 或 max )的成
+// ZRangeByScore 返回有序集 key 中，所有 score 值介于 min 和 max 之间的成员
 // 有序集成员按 score 值递增(从小到大)次序排列
-func (c *cache) ZRangeByScore(key string, min, max, limit, offset, count int) ([]string, error) {
-	return redis.Strings(c.DO("ZRANGEBYSCORE", key, min, max, "WITHSCORES", limit, offset, count))
+// 跳过 offset 个成员之后最多返回 count 个，count 小于 0 时返回 offset 之后所有的成员
+func (c *cache) ZRangeByScore(key, min, max string, offset, count int) ([]Z, error) {
+	return zSlice(c.DO("ZRANGEBYSCORE", key, min, max, "WITHSCORES", "LIMIT", offset, count))
 }
 
 // ZRevRank 返回有序集 key 中成员 member 的排名。其中有序集成员按 score 值递减(从大到小)排序
@@ -68,21 +157,26 @@ func (c *cache) ZRevRank(key, member string) (int, error) {
 	return redis.Int(c.DO("ZREVRANK", key, member))
 }
 
-// ZRevRangeByScore 返回有序集 key 中， score 值介于 max 和 min 之间(默认包括等于 max 或 min )的所有的成员
+// ZRevRangeByScore 返回有序集 key 中， score 值介于 max 和 min 之间的所有的成员
 // 有序集成员按 score 值递减(从大到小)的次序排列
-func (c *cache) ZRevRangeByScore(key string, max, min, limit, offset, count int) ([]string, error) {
-	return redis.Strings(c.DO("ZREVRANGEBYSCORE", key, max, min, "WITHSCORES", limit, offset, count))
+// 跳过 offset 个成员之后最多返回 count 个，count 小于 0 时返回 offset 之后所有的成员
+func (c *cache) ZRevRangeByScore(key, max, min string, offset, count int) ([]Z, error) {
+	return zSlice(c.DO("ZREVRANGEBYSCORE", key, max, min, "WITHSCORES", "LIMIT", offset, count))
 }
 
-// ZRevRange 返回有序集 key 中，指定下标区间内的成员
+// ZRevRange 返回有序集 key 中，指定下标区间内的成员，按 score 值递减(从大到小)排列
 func (c *cache) ZRevRange(key string, start, stop int) ([]string, error) {
-	return redis.Strings(c.DO("ZREVRANGE", key, start, stop, "WITHSCORES"))
+	return redis.Strings(c.DO("ZREVRANGE", key, start, stop))
+}
+
+// ZRevRangeWithScores 返回有序集 key 中，指定下标区间内的成员和 score 值，按 score 值递减(从大到小)排列
+func (c *cache) ZRevRangeWithScores(key string, start, stop int) ([]Z, error) {
+	return zSlice(c.DO("ZREVRANGE", key, start, stop, "WITHSCORES"))
 }
 
-// ZRemRangeByScore 移除有序集 key 中，所有 score 值介于 min 和 max 之间(包括等于 min 或 max )的成员
-// 移除有序集 key 中，所有 score 值介于 min 和 max 之间(包括等于 min 或 max )的成员
+// ZRemRangeByScore 移除有序集 key 中，所有 score 值介于 min 和 max 之间的成员
 // 返回被移除成员的数量
-func (c *cache) ZRemRangeByScore(key string, min, max int) (int, error) {
+func (c *cache) ZRemRangeByScore(key, min, max string) (int, error) {
 	return redis.Int(c.DO("ZREMRANGEBYSCORE", key, min, max))
 }
 
@@ -99,3 +193,117 @@ func (c *cache) ZRemRangeByRank(key string, start, stop int) (int, error) {
 func (c *cache) ZRem(v ...interface{}) (int, error) {
 	return redis.Int(c.DO("ZREM", v...))
 }
+
+// ZUnionStore 计算给定的一个或多个有序集的并集，并将结果保存到 destination
+// 返回 destination 的成员数量
+func (c *cache) ZUnionStore(destination string, store ZStore) (int, error) {
+	return c.zStore("ZUNIONSTORE", destination, store)
+}
+
+// ZInterStore 计算给定的一个或多个有序集的交集，并将结果保存到 destination
+// 返回 destination 的成员数量
+func (c *cache) ZInterStore(destination string, store ZStore) (int, error) {
+	return c.zStore("ZINTERSTORE", destination, store)
+}
+
+func (c *cache) zStore(cmd, destination string, store ZStore) (int, error) {
+	if len(store.Keys) == 0 || (len(store.Weights) > 0 && len(store.Weights) != len(store.Keys)) {
+		return 0, ErrInvalidParamCount
+	}
+	args := []interface{}{destination, len(store.Keys)}
+	for _, key := range store.Keys {
+		args = append(args, key)
+	}
+	if len(store.Weights) > 0 {
+		args = append(args, "WEIGHTS")
+		for _, weight := range store.Weights {
+			args = append(args, weight)
+		}
+	}
+	if store.Aggregate != "" {
+		args = append(args, "AGGREGATE", store.Aggregate)
+	}
+	return redis.Int(c.DO(cmd, args...))
+}
+
+// ZPopMin 移除并返回有序集 key 中 score 最小的 count 个成员
+func (c *cache) ZPopMin(key string, count int) ([]Z, error) {
+	return zSlice(c.DO("ZPOPMIN", key, count))
+}
+
+// ZPopMax 移除并返回有序集 key 中 score 最大的 count 个成员
+func (c *cache) ZPopMax(key string, count int) ([]Z, error) {
+	return zSlice(c.DO("ZPOPMAX", key, count))
+}
+
+// BZPopMin ZPopMin 的阻塞版本，从第一个不为空的有序集中弹出 score 最小的成员
+// 所有的有序集都为空时阻塞，超过 timeout 返回 ErrNil，timeout 为 0 时一直阻塞
+// timeout 向上取整到秒
+func (c *cache) BZPopMin(timeout time.Duration, keys ...string) (*ZWithKey, error) {
+	return c.bzPop("BZPOPMIN", timeout, keys)
+}
+
+// BZPopMax ZPopMax 的阻塞版本，从第一个不为空的有序集中弹出 score 最大的成员
+func (c *cache) BZPopMax(timeout time.Duration, keys ...string) (*ZWithKey, error) {
+	return c.bzPop("BZPOPMAX", timeout, keys)
+}
+
+func (c *cache) bzPop(cmd string, timeout time.Duration, keys []string) (*ZWithKey, error) {
+	if len(keys) == 0 {
+		return nil, ErrInvalidParamCount
+	}
+	args := make([]interface{}, 0, len(keys)+1)
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	args = append(args, int64((timeout+time.Second-1)/time.Second))
+
+	values, err := redis.Strings(c.DO(cmd, args...))
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 3 {
+		return nil, errSortedSetReply
+	}
+	score, err := parseFloat(values[2])
+	if err != nil {
+		return nil, err
+	}
+	return &ZWithKey{Key: values[0], Z: Z{Member: values[1], Score: score}}, nil
+}
+
+// zSlice 将 member 与 score 交替出现的回复转为 []Z
+func zSlice(reply interface{}, err error) ([]Z, error) {
+	values, err := redis.Strings(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	if len(values)%2 != 0 {
+		return nil, errSortedSetReply
+	}
+	zs := make([]Z, len(values)/2)
+	for i := range zs {
+		score, err := parseFloat(values[i*2+1])
+		if err != nil {
+			return nil, err
+		}
+		zs[i] = Z{Member: values[i*2], Score: score}
+	}
+	return zs, nil
+}
+
+// parseFloat 解析 redis 返回的 score，包括 inf 以及 -inf
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+// formatFloat 与 redis 一致，无穷使用 +inf 以及 -inf 表示
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return MaxScore
+	case math.IsInf(f, -1):
+		return MinScore
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
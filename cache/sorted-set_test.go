@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSortedSetScore(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	c.ZAddZ("rank", Z{"alex", 100.0002}, Z{"bob", 100.0001}, Z{"carl", 60.5}, Z{"dave", math.Inf(1)})
+
+	if score, _ := c.ZScore("rank", "carl"); score != 60.5 {
+		t.Fatalf("ZScore: %v", score)
+	}
+	if _, err := c.ZScore("rank", "none"); err != ErrNil {
+		t.Fatalf("ZScore missing member, err: %v", err)
+	}
+	if score, _ := c.ZIncrby("rank", "carl", 0.25); score != 60.75 {
+		t.Fatalf("ZIncrby: %v", score)
+	}
+
+	zs, _ := c.ZRevRangeWithScores("rank", 0, 2)
+	if len(zs) != 3 || zs[0].Member != "dave" || !math.IsInf(zs[0].Score, 1) || zs[1] != (Z{"alex", 100.0002}) || zs[2].Member != "bob" {
+		t.Fatalf("ZRevRangeWithScores: %v", zs)
+	}
+	if members, _ := c.ZRevRange("rank", 0, 0); len(members) != 1 || members[0] != "dave" {
+		t.Fatalf("ZRevRange: %v", members)
+	}
+
+	// 不包含边界以及无穷边界
+	if n, _ := c.ZCount("rank", ExclusiveScore(60.75), MaxScore); n != 3 {
+		t.Fatalf("ZCount exclusive: %d", n)
+	}
+	if n, _ := c.ZCount("rank", MinScore, Score(100.0001)); n != 2 {
+		t.Fatalf("ZCount inclusive: %d", n)
+	}
+	zs, _ = c.ZRangeByScore("rank", ExclusiveScore(60.75), MaxScore, 1, 1)
+	if len(zs) != 1 || zs[0].Member != "alex" {
+		t.Fatalf("ZRangeByScore: %v", zs)
+	}
+	zs, _ = c.ZRevRangeByScore("rank", ExclusiveScore(math.Inf(1)), MinScore, 0, -1)
+	if len(zs) != 3 || zs[0].Member != "alex" || zs[2].Member != "carl" {
+		t.Fatalf("ZRevRangeByScore: %v", zs)
+	}
+	if n, _ := c.ZRemRangeByScore("rank", Score(100), ExclusiveScore(100.0002)); n != 1 {
+		t.Fatalf("ZRemRangeByScore: %d", n)
+	}
+}
+
+func TestSortedSetStore(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	c.ZAddZ("a", Z{"m1", 1}, Z{"m2", 2})
+	c.ZAddZ("b", Z{"m2", 3}, Z{"m3", 4})
+	c.SAdd("s", "m2", "m4")
+
+	if n, _ := c.ZUnionStore("union", ZStore{Keys: []string{"a", "b", "s"}}); n != 4 {
+		t.Fatalf("ZUnionStore: %d", n)
+	}
+	if zs, _ := c.ZRangeWithScores("union", 0, -1); len(zs) != 4 || zs[0] != (Z{"m1", 1}) || zs[3] != (Z{"m2", 6}) {
+		t.Fatalf("union: %v", zs)
+	}
+
+	n, _ := c.ZInterStore("inter", ZStore{Keys: []string{"a", "b"}, Weights: []float64{2, 0.5}, Aggregate: "MAX"})
+	if n != 1 {
+		t.Fatalf("ZInterStore: %d", n)
+	}
+	if score, _ := c.ZScore("inter", "m2"); score != 4 {
+		t.Fatalf("inter score: %v", score)
+	}
+
+	if _, err := c.ZUnionStore("union", ZStore{Keys: []string{"a"}, Weights: []float64{1, 2}}); err != ErrInvalidParamCount {
+		t.Fatalf("ZUnionStore with wrong weights, err: %v", err)
+	}
+}
+
+func TestSortedSetPop(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	c.ZAddZ("rank", Z{"m1", 1}, Z{"m2", 2}, Z{"m3", 3}, Z{"m4", 4})
+	if zs, _ := c.ZPopMin("rank", 2); len(zs) != 2 || zs[0] != (Z{"m1", 1}) || zs[1] != (Z{"m2", 2}) {
+		t.Fatalf("ZPopMin: %v", zs)
+	}
+	if zs, _ := c.ZPopMax("rank", 1); len(zs) != 1 || zs[0] != (Z{"m4", 4}) {
+		t.Fatalf("ZPopMax: %v", zs)
+	}
+
+	z, err := c.BZPopMin(time.Second, "none", "rank")
+	if err != nil || z.Key != "rank" || z.Z != (Z{"m3", 3}) {
+		t.Fatalf("BZPopMin: %v, err: %v", z, err)
+	}
+
+	// 所有的有序集都为空时阻塞，直到有新的成员
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c.ZAdd("rank", 5, "m5")
+	}()
+	start := time.Now()
+	z, err = c.BZPopMax(time.Second, "rank")
+	if err != nil || z.Member != "m5" || time.Since(start) < 40*time.Millisecond {
+		t.Fatalf("BZPopMax: %v, err: %v", z, err)
+	}
+
+	if _, err := c.BZPopMin(time.Second/2, "rank"); err != ErrNil {
+		t.Fatalf("BZPopMin timeout, err: %v", err)
+	}
+}
+
+func TestSortedSetLex(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	c.ZAdd("words", 0, "a", 0, "b", 0, "c", 0, "d")
+	if n, _ := c.ZLexCount("words", MinLex, MaxLex); n != 4 {
+		t.Fatalf("ZLexCount all: %d", n)
+	}
+	if n, _ := c.ZLexCount("words", Lex("b"), ExclusiveLex("d")); n != 2 {
+		t.Fatalf("ZLexCount: %d", n)
+	}
+	if _, err := c.ZLexCount("words", "b", MaxLex); err == nil {
+		t.Fatal("ZLexCount with invalid bound should fail")
+	}
+}
+
+func TestSortedSetReply(t *testing.T) {
+	// member 与 score 不成对时是回复格式错误，不是参数错误
+	if _, err := zSlice([]interface{}{[]byte("alex")}, nil); err != errSortedSetReply {
+		t.Fatalf("zSlice odd reply, err: %v", err)
+	}
+	zs, err := zSlice([]interface{}{[]byte("alex"), []byte("1.5")}, nil)
+	if err != nil || len(zs) != 1 || zs[0].Member != "alex" || zs[0].Score != 1.5 {
+		t.Fatalf("zSlice: %v, err: %v", zs, err)
+	}
+}
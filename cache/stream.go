@@ -0,0 +1,357 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// 需要 redis-server 5.0 及以上版本
+//
+// eg:
+// 生产者
+// id, err := c.XAdd("events", "type", "order", "id", 1001)
+//
+// 消费者组
+// c.XGroupCreate("events", "workers", "0")
+// for {
+// 	streams, err := c.XReadGroup("workers", "worker-1", 10, 5*time.Second, "events", ">")
+// 	if err == cache.ErrNil {
+// 		// 超时，认领其它消费者超过 1 分钟没有确认的消息
+// 		messages, _ := c.XClaimStale("events", "workers", "worker-1", time.Minute, 10)
+// 		...
+// 		continue
+// 	}
+// 	for _, stream := range streams {
+// 		for _, msg := range stream.Messages {
+// 			// 处理消息
+// 			c.XAck(stream.Stream, "workers", msg.ID)
+// 		}
+// 	}
+// }
+
+var (
+	// ErrGroupExists 消费者组已经存在
+	ErrGroupExists = errors.New("consumer group already exists")
+
+	errStreamReply = errors.New("cache: unexpected stream reply")
+)
+
+// Stream 消息流
+type Stream interface {
+	// XAdd 添加消息，values 为 field value 交替出现，返回消息 id
+	XAdd(key string, values ...interface{}) (string, error)
+
+	// XAddMaxLen 添加消息，并将消息流的长度限制为大约 maxLen
+	XAddMaxLen(key string, maxLen int, values ...interface{}) (string, error)
+
+	// XLen 消息数量
+	XLen(key string) (int, error)
+
+	// XRange 返回 id 在 [start, end] 之间的消息，- 和 + 分别表示最小和最大的 id，count <= 0 表示不限制数量
+	XRange(key, start, end string, count int) ([]XMessage, error)
+
+	// XDel 删除消息，返回删除的数量
+	XDel(key string, ids ...string) (int, error)
+
+	// XGroupCreate 创建消费者组，key 不存在时会创建
+	// start 为 $ 表示只消费之后添加的消息，为 0 表示从头开始消费
+	// 消费者组已经存在时返回 ErrGroupExists
+	XGroupCreate(key, group, start string) error
+
+	// XGroupDestroy 删除消费者组
+	XGroupDestroy(key, group string) (bool, error)
+
+	// XReadGroup 以 consumer 的身份读取消息
+	// streams 为 key 和 id 两部分，例如 "s1", "s2", ">", ">"
+	// id 为 > 表示读取新的消息，否则读取该消费者已经读取但是没有确认的消息
+	// block > 0 时，没有消息则最多等待 block，超时返回 ErrNil
+	XReadGroup(group, consumer string, count int, block time.Duration, streams ...string) ([]XStream, error)
+
+	// XAck 确认消息，返回确认的数量
+	XAck(key, group string, ids ...string) (int, error)
+
+	// XPending 返回已经读取但是没有确认的消息，consumer 为空表示所有消费者
+	XPending(key, group, start, end string, count int, consumer string) ([]XPendingEntry, error)
+
+	// XClaim 将空闲时间超过 minIdle 的未确认消息转移给 consumer
+	XClaim(key, group, consumer string, minIdle time.Duration, ids ...string) ([]XMessage, error)
+
+	// XClaimStale 最多认领 count 条空闲时间超过 minIdle 的未确认消息，用于处理崩溃的消费者遗留的消息
+	XClaimStale(key, group, consumer string, minIdle time.Duration, count int) ([]XMessage, error)
+}
+
+// XMessage 消息
+type XMessage struct {
+	ID string
+	// Values 消息内容，消息已经被删除时为 nil
+	Values map[string]string
+}
+
+// XStream XReadGroup 返回的一个消息流的消息
+type XStream struct {
+	Stream   string
+	Messages []XMessage
+}
+
+// XPendingEntry 未确认的消息
+type XPendingEntry struct {
+	ID       string
+	Consumer string
+	// Idle 距离上一次投递的时间
+	Idle time.Duration
+	// RetryCount 投递次数
+	RetryCount int64
+}
+
+// XAdd 添加消息
+// XADD key * field value [field value ...]
+func (c *cache) XAdd(key string, values ...interface{}) (string, error) {
+	if len(values) == 0 || len(values)%2 != 0 {
+		return "", ErrInvalidParamCount
+	}
+	args := append([]interface{}{key, "*"}, values...)
+	return redis.String(c.DO("XADD", args...))
+}
+
+// XAddMaxLen 添加消息，并限制消息流的长度
+// XADD key MAXLEN ~ maxLen * field value [field value ...]
+func (c *cache) XAddMaxLen(key string, maxLen int, values ...interface{}) (string, error) {
+	if len(values) == 0 || len(values)%2 != 0 {
+		return "", ErrInvalidParamCount
+	}
+	args := append([]interface{}{key, "MAXLEN", "~", maxLen, "*"}, values...)
+	return redis.String(c.DO("XADD", args...))
+}
+
+// XLen 消息数量
+func (c *cache) XLen(key string) (int, error) {
+	return redis.Int(c.DO("XLEN", key))
+}
+
+// XRange 返回 id 在 [start, end] 之间的消息
+// XRANGE key start end [COUNT count]
+func (c *cache) XRange(key, start, end string, count int) ([]XMessage, error) {
+	args := []interface{}{key, start, end}
+	if count > 0 {
+		args = append(args, "COUNT", count)
+	}
+	return xMessages(c.DO("XRANGE", args...))
+}
+
+// XDel 删除消息
+func (c *cache) XDel(key string, ids ...string) (int, error) {
+	if len(ids) == 0 {
+		return 0, ErrInvalidParamCount
+	}
+	args := []interface{}{key}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return redis.Int(c.DO("XDEL", args...))
+}
+
+// XGroupCreate 创建消费者组
+// XGROUP CREATE key group start MKSTREAM
+func (c *cache) XGroupCreate(key, group, start string) error {
+	_, err := c.DO("XGROUP", "CREATE", key, group, start, "MKSTREAM")
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return ErrGroupExists
+	}
+	return err
+}
+
+// XGroupDestroy 删除消费者组
+func (c *cache) XGroupDestroy(key, group string) (bool, error) {
+	return redis.Bool(c.DO("XGROUP", "DESTROY", key, group))
+}
+
+// XReadGroup 以 consumer 的身份读取消息
+// XREADGROUP GROUP group consumer [COUNT count] [BLOCK milliseconds] STREAMS key [key ...] id [id ...]
+func (c *cache) XReadGroup(group, consumer string, count int, block time.Duration, streams ...string) ([]XStream, error) {
+	if len(streams) == 0 || len(streams)%2 != 0 {
+		return nil, ErrInvalidParamCount
+	}
+
+	args := []interface{}{"GROUP", group, consumer}
+	if count > 0 {
+		args = append(args, "COUNT", count)
+	}
+	if block > 0 {
+		args = append(args, "BLOCK", int64(block/time.Millisecond))
+	}
+	args = append(args, "STREAMS")
+	for _, stream := range streams {
+		args = append(args, stream)
+	}
+
+	values, err := redis.Values(c.DO("XREADGROUP", args...))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]XStream, 0, len(values))
+	for _, value := range values {
+		stream, err := redis.Values(value, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(stream) != 2 {
+			return nil, errStreamReply
+		}
+		key, err := redis.String(stream[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		messages, err := xMessages(stream[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, XStream{Stream: key, Messages: messages})
+	}
+	return result, nil
+}
+
+// XAck 确认消息
+func (c *cache) XAck(key, group string, ids ...string) (int, error) {
+	if len(ids) == 0 {
+		return 0, ErrInvalidParamCount
+	}
+	args := []interface{}{key, group}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return redis.Int(c.DO("XACK", args...))
+}
+
+// XPending 返回未确认的消息
+// XPENDING key group start end count [consumer]
+func (c *cache) XPending(key, group, start, end string, count int, consumer string) ([]XPendingEntry, error) {
+	args := []interface{}{key, group, start, end, count}
+	if consumer != "" {
+		args = append(args, consumer)
+	}
+
+	values, err := redis.Values(c.DO("XPENDING", args...))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]XPendingEntry, 0, len(values))
+	for _, value := range values {
+		var (
+			entry XPendingEntry
+			idle  int64
+		)
+		fields, err := redis.Values(value, nil)
+		if err == nil {
+			_, err = redis.Scan(fields, &entry.ID, &entry.Consumer, &idle, &entry.RetryCount)
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry.Idle = time.Duration(idle) * time.Millisecond
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// XClaim 将空闲时间超过 minIdle 的未确认消息转移给 consumer
+// XCLAIM key group consumer min-idle-time id [id ...]
+func (c *cache) XClaim(key, group, consumer string, minIdle time.Duration, ids ...string) ([]XMessage, error) {
+	if len(ids) == 0 {
+		return nil, ErrInvalidParamCount
+	}
+	args := []interface{}{key, group, consumer, int64(minIdle / time.Millisecond)}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return xMessages(c.DO("XCLAIM", args...))
+}
+
+// XClaimStale 认领空闲时间超过 minIdle 的未确认消息
+// 分页遍历 XPENDING，避免前 count 条消息都在处理中时，之后的消息永远无法被认领
+func (c *cache) XClaimStale(key, group, consumer string, minIdle time.Duration, count int) ([]XMessage, error) {
+	if count <= 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, count)
+	start := "-"
+	for len(ids) < count {
+		entries, err := c.XPending(key, group, start, "+", count, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Idle >= minIdle && len(ids) < count {
+				ids = append(ids, entry.ID)
+			}
+		}
+		if len(entries) < count {
+			break
+		}
+		if start, err = nextStreamID(entries[len(entries)-1].ID); err != nil {
+			return nil, err
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return c.XClaim(key, group, consumer, minIdle, ids...)
+}
+
+// nextStreamID 大于 id 的最小的 id，用于分页，redis 6.2 之前不支持 (id 的写法
+func nextStreamID(id string) (string, error) {
+	i := strings.IndexByte(id, '-')
+	if i < 0 {
+		return "", errStreamReply
+	}
+	seq, err := strconv.ParseUint(id[i+1:], 10, 64)
+	if err != nil {
+		return "", errStreamReply
+	}
+	if seq < math.MaxUint64 {
+		return id[:i+1] + strconv.FormatUint(seq+1, 10), nil
+	}
+	ms, err := strconv.ParseUint(id[:i], 10, 64)
+	if err != nil {
+		return "", errStreamReply
+	}
+	return strconv.FormatUint(ms+1, 10) + "-0", nil
+}
+
+// xMessages 将 [[id, [field, value, ...]], ...] 转为消息
+func xMessages(reply interface{}, err error) ([]XMessage, error) {
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]XMessage, 0, len(values))
+	for _, value := range values {
+		entry, err := redis.Values(value, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(entry) != 2 {
+			return nil, errStreamReply
+		}
+
+		var msg XMessage
+		if msg.ID, err = redis.String(entry[0], nil); err != nil {
+			return nil, err
+		}
+		if entry[1] != nil {
+			if msg.Values, err = redis.StringMap(entry[1], nil); err != nil {
+				return nil, err
+			}
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	if _, err := c.XAdd("events", "type"); err != ErrInvalidParamCount {
+		t.Fatalf("XAdd with odd values, err: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := c.XAdd("events", "type", "order", "index", i); err != nil {
+			t.Fatalf("XAdd failed: %s", err.Error())
+		}
+	}
+	if n, _ := c.XLen("events"); n != 5 {
+		t.Fatalf("XLen: %d", n)
+	}
+
+	msgs, err := c.XRange("events", "-", "+", 2)
+	if err != nil || len(msgs) != 2 || msgs[1].Values["index"] != "1" {
+		t.Fatalf("XRange: %v, err: %v", msgs, err)
+	}
+	if n, _ := c.XDel("events", msgs[0].ID, "1-1"); n != 1 {
+		t.Fatalf("XDel: %d", n)
+	}
+
+	c.XAddMaxLen("events", 3, "type", "order", "index", 5)
+	if n, _ := c.XLen("events"); n != 3 {
+		t.Fatalf("XLen after XAddMaxLen: %d", n)
+	}
+}
+
+func TestStreamGroup(t *testing.T) {
+	c := newMemoryCache(t)
+	defer c.Close()
+
+	if err := c.XGroupCreate("events", "workers", "$"); err != nil {
+		t.Fatalf("XGroupCreate failed: %s", err.Error())
+	}
+	if err := c.XGroupCreate("events", "workers", "$"); err != ErrGroupExists {
+		t.Fatalf("XGroupCreate twice, err: %v", err)
+	}
+
+	// 没有消息时阻塞，直到超时
+	start := time.Now()
+	if _, err := c.XReadGroup("workers", "w1", 10, 20*time.Millisecond, "events", ">"); err != ErrNil {
+		t.Fatalf("XReadGroup on empty stream, err: %v", err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("XReadGroup should block")
+	}
+
+	// 阻塞期间添加的消息会被读取
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.XAdd("events", "type", "order")
+	}()
+	streams, err := c.XReadGroup("workers", "w1", 10, time.Second, "events", ">")
+	if err != nil || len(streams) != 1 || len(streams[0].Messages) != 1 {
+		t.Fatalf("XReadGroup: %v, err: %v", streams, err)
+	}
+	id := streams[0].Messages[0].ID
+
+	c.XAdd("events", "type", "refund")
+	streams, _ = c.XReadGroup("workers", "w1", 10, 0, "events", ">")
+	if len(streams) != 1 || streams[0].Messages[0].Values["type"] != "refund" {
+		t.Fatalf("XReadGroup: %v", streams)
+	}
+	if n, _ := c.XAck("events", "workers", streams[0].Messages[0].ID); n != 1 {
+		t.Fatalf("XAck: %d", n)
+	}
+
+	// 读取已投递但是没有确认的消息
+	streams, _ = c.XReadGroup("workers", "w1", 10, 0, "events", "0")
+	if len(streams) != 1 || len(streams[0].Messages) != 1 || streams[0].Messages[0].ID != id {
+		t.Fatalf("XReadGroup pending: %v", streams)
+	}
+
+	entries, err := c.XPending("events", "workers", "-", "+", 10, "")
+	if err != nil || len(entries) != 1 || entries[0].Consumer != "w1" || entries[0].RetryCount != 2 {
+		t.Fatalf("XPending: %v, err: %v", entries, err)
+	}
+
+	// w1 没有确认，由 w2 认领
+	if msgs, _ := c.XClaimStale("events", "workers", "w2", time.Hour, 10); len(msgs) != 0 {
+		t.Fatalf("XClaimStale should skip active message: %v", msgs)
+	}
+	time.Sleep(20 * time.Millisecond)
+	msgs, err := c.XClaimStale("events", "workers", "w2", 10*time.Millisecond, 10)
+	if err != nil || len(msgs) != 1 || msgs[0].ID != id {
+		t.Fatalf("XClaimStale: %v, err: %v", msgs, err)
+	}
+	if entries, _ := c.XPending("events", "workers", "-", "+", 10, "w2"); len(entries) != 1 {
+		t.Fatalf("XPending of w2: %v", entries)
+	}
+
+	// 前 count 条消息正在处理，之后的消息空闲时间已经超过 minIdle
+	c.XAck("events", "workers", id)
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, _ := c.XAdd("events", "index", i)
+		ids = append(ids, id)
+	}
+	c.XReadGroup("workers", "w1", 10, 0, "events", ">")
+	time.Sleep(20 * time.Millisecond)
+	if msgs, err := c.XClaim("events", "workers", "w1", 0, ids[0], ids[1]); err != nil || len(msgs) != 2 {
+		t.Fatalf("XClaim: %v, err: %v", msgs, err)
+	}
+	msgs, err = c.XClaimStale("events", "workers", "w2", 10*time.Millisecond, 2)
+	if err != nil || len(msgs) != 1 || msgs[0].ID != ids[2] {
+		t.Fatalf("XClaimStale behind active messages: %v, err: %v", msgs, err)
+	}
+	id = ids[0]
+	c.XAck("events", "workers", ids[1], ids[2])
+
+	c.XAck("events", "workers", id)
+	if entries, _ := c.XPending("events", "workers", "-", "+", 10, ""); len(entries) != 0 {
+		t.Fatalf("XPending after XAck: %v", entries)
+	}
+
+	if ok, _ := c.XGroupDestroy("events", "workers"); !ok {
+		t.Fatal("XGroupDestroy failed")
+	}
+	if _, err := c.XReadGroup("workers", "w1", 10, 0, "events", ">"); err == nil {
+		t.Fatal("XReadGroup without group should fail")
+	}
+}
@@ -0,0 +1,70 @@
+package tiered
+
+import "time"
+
+// Policy 本地缓存的淘汰策略
+type Policy int
+
+const (
+	// LRU 淘汰最久没有访问的 key
+	LRU Policy = iota
+	// LFU 淘汰访问次数最少的 key，次数相同时淘汰最久没有访问的
+	LFU
+)
+
+// config 配置
+type config struct {
+	policy Policy
+	// size 本地缓存最多保存的 key 数量
+	size int
+	// ttl 本地缓存的生存时间，同时也是收不到失效通知时 (例如订阅断开) 数据最长的不一致时间
+	ttl time.Duration
+	// channel 失效通知的频道，同一组实例需要相同
+	channel string
+}
+
+func defaultConfig() *config {
+	return &config{
+		policy:  LRU,
+		size:    10000,
+		ttl:     time.Minute,
+		channel: "ghelper:tiered:invalidate",
+	}
+}
+
+// Option ..
+type Option func(*config)
+
+// WithPolicy 淘汰策略，默认为 LRU
+func WithPolicy(policy Policy) Option {
+	return func(c *config) {
+		c.policy = policy
+	}
+}
+
+// WithSize 本地缓存最多保存的 key 数量，默认为 10000
+func WithSize(size int) Option {
+	return func(c *config) {
+		if size > 0 {
+			c.size = size
+		}
+	}
+}
+
+// WithTTL 本地缓存的生存时间，默认为 1 分钟
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		if ttl > 0 {
+			c.ttl = ttl
+		}
+	}
+}
+
+// WithChannel 失效通知的频道
+func WithChannel(channel string) Option {
+	return func(c *config) {
+		if channel != "" {
+			c.channel = channel
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package tiered
+
+import (
+	"container/heap"
+	"container/list"
+	"time"
+)
+
+// store 本地缓存，由调用者加锁
+type store interface {
+	// get 获取未过期的值
+	get(key string, now time.Time) (string, bool)
+	// set 保存值，返回因为容量不足被淘汰的数量
+	set(key, value string, expireAt time.Time) int
+	del(key string) bool
+	clear()
+	len() int
+}
+
+func newStore(policy Policy, size int) store {
+	if policy == LFU {
+		return newLFU(size)
+	}
+	return newLRU(size)
+}
+
+type entry struct {
+	key      string
+	value    string
+	expireAt time.Time
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !now.Before(e.expireAt)
+}
+
+// lru 最近最少使用，链表头部为最近访问的 key
+type lru struct {
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (s *lru) get(key string, now time.Time) (string, bool) {
+	elem, exist := s.items[key]
+	if !exist {
+		return "", false
+	}
+	e := elem.Value.(*entry)
+	if e.expired(now) {
+		s.remove(elem)
+		return "", false
+	}
+	s.ll.MoveToFront(elem)
+	return e.value, true
+}
+
+func (s *lru) set(key, value string, expireAt time.Time) int {
+	if elem, exist := s.items[key]; exist {
+		e := elem.Value.(*entry)
+		e.value, e.expireAt = value, expireAt
+		s.ll.MoveToFront(elem)
+		return 0
+	}
+
+	s.items[key] = s.ll.PushFront(&entry{key: key, value: value, expireAt: expireAt})
+
+	evicted := 0
+	for s.ll.Len() > s.size {
+		s.remove(s.ll.Back())
+		evicted++
+	}
+	return evicted
+}
+
+func (s *lru) del(key string) bool {
+	elem, exist := s.items[key]
+	if exist {
+		s.remove(elem)
+	}
+	return exist
+}
+
+func (s *lru) remove(elem *list.Element) {
+	s.ll.Remove(elem)
+	delete(s.items, elem.Value.(*entry).key)
+}
+
+func (s *lru) clear() {
+	s.ll.Init()
+	s.items = make(map[string]*list.Element)
+}
+
+func (s *lru) len() int {
+	return s.ll.Len()
+}
+
+// lfu 最不经常使用，使用最小堆按照访问次数以及最后访问时间排序
+type lfu struct {
+	size  int
+	items map[string]*lfuEntry
+	heap  lfuHeap
+	// clock 访问序号，用于次数相同时比较先后
+	clock uint64
+}
+
+type lfuEntry struct {
+	entry
+	count  uint64
+	access uint64
+	index  int
+}
+
+type lfuHeap []*lfuEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count < h[j].count
+	}
+	return h[i].access < h[j].access
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	e := x.(*lfuEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+func newLFU(size int) *lfu {
+	return &lfu{
+		size:  size,
+		items: make(map[string]*lfuEntry),
+	}
+}
+
+func (s *lfu) touch(e *lfuEntry) {
+	s.clock++
+	e.count++
+	e.access = s.clock
+	heap.Fix(&s.heap, e.index)
+}
+
+func (s *lfu) get(key string, now time.Time) (string, bool) {
+	e, exist := s.items[key]
+	if !exist {
+		return "", false
+	}
+	if e.expired(now) {
+		s.remove(e)
+		return "", false
+	}
+	s.touch(e)
+	return e.value, true
+}
+
+func (s *lfu) set(key, value string, expireAt time.Time) int {
+	if e, exist := s.items[key]; exist {
+		e.value, e.expireAt = value, expireAt
+		s.touch(e)
+		return 0
+	}
+
+	evicted := 0
+	for len(s.items) >= s.size {
+		s.remove(s.heap[0])
+		evicted++
+	}
+
+	s.clock++
+	e := &lfuEntry{entry: entry{key: key, value: value, expireAt: expireAt}, count: 1, access: s.clock}
+	heap.Push(&s.heap, e)
+	s.items[key] = e
+	return evicted
+}
+
+func (s *lfu) del(key string) bool {
+	e, exist := s.items[key]
+	if exist {
+		s.remove(e)
+	}
+	return exist
+}
+
+func (s *lfu) remove(e *lfuEntry) {
+	heap.Remove(&s.heap, e.index)
+	delete(s.items, e.key)
+}
+
+func (s *lfu) clear() {
+	s.items = make(map[string]*lfuEntry)
+	s.heap = nil
+}
+
+func (s *lfu) len() int {
+	return len(s.items)
+}
@@ -0,0 +1,265 @@
+package tiered
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alex-my/ghelper/cache"
+	"github.com/alex-my/ghelper/random"
+)
+
+// 两级缓存: 进程内的 LRU/LFU 缓存 + redis
+// 通过 Set, SetEx, Del, Invalidate 修改数据时，会通过 redis 发布订阅通知其它实例删除本地缓存
+// 直接通过 cache.Cache 修改的数据，需要调用 Invalidate
+// 订阅断开期间的失效通知会丢失，此时本地缓存最多保留 WithTTL 设置的时间
+//
+// eg:
+// t, err := tiered.New(c, tiered.WithPolicy(tiered.LFU), tiered.WithSize(1000), tiered.WithTTL(30*time.Second))
+// if err != nil {
+// 	return err
+// }
+// defer t.Close()
+//
+// v, err := t.Get("config:version")
+// stats := t.Stats()
+
+// Cache 两级缓存
+type Cache interface {
+	// Get 优先读取本地缓存，不存在时读取 redis 并写入本地缓存
+	Get(key string) (string, error)
+
+	// Set 写入 redis，并删除所有实例的本地缓存
+	Set(key string, value interface{}) error
+
+	// SetEx 写入 redis 并设置生存时间，并删除所有实例的本地缓存
+	SetEx(key string, value interface{}, ttl time.Duration) error
+
+	// Del 删除 redis 中的 key，并删除所有实例的本地缓存
+	Del(keys ...string) (int, error)
+
+	// Invalidate 删除所有实例的本地缓存，keys 为空时清空所有本地缓存
+	Invalidate(keys ...string) error
+
+	// Stats 统计信息
+	Stats() Stats
+
+	// Close 取消订阅，之后不再接收失效通知
+	Close() error
+}
+
+// Stats 统计信息
+type Stats struct {
+	// Hits, Misses 本地缓存命中以及没有命中的次数
+	Hits   uint64
+	Misses uint64
+	// RedisHits, RedisMisses 本地缓存没有命中时，redis 命中以及没有命中的次数
+	RedisHits   uint64
+	RedisMisses uint64
+	// Evictions 本地缓存容量不足被淘汰的数量
+	Evictions uint64
+	// Invalidations 收到其它实例的失效通知的次数
+	Invalidations uint64
+	// Size 本地缓存当前的 key 数量
+	Size int
+}
+
+// HitRate 本地缓存命中率
+func (s Stats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// invalidation 失效通知
+type invalidation struct {
+	// ID 发送通知的实例
+	ID   string   `json:"id"`
+	Keys []string `json:"keys"`
+}
+
+type tiered struct {
+	c    cache.Cache
+	conf *config
+	id   string
+	sub  cache.Subscription
+
+	mu    sync.Mutex
+	local store
+	// epoch 每次删除本地缓存时增加，读取 redis 期间发生删除时不写入本地缓存，避免写入旧值
+	epoch uint64
+
+	hits          uint64
+	misses        uint64
+	redisHits     uint64
+	redisMisses   uint64
+	evictions     uint64
+	invalidations uint64
+}
+
+// New 创建两级缓存，并订阅失效通知
+func New(c cache.Cache, opts ...Option) (Cache, error) {
+	conf := defaultConfig()
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	sub, err := c.Subscribe(conf.channel)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &tiered{
+		c:     c,
+		conf:  conf,
+		id:    random.NewUUID(),
+		sub:   sub,
+		local: newStore(conf.policy, conf.size),
+	}
+	go t.listen()
+	return t, nil
+}
+
+// Get ..
+func (t *tiered) Get(key string) (string, error) {
+	now := time.Now()
+
+	t.mu.Lock()
+	value, ok := t.local.get(key, now)
+	epoch := t.epoch
+	t.mu.Unlock()
+
+	if ok {
+		atomic.AddUint64(&t.hits, 1)
+		return value, nil
+	}
+	atomic.AddUint64(&t.misses, 1)
+
+	p := t.c.Pipeline()
+	get := p.Get(key)
+	pttl := p.PTTL(key)
+	p.Exec()
+
+	value, err := get.Result()
+	if err == cache.ErrNil {
+		atomic.AddUint64(&t.redisMisses, 1)
+		return "", err
+	}
+	if err != nil {
+		return "", err
+	}
+	atomic.AddUint64(&t.redisHits, 1)
+
+	// 本地缓存不超过 redis 中剩余的生存时间
+	ttl := t.conf.ttl
+	if ms, err := pttl.Result(); err == nil && ms > 0 && time.Duration(ms)*time.Millisecond < ttl {
+		ttl = time.Duration(ms) * time.Millisecond
+	}
+
+	t.mu.Lock()
+	if t.epoch == epoch {
+		if evicted := t.local.set(key, value, now.Add(ttl)); evicted > 0 {
+			atomic.AddUint64(&t.evictions, uint64(evicted))
+		}
+	}
+	t.mu.Unlock()
+
+	return value, nil
+}
+
+// Set ..
+func (t *tiered) Set(key string, value interface{}) error {
+	if err := t.c.Set(key, value); err != nil {
+		return err
+	}
+	return t.Invalidate(key)
+}
+
+// SetEx ..
+func (t *tiered) SetEx(key string, value interface{}, ttl time.Duration) error {
+	ms := int64(ttl / time.Millisecond)
+	if _, err := t.c.DO("SET", key, value, "PX", ms); err != nil {
+		return err
+	}
+	return t.Invalidate(key)
+}
+
+// Del ..
+func (t *tiered) Del(keys ...string) (int, error) {
+	if len(keys) == 0 {
+		return 0, cache.ErrInvalidParamCount
+	}
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	n, err := t.c.Del(args...)
+	if err != nil {
+		return n, err
+	}
+	return n, t.Invalidate(keys...)
+}
+
+// Invalidate ..
+func (t *tiered) Invalidate(keys ...string) error {
+	t.drop(keys)
+
+	data, err := json.Marshal(&invalidation{ID: t.id, Keys: keys})
+	if err != nil {
+		return err
+	}
+	_, err = t.c.Publish(t.conf.channel, data)
+	return err
+}
+
+// Stats ..
+func (t *tiered) Stats() Stats {
+	t.mu.Lock()
+	size := t.local.len()
+	t.mu.Unlock()
+
+	return Stats{
+		Hits:          atomic.LoadUint64(&t.hits),
+		Misses:        atomic.LoadUint64(&t.misses),
+		RedisHits:     atomic.LoadUint64(&t.redisHits),
+		RedisMisses:   atomic.LoadUint64(&t.redisMisses),
+		Evictions:     atomic.LoadUint64(&t.evictions),
+		Invalidations: atomic.LoadUint64(&t.invalidations),
+		Size:          size,
+	}
+}
+
+// Close ..
+func (t *tiered) Close() error {
+	return t.sub.Close()
+}
+
+// drop 删除本地缓存，keys 为空时清空
+func (t *tiered) drop(keys []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.epoch++
+	if len(keys) == 0 {
+		t.local.clear()
+		return
+	}
+	for _, key := range keys {
+		t.local.del(key)
+	}
+}
+
+// listen 接收其它实例的失效通知
+func (t *tiered) listen() {
+	for msg := range t.sub.Channel() {
+		var inv invalidation
+		if err := json.Unmarshal(msg.Data, &inv); err != nil || inv.ID == t.id {
+			continue
+		}
+		atomic.AddUint64(&t.invalidations, 1)
+		t.drop(inv.Keys)
+	}
+}
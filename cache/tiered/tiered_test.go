@@ -0,0 +1,172 @@
+package tiered
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alex-my/ghelper/cache"
+)
+
+func newCache(t *testing.T) cache.Cache {
+	c := cache.NewCache(cache.WithMemory())
+	if err := c.Open(); err != nil {
+		t.Fatalf("open memory cache failed: %s", err.Error())
+	}
+	return c
+}
+
+func newTiered(t *testing.T, c cache.Cache, opts ...Option) Cache {
+	tc, err := New(c, opts...)
+	if err != nil {
+		t.Fatalf("New failed: %s", err.Error())
+	}
+	return tc
+}
+
+func TestGet(t *testing.T) {
+	c := newCache(t)
+	defer c.Close()
+
+	tc := newTiered(t, c)
+	defer tc.Close()
+
+	if _, err := tc.Get("name"); err != cache.ErrNil {
+		t.Fatalf("Get missing key, err: %v", err)
+	}
+
+	c.Set("name", "alex")
+	for i := 0; i < 3; i++ {
+		if v, err := tc.Get("name"); err != nil || v != "alex" {
+			t.Fatalf("Get: %s, err: %v", v, err)
+		}
+	}
+
+	// 直接修改 redis，本地缓存仍然是旧值
+	c.Set("name", "bob")
+	if v, _ := tc.Get("name"); v != "alex" {
+		t.Fatalf("Get from local: %s", v)
+	}
+	tc.Invalidate("name")
+	if v, _ := tc.Get("name"); v != "bob" {
+		t.Fatalf("Get after Invalidate: %s", v)
+	}
+
+	stats := tc.Stats()
+	if stats.Hits != 3 || stats.Misses != 3 || stats.RedisHits != 2 || stats.RedisMisses != 1 || stats.Size != 1 {
+		t.Fatalf("Stats: %+v", stats)
+	}
+	if rate := stats.HitRate(); rate != 0.5 {
+		t.Fatalf("HitRate: %f", rate)
+	}
+}
+
+func TestRedisTTL(t *testing.T) {
+	c := newCache(t)
+	defer c.Close()
+
+	tc := newTiered(t, c)
+	defer tc.Close()
+
+	// 本地缓存不会超过 redis 中的生存时间
+	tc.SetEx("name", "alex", 20*time.Millisecond)
+	if v, _ := tc.Get("name"); v != "alex" {
+		t.Fatalf("Get: %s", v)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := tc.Get("name"); err != cache.ErrNil {
+		t.Fatalf("Get expired key, err: %v", err)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	c := newCache(t)
+	defer c.Close()
+
+	t1 := newTiered(t, c)
+	defer t1.Close()
+	t2 := newTiered(t, c)
+	defer t2.Close()
+
+	// 等待通知到达，避免后续的判断受到影响
+	waitInvalidations := func(n uint64) {
+		for i := 0; i < 100 && t2.Stats().Invalidations < n; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	t1.Set("name", "alex")
+	waitInvalidations(1)
+	if v, _ := t2.Get("name"); v != "alex" {
+		t.Fatalf("t2 Get: %s", v)
+	}
+
+	// t1 修改之后，t2 收到通知删除本地缓存
+	t1.Set("name", "bob")
+	waitInvalidations(2)
+	if v, _ := t2.Get("name"); v != "bob" {
+		t.Fatalf("t2 Get after broadcast: %s", v)
+	}
+	if n := t1.Stats().Invalidations; n != 0 {
+		t.Fatalf("t1 should ignore its own invalidation: %d", n)
+	}
+
+	if n, err := t1.Del("name"); err != nil || n != 1 {
+		t.Fatalf("Del: %d, err: %v", n, err)
+	}
+	waitInvalidations(3)
+	if _, err := t2.Get("name"); err != cache.ErrNil {
+		t.Fatalf("t2 Get after Del, err: %v", err)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	s := newStore(LRU, 2)
+	expireAt := time.Now().Add(time.Minute)
+
+	s.set("a", "1", expireAt)
+	s.set("b", "2", expireAt)
+	s.get("a", time.Now())
+	if evicted := s.set("c", "3", expireAt); evicted != 1 {
+		t.Fatalf("evicted: %d", evicted)
+	}
+	if _, ok := s.get("b", time.Now()); ok {
+		t.Fatal("b should be evicted")
+	}
+	if _, ok := s.get("a", time.Now()); !ok {
+		t.Fatal("a should be kept")
+	}
+
+	s.set("d", "4", time.Now())
+	if _, ok := s.get("d", time.Now()); ok {
+		t.Fatal("d should be expired")
+	}
+}
+
+func TestLFU(t *testing.T) {
+	s := newStore(LFU, 3)
+	expireAt := time.Now().Add(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		s.set(strconv.Itoa(i), "v", expireAt)
+	}
+	// 0 访问 3 次，1 访问 1 次，2 访问 2 次
+	for i := 0; i < 2; i++ {
+		s.get("0", time.Now())
+	}
+	s.get("2", time.Now())
+
+	s.set("3", "v", expireAt)
+	if _, ok := s.get("1", time.Now()); ok {
+		t.Fatal("1 should be evicted")
+	}
+
+	// 3 访问次数最少
+	s.set("4", "v", expireAt)
+	if _, ok := s.get("3", time.Now()); ok {
+		t.Fatal("3 should be evicted")
+	}
+	if s.len() != 3 || !s.del("0") || s.len() != 2 {
+		t.Fatalf("len: %d", s.len())
+	}
+}
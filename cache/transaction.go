@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// eg:
+// 乐观锁: 读取 key 之后计算新值，如果期间 key 被其它客户端修改，则重试
+// err := c.WatchRetry(3, func(tx cache.Tx) error {
+// 	n, err := c.Int(tx.DO("GET", "count"))
+// 	if err != nil && err != cache.ErrNil {
+// 		return err
+// 	}
+//
+// 	p := tx.Pipeline()
+// 	p.Set("count", n*2)
+// 	return p.Exec()
+// }, "count")
+
+var (
+	// ErrTxFailed 被 WATCH 的 key 在事务执行之前被修改，事务没有执行
+	ErrTxFailed = errors.New("transaction failed")
+)
+
+// Transaction 事务
+type Transaction interface {
+	// Pipeline 创建管道
+	Pipeline() Pipeline
+
+	// TxPipeline 创建事务管道，使用 MULTI/EXEC 包裹所有命令
+	TxPipeline() Pipeline
+
+	// Watch 监视 keys，然后执行 fn
+	// 在 fn 中通过 tx.Pipeline() 提交的命令，如果 keys 被其它客户端修改，返回 ErrTxFailed
+	Watch(fn func(tx Tx) error, keys ...string) error
+
+	// WatchRetry 与 Watch 相同，当返回 ErrTxFailed 时最多重试 retries 次
+	WatchRetry(retries int, fn func(tx Tx) error, keys ...string) error
+}
+
+// Tx 乐观锁事务，只在 Watch 的回调函数中有效
+type Tx interface {
+	// DO 在被监视的连接上立即执行命令，通常用于读取被监视的 key
+	DO(cmd string, args ...interface{}) (interface{}, error)
+
+	// Pipeline 创建事务管道，Exec 时使用 MULTI/EXEC 包裹所有命令
+	Pipeline() Pipeline
+}
+
+type tx struct {
+	c    *cache
+	conn redis.Conn
+}
+
+// DO ..
+func (t *tx) DO(cmd string, args ...interface{}) (interface{}, error) {
+	return t.c.process(t.c.Context(), cmd, args, func(ctx context.Context) (interface{}, error) {
+		return doContext(ctx, t.conn, cmd, args...)
+	})
+}
+
+// Pipeline ..
+func (t *tx) Pipeline() Pipeline {
+	return &pipeline{c: t.c, conn: t.conn, tx: true}
+}
+
+// Watch 监视 keys，然后执行 fn
+func (c *cache) Watch(fn func(tx Tx) error, keys ...string) error {
+	if len(keys) == 0 {
+		return ErrInvalidParamCount
+	}
+	if err := c.Context().Err(); err != nil {
+		return err
+	}
+
+	conn := c.pool.Get()
+	if conn == nil {
+		return ErrInvalidConn
+	}
+	// 连接放回连接池时会自动 UNWATCH
+	defer conn.Close()
+
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	if _, err := conn.Do("WATCH", args...); err != nil {
+		return err
+	}
+
+	return fn(&tx{c: c, conn: conn})
+}
+
+// WatchRetry 当返回 ErrTxFailed 时最多重试 retries 次
+func (c *cache) WatchRetry(retries int, fn func(tx Tx) error, keys ...string) error {
+	for i := 0; ; i++ {
+		err := c.Watch(fn, keys...)
+		if err != ErrTxFailed || i >= retries {
+			return err
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+// ghelper 命令行工具
+//
+// 加密配置中的值，输出 ENC(...)，可以直接写入配置文件
+// ghelper config encrypt -key my-secret 123456
+//
+// 解密，没有指定 value 时从标准输入读取
+// echo 'ENC(...)' | CONFIG_SECRET_KEY=my-secret ghelper config decrypt
+//
+// 没有指定 -key 时使用环境变量 CONFIG_SECRET_KEY 或者 CONFIG_SECRET_KEY_FILE
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/alex-my/ghelper/config"
+)
+
+const usage = `usage:
+  ghelper config encrypt [-key secret] [value]
+  ghelper config decrypt [-key secret] [value]
+`
+
+func main() {
+	if len(os.Args) < 3 || os.Args[1] != "config" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err := runConfig(os.Args[2], os.Args[3:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func runConfig(cmd string, args []string) error {
+	fs := flag.NewFlagSet("config "+cmd, flag.ExitOnError)
+	key := fs.String("key", "", "secret key, default: $"+config.SecretKeyEnv+" or the file in $"+config.SecretKeyFileEnv)
+	fs.Parse(args)
+
+	secret := *key
+	if secret == "" {
+		var err error
+		if secret, err = config.SecretKeyFromEnv(); err != nil {
+			return err
+		}
+	}
+
+	value := strings.Join(fs.Args(), " ")
+	if fs.NArg() == 0 {
+		bytes, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		value = strings.TrimRight(string(bytes), "\r\n")
+	}
+
+	var result string
+	var err error
+	switch cmd {
+	case "encrypt":
+		result, err = config.Encrypt(secret, value)
+	case "decrypt":
+		result, err = config.Decrypt(secret, strings.TrimSpace(value))
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(result)
+	return nil
+}
@@ -3,7 +3,9 @@ package compress
 import (
 	"bytes"
 	"compress/gzip"
+	"io"
 	"io/ioutil"
+	"os"
 )
 
 var defaultLevel = gzip.DefaultCompression
@@ -21,10 +23,15 @@ func GzipCompress(in []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer writer.Close()
 
 	_, err = writer.Write(in)
 	if err != nil {
+		writer.Close()
+		return nil, err
+	}
+
+	// Close 之后才会写入剩余的数据以及校验信息
+	if err = writer.Close(); err != nil {
 		return nil, err
 	}
 
@@ -42,3 +49,31 @@ func GzipUncompress(in []byte) ([]byte, error) {
 
 	return ioutil.ReadAll(reader)
 }
+
+// GzipFile 将 src 文件压缩为 dst，不会读取整个文件到内存，失败时删除 dst
+func GzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	writer, err := gzip.NewWriterLevel(out, defaultLevel)
+	if err == nil {
+		if _, err = io.Copy(writer, in); err == nil {
+			err = writer.Close()
+		}
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
@@ -3,9 +3,12 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"flag"
 	"io/ioutil"
 	"os"
 	"strconv"
+	"sync"
+	"sync/atomic"
 
 	"github.com/BurntSushi/toml"
 	yaml "gopkg.in/yaml.v2"
@@ -25,11 +28,16 @@ import (
 // 使用配置的实例来获取配置
 // framework, _ := c.Any("framework")
 //
-// 如果只有一个配置实例，推荐使用 config.C, config.D 等辅助函数
+// 推荐使用 config.C, config.D 等辅助函数读取默认实例，第一个 NewConfig 创建的实例为默认实例
 // version, _ := config.C("framework")
 //
+// 库或者模块使用独立的实例，不影响默认实例，见 registry.go
+// flags := config.Named("feature-flags")
+//
 // 如果配置不存在，使用默认值
 // addr := config.D("addr", "127.0.0.1:8080")
+//
+// 同时从配置文件、环境变量以及命令行参数中读取配置，见 layer.go
 
 // Config 配置文件
 type Config interface {
@@ -51,71 +59,191 @@ type Config interface {
 	// FileYAML 从 yaml 文件中读取配置
 	FileYAML(path string) error
 
-	// Any 根据 key 获取对应数据
+	// LoadEnv 读取以 prefix 开头的环境变量，见 layer.go
+	LoadEnv(prefix string, opts ...EnvOption) error
+
+	// FileEnv 从 .env 文件中读取配置，规则与 LoadEnv 相同
+	FileEnv(path, prefix string, opts ...EnvOption) error
+
+	// LoadFlags 读取命令行参数，fs 为 nil 时使用 flag.CommandLine
+	LoadFlags(fs *flag.FlagSet) error
+
+	// Set 设置 key 的值，优先级最高
+	Set(key string, value interface{})
+
+	// Any 根据 key 获取对应数据，key 可以是 . 连接的路径，例如 database.host, servers.0.addr
 	Any(key string) (interface{}, error)
+
+	// Unmarshal 将 path 对应的数据绑定到结构体 v，path 为空时绑定所有数据，见 unmarshal.go
+	Unmarshal(path string, v interface{}) error
+
+	// SetSecretKey 设置解密 ENC(...) 格式的值使用的密钥，没有设置时使用环境变量，见 secret.go
+	SetSecretKey(secret string)
+
+	// OnChange 注册回调，配置发生变化时调用，keys 为发生变化的 key，嵌套的 key 使用 . 连接，例如 server.port
+	OnChange(fn func(keys []string))
+
+	// Watch 监听通过 FileJSON, FileTOML, FileYAML, FileEnv 加载的文件，文件发生变化时重新加载
+	Watch(opts ...WatchOption) error
+
+	// StopWatch 停止监听
+	StopWatch()
 }
 
+var (
+	// ErrNotExist 配置不存在
+	ErrNotExist = errors.New("Configuration does not exist")
+	// ErrNotScalar 配置是对象或者数组，无法转为字符串
+	ErrNotScalar = errors.New("Configuration is not a scalar value")
+)
+
 type config struct {
-	// init true: 初始化完毕；false 尚未初始化完毕
-	init bool
-	// data 存储数据
+	// init 1: 初始化完毕；0 尚未初始化完毕，使用 atomic 读写
+	init int32
+	// data 存储数据，类型为 map[string]interface{}，重新加载时整体替换
+	data atomic.Value
+
+	// mu 保护以下字段，同时保证加载数据的操作依次执行
+	mu sync.Mutex
+	// sources 按加载顺序记录的数据来源，重新加载时依次读取，合并时按照优先级排序
+	sources []*source
+	// secret 解密 ENC(...) 使用的密钥，见 SetSecretKey
+	secret string
+	// overrides Set 设置的值
+	overrides *source
+	// callbacks 配置发生变化时的回调
+	callbacks []func(keys []string)
+	// watcher 文件监听，见 Watch
+	watcher *watcher
+}
+
+// source 数据来源
+type source struct {
+	// layer 优先级，见 layer.go
+	layer int
+	// name 名称，用于校验失败时提示配置的位置，文件为文件路径
+	name string
+	// path 文件路径，用于 Watch，不是文件时为空
+	path string
+	// read 读取数据，重新加载时再次调用
+	read func() (map[string]interface{}, error)
+	// data 最近一次读取的数据
 	data map[string]interface{}
 }
 
-var defaultConfig *config
+const (
+	formatJSON = "json"
+	formatTOML = "toml"
+	formatYAML = "yaml"
+)
 
 // NewConfig 生成一个配置文件实例
+// 还没有默认实例时，该实例成为默认实例，供 config.C 等辅助函数使用，之后创建的实例不会替换默认实例，见 registry.go
 func NewConfig() Config {
-	data := make(map[string]interface{})
-	defaultConfig = &config{data: data}
-	return defaultConfig
+	c := newConfig()
+	setDefaultIfNil(c)
+	return c
+}
+
+func newConfig() *config {
+	c := &config{}
+	c.data.Store(make(map[string]interface{}))
+	return c
 }
 
 // LoadJSON 从 bytes 数据中读取 JSON 配置
 func (c *config) LoadJSON(bytes []byte) error {
-	if bytes == nil {
-		return errors.New("Bytes cannot be empty")
-	}
-
-	err := json.Unmarshal(bytes, &c.data)
-	if err != nil {
-		return err
-	}
-
-	c.init = true
-	return nil
+	return c.load(bytesSource(formatJSON, bytes))
 }
 
 // LoadTOML 从 bytes 数据中读取 TOML 配置
 func (c *config) LoadTOML(bytes []byte) error {
-	if bytes == nil {
-		return errors.New("Bytes cannot be empty")
-	}
+	return c.load(bytesSource(formatTOML, bytes))
+}
 
-	// m := map[string]interface{}{}
-	if _, err := toml.Decode(string(bytes), &c.data); err != nil {
-		return err
+// LoadYAML 从 bytes 数据中读取 YAML 配置
+func (c *config) LoadYAML(bytes []byte) error {
+	return c.load(bytesSource(formatYAML, bytes))
+}
+
+func bytesSource(format string, bytes []byte) *source {
+	return &source{
+		layer: layerFile,
+		name:  "<" + format + " bytes>",
+		read: func() (map[string]interface{}, error) {
+			return decodeBytes(format, bytes)
+		},
 	}
+}
 
-	c.init = true
-	return nil
+// fileSource 每次读取时都重新读取文件
+func fileSource(format, path string) *source {
+	return &source{
+		layer: layerFile,
+		name:  path,
+		path:  path,
+		read: func() (map[string]interface{}, error) {
+			bytes, err := loadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			return decodeBytes(format, bytes)
+		},
+	}
 }
 
-// LoadYAML 从 bytes 数据中读取 YAML 配置
-func (c *config) LoadYAML(bytes []byte) error {
+func decodeBytes(format string, bytes []byte) (map[string]interface{}, error) {
 	if bytes == nil {
-		return errors.New("Bytes cannot be empty")
+		return nil, errors.New("Bytes cannot be empty")
 	}
 
-	err := yaml.Unmarshal(bytes, &c.data)
+	data := make(map[string]interface{})
+	var err error
+	switch format {
+	case formatJSON:
+		err = json.Unmarshal(bytes, &data)
+	case formatTOML:
+		_, err = toml.Decode(string(bytes), &data)
+	case formatYAML:
+		err = yaml.Unmarshal(bytes, &data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// load 读取 src，然后与其它数据来源合并
+func (c *config) load(src *source) error {
+	data, err := src.read()
 	if err != nil {
 		return err
 	}
+	src.data = data
+
+	c.mu.Lock()
+	c.sources = append(c.sources, src)
+	keys := c.swap(mergeSources(c.sources))
+	atomic.StoreInt32(&c.init, 1)
+	callbacks := c.callbacks
+	c.mu.Unlock()
 
-	c.init = true
+	notify(callbacks, keys)
 	return nil
 }
 
+// values 当前的数据，不可修改
+func (c *config) values() map[string]interface{} {
+	return c.data.Load().(map[string]interface{})
+}
+
+// swap 替换数据，返回发生变化的 key
+func (c *config) swap(data map[string]interface{}) []string {
+	keys := changedKeys(c.values(), data)
+	c.data.Store(data)
+	return keys
+}
+
 func loadFile(path string) ([]byte, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -131,58 +259,63 @@ func loadFile(path string) ([]byte, error) {
 
 // FileJSON 从 json 文件中读取配置
 func (c *config) FileJSON(path string) error {
-	bytes, err := loadFile(path)
-	if err != nil {
-		return err
-	}
-	return c.LoadJSON(bytes)
+	return c.load(fileSource(formatJSON, path))
 }
 
 // FileTOML 从 toml 文件中读取配置
 func (c *config) FileTOML(path string) error {
-	bytes, err := loadFile(path)
-	if err != nil {
-		return err
-	}
-	return c.LoadTOML(bytes)
+	return c.load(fileSource(formatTOML, path))
 }
 
 // FileYAML 从 yaml 文件中读取配置
 func (c *config) FileYAML(path string) error {
-	bytes, err := loadFile(path)
-	if err != nil {
-		return err
-	}
-	return c.LoadYAML(bytes)
+	return c.load(fileSource(formatYAML, path))
 }
 
-// Any 根据 key 获取对应数据
+// Any 根据 key 获取对应数据，key 可以是 . 连接的路径，例如 servers.0.addr
+// ENC(...) 格式的值会被解密，见 secret.go
 func (c *config) Any(key string) (interface{}, error) {
-	if value, exist := c.data[key]; exist {
-		return value, nil
+	value, exist := lookup(c.values(), key)
+	if !exist {
+		return nil, ErrNotExist
 	}
-	return nil, errors.New("Configuration does not exist")
+	return c.decrypt(key, value)
+}
+
+// loaded 是否已经加载过数据
+func (c *config) loaded() bool {
+	return atomic.LoadInt32(&c.init) == 1
 }
 
-func checkInit() {
-	if defaultConfig == nil || !defaultConfig.init {
+// checkInit 返回默认实例，尚未初始化时 panic
+func checkInit() Config {
+	c := Default()
+	if conf, ok := c.(*config); c == nil || ok && !conf.loaded() {
 		panic("Please call NewConfig initialization first, then call Loadxxx or Filexxx to load the data.")
 	}
+	return c
 }
 
 // Any 获取指定 key 对应的数据
 func Any(key string) (interface{}, error) {
-	checkInit()
-	return defaultConfig.Any(key)
+	return checkInit().Any(key)
+}
+
+// Unmarshal 将 path 对应的数据绑定到结构体 v
+func Unmarshal(path string, v interface{}) error {
+	return checkInit().Unmarshal(path, v)
 }
 
-// C 获取配置
+// C 获取配置，数字以及 bool 转为字符串
 func C(key string) (string, error) {
 	value, err := Any(key)
 	if err != nil {
 		return "", err
 	}
-	return value.(string), nil
+	if s, ok := toString(value); ok {
+		return s, nil
+	}
+	return "", ErrNotScalar
 }
 
 // CB 获取配置，结果转为 bool
@@ -6,6 +6,7 @@ import (
 
 func TestConfigJSON(t *testing.T) {
 	c := NewConfig()
+	SetDefault(c)
 	err := c.FileJSON("./json_test.json")
 	if err != nil {
 		t.Errorf("TestConfigJSON failed, err: %s", err.Error())
@@ -32,6 +33,7 @@ func TestConfigJSON(t *testing.T) {
 
 func TestConfigTOML(t *testing.T) {
 	c := NewConfig()
+	SetDefault(c)
 	err := c.FileTOML("./toml_test.toml")
 	if err != nil {
 		t.Errorf("TestConfigTOML failed, err: %s", err.Error())
@@ -65,6 +67,7 @@ func TestConfigTOML(t *testing.T) {
 
 func TestConfigYAML(t *testing.T) {
 	c := NewConfig()
+	SetDefault(c)
 	err := c.FileYAML("./yaml_test.yaml")
 	if err != nil {
 		t.Errorf("TestConfigYAML failed, err: %s", err.Error())
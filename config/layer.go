@@ -0,0 +1,452 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// eg:
+// c := config.NewConfig()
+// c.FileYAML("./app.yaml")
+// c.FileEnv("./.env", "APP_")
+//
+// APP_DATABASE_HOST=10.0.0.1 覆盖 app.yaml 中的 database.host
+// c.LoadEnv("APP_", config.EnvBind("DATABASE_URL", "database.url"))
+//
+// flag.String("database.host", "127.0.0.1", "database host")
+// flag.Parse()
+// c.LoadFlags(nil)
+//
+// host := config.D("database.host", "localhost")
+//
+// 优先级从低到高，优先级相同时后加载的覆盖先加载的:
+// 1. 命令行参数的默认值
+// 2. 配置文件以及 LoadJSON 等从 bytes 中读取的数据
+// 3. .env 文件
+// 4. 环境变量
+// 5. 命令行中指定的参数
+// 6. Set 设置的值
+//
+// 对象会逐层合并，例如环境变量 APP_DATABASE_HOST 只覆盖 database.host，不影响 database 中的其它 key
+
+const (
+	layerFlagDefault = iota
+	layerFile
+	layerDotEnv
+	layerEnv
+	layerFlag
+	layerOverride
+)
+
+// envConfig 环境变量的参数
+type envConfig struct {
+	prefix  string
+	mapping func(name string) string
+	binds   map[string]string
+}
+
+// EnvOption ..
+type EnvOption func(*envConfig)
+
+// EnvMapping 自定义环境变量名称到 key 的转换，name 不包括前缀，返回空字符串时忽略该变量
+// 默认转为小写，并将 _ 替换为 .，例如 DATABASE_HOST 转为 database.host
+func EnvMapping(fn func(name string) string) EnvOption {
+	return func(c *envConfig) {
+		c.mapping = fn
+	}
+}
+
+// EnvBind 将环境变量 name 绑定到 key，name 为完整的名称，不需要包含前缀
+func EnvBind(name, key string) EnvOption {
+	return func(c *envConfig) {
+		c.binds[name] = key
+	}
+}
+
+func newEnvConfig(prefix string, opts []EnvOption) *envConfig {
+	conf := &envConfig{
+		prefix: prefix,
+		mapping: func(name string) string {
+			return strings.ToLower(strings.Replace(name, "_", ".", -1))
+		},
+		binds: make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return conf
+}
+
+// key 环境变量对应的 key，不需要读取时返回空字符串
+func (c *envConfig) key(name string) string {
+	if key, exist := c.binds[name]; exist {
+		return key
+	}
+	if !strings.HasPrefix(strings.ToUpper(name), strings.ToUpper(c.prefix)) {
+		return ""
+	}
+	name = name[len(c.prefix):]
+	if name == "" {
+		return ""
+	}
+	return c.mapping(name)
+}
+
+// values 将环境变量转为配置数据
+func (c *envConfig) values(env map[string]string) map[string]interface{} {
+	// 按名称排序，使多个变量映射到同一个 key 时结果固定
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	data := make(map[string]interface{})
+	for _, name := range names {
+		if key := c.key(name); key != "" {
+			setPath(data, key, env[name])
+		}
+	}
+	return data
+}
+
+// LoadEnv 读取以 prefix 开头的环境变量，重新加载时会再次读取
+func (c *config) LoadEnv(prefix string, opts ...EnvOption) error {
+	conf := newEnvConfig(prefix, opts)
+	return c.load(&source{
+		layer: layerEnv,
+		name:  "<env>",
+		read: func() (map[string]interface{}, error) {
+			env := make(map[string]string)
+			for _, kv := range os.Environ() {
+				if i := strings.Index(kv, "="); i > 0 {
+					env[kv[:i]] = kv[i+1:]
+				}
+			}
+			return conf.values(env), nil
+		},
+	})
+}
+
+// FileEnv 读取 .env 文件，规则与 LoadEnv 相同，不会修改进程的环境变量
+func (c *config) FileEnv(path, prefix string, opts ...EnvOption) error {
+	conf := newEnvConfig(prefix, opts)
+	return c.load(&source{
+		layer: layerDotEnv,
+		name:  path,
+		path:  path,
+		read: func() (map[string]interface{}, error) {
+			content, err := loadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			env, err := parseDotEnv(path, content)
+			if err != nil {
+				return nil, err
+			}
+			return conf.values(env), nil
+		},
+	})
+}
+
+// parseDotEnv 解析 .env 文件
+// 支持 # 注释、export 前缀、单引号以及双引号，双引号中可以使用 \n 等转义字符
+func parseDotEnv(path string, content []byte) (map[string]string, error) {
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+
+		i := strings.Index(text, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("%s:%d: invalid line", path, line)
+		}
+		name := strings.TrimSpace(text[:i])
+		value := strings.TrimSpace(text[i+1:])
+
+		switch {
+		case strings.HasPrefix(value, `"`):
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid quoted value", path, line)
+			}
+			value = unquoted
+		case strings.HasPrefix(value, "'"):
+			if len(value) < 2 || !strings.HasSuffix(value, "'") {
+				return nil, fmt.Errorf("%s:%d: invalid quoted value", path, line)
+			}
+			value = value[1 : len(value)-1]
+		default:
+			if j := strings.Index(value, " #"); j >= 0 {
+				value = strings.TrimSpace(value[:j])
+			}
+		}
+		env[name] = value
+	}
+	return env, scanner.Err()
+}
+
+// LoadFlags 读取命令行参数，参数名称即为 key，例如 -database.host
+// 命令行中指定的参数优先级高于配置文件以及环境变量，没有指定的参数使用默认值，优先级最低
+// fs 为 nil 时使用 flag.CommandLine，需要在 Parse 之后调用
+func (c *config) LoadFlags(fs *flag.FlagSet) error {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	if !fs.Parsed() {
+		return errors.New("Flags must be parsed before loading")
+	}
+
+	values := func(visit func(func(*flag.Flag)), skip map[string]bool) map[string]interface{} {
+		data := make(map[string]interface{})
+		visit(func(f *flag.Flag) {
+			if skip[f.Name] {
+				return
+			}
+			var value interface{} = f.Value.String()
+			if getter, ok := f.Value.(flag.Getter); ok {
+				value = getter.Get()
+			}
+			setPath(data, f.Name, value)
+		})
+		return data
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if err := c.load(&source{
+		layer: layerFlagDefault,
+		name:  "<flag defaults>",
+		read: func() (map[string]interface{}, error) {
+			return values(fs.VisitAll, set), nil
+		},
+	}); err != nil {
+		return err
+	}
+	return c.load(&source{
+		layer: layerFlag,
+		name:  "<flags>",
+		read: func() (map[string]interface{}, error) {
+			return values(fs.Visit, nil), nil
+		},
+	})
+}
+
+// Set 设置 key 的值，优先级最高，重新加载时保留
+func (c *config) Set(key string, value interface{}) {
+	c.mu.Lock()
+	if c.overrides == nil {
+		c.overrides = &source{layer: layerOverride, name: "<set>", data: make(map[string]interface{})}
+		overrides := c.overrides
+		overrides.read = func() (map[string]interface{}, error) {
+			return overrides.data, nil
+		}
+		c.sources = append(c.sources, overrides)
+	}
+	setPath(c.overrides.data, key, value)
+	keys := c.swap(mergeSources(c.sources))
+	atomic.StoreInt32(&c.init, 1)
+	callbacks := c.callbacks
+	c.mu.Unlock()
+
+	notify(callbacks, keys)
+}
+
+// mergeSources 按照优先级合并所有数据来源
+func mergeSources(sources []*source) map[string]interface{} {
+	data := make(map[string]interface{})
+	for _, src := range sortSources(sources) {
+		mergeMap(data, src.data)
+	}
+	return data
+}
+
+// sortSources 按照优先级从低到高排序，优先级相同时保持加载顺序
+func sortSources(sources []*source) []*source {
+	sorted := make([]*source, len(sources))
+	copy(sorted, sources)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].layer < sorted[j].layer
+	})
+	return sorted
+}
+
+// origin 最终生效的 key 的值来自哪个数据来源，key 不存在时返回空字符串
+func (c *config) origin(key string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sorted := sortSources(c.sources)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if _, exist := lookup(sorted[i].data, key); exist {
+			return sorted[i].name
+		}
+	}
+	return ""
+}
+
+// mergeMap 将 src 合并到 dst，两边都是对象时逐层合并
+// dst 为数组，src 为使用下标作为 key 的对象时 (例如环境变量 APP_SERVERS_0_ADDR)，只修改对应的元素
+// 不修改 src 以及 dst 中已有的对象以及数组，src 中的值会复制，避免之后修改 src (例如 Set) 时影响合并的结果
+func mergeMap(dst, src map[string]interface{}) {
+	for key, value := range src {
+		dst[key] = mergeValue(dst[key], value)
+	}
+}
+
+// mergeValue 将 value 合并到 old，返回合并之后的值
+func mergeValue(old, value interface{}) interface{} {
+	m, ok := toStringMap(value)
+	if !ok {
+		return cloneValue(value)
+	}
+	if merged, ok := mergeIndex(old, m); ok {
+		return merged
+	}
+	oldMap, ok := toStringMap(old)
+	if !ok {
+		return cloneValue(value)
+	}
+	merged := make(map[string]interface{}, len(oldMap)+len(m))
+	for k, v := range oldMap {
+		merged[k] = v
+	}
+	mergeMap(merged, m)
+	return merged
+}
+
+// mergeIndex old 为数组，m 的 key 都是下标时，复制数组并合并对应的元素
+func mergeIndex(old interface{}, m map[string]interface{}) (interface{}, bool) {
+	if !isSlice(old) || len(m) == 0 {
+		return nil, false
+	}
+	indexes := make(map[int]interface{}, len(m))
+	for key, value := range m {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 {
+			return nil, false
+		}
+		indexes[index] = value
+	}
+
+	result := cloneValue(old)
+	for index, value := range indexes {
+		result = setIndex(result, index, mergeValue(sliceIndex(result, index), value))
+	}
+	return result, true
+}
+
+// isSlice 是否为数组，包括 toml 中的 []map[string]interface{}
+func isSlice(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	kind := reflect.ValueOf(v).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+// sliceIndex 数组中下标对应的值，超出范围时为 nil
+func sliceIndex(v interface{}, index int) interface{} {
+	rv := reflect.ValueOf(v)
+	if index >= rv.Len() {
+		return nil
+	}
+	return rv.Index(index).Interface()
+}
+
+// setIndex 设置数组中下标对应的值，直接修改 v，超出范围时使用 nil 补齐
+// 值的类型与数组元素的类型不一致时转为 []interface{}
+func setIndex(v interface{}, index int, value interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	ev := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice || index >= rv.Len() || !ev.IsValid() || !ev.Type().AssignableTo(rv.Type().Elem()) {
+		n := rv.Len()
+		if index >= n {
+			n = index + 1
+		}
+		result := make([]interface{}, n)
+		for i := 0; i < rv.Len(); i++ {
+			result[i] = rv.Index(i).Interface()
+		}
+		result[index] = value
+		return result
+	}
+	rv.Index(index).Set(ev)
+	return v
+}
+
+// cloneValue 复制对象以及数组，保持原来的类型，其它类型的值直接返回
+func cloneValue(value interface{}) interface{} {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			result[k] = cloneValue(v)
+		}
+		return result
+	case map[interface{}]interface{}:
+		result := make(map[interface{}]interface{}, len(m))
+		for k, v := range m {
+			result[k] = cloneValue(v)
+		}
+		return result
+	case []byte:
+		return value
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice || rv.IsNil() {
+		return value
+	}
+	result := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		if elem := rv.Index(i).Interface(); elem != nil {
+			result.Index(i).Set(reflect.ValueOf(cloneValue(elem)))
+		}
+	}
+	return result.Interface()
+}
+
+// setPath 按照路径设置值，中间的对象不存在时创建，路径中的下标修改已有数组中对应的元素
+func setPath(data map[string]interface{}, path string, value interface{}) {
+	parts := strings.Split(path, ".")
+	data[parts[0]] = setChild(data[parts[0]], parts[1:], value)
+}
+
+// setChild 在 current 中按照路径设置值，返回修改之后的 current
+func setChild(current interface{}, parts []string, value interface{}) interface{} {
+	if len(parts) == 0 {
+		return value
+	}
+	key := parts[0]
+	if isSlice(current) {
+		if index, err := strconv.Atoi(key); err == nil && index >= 0 {
+			current = cloneValue(current)
+			return setIndex(current, index, setChild(sliceIndex(current, index), parts[1:], value))
+		}
+	}
+
+	m, ok := current.(map[string]interface{})
+	if !ok {
+		if m, _ = toStringMap(current); m == nil {
+			m = make(map[string]interface{})
+		}
+	}
+	m[key] = setChild(m[key], parts[1:], value)
+	return m
+}
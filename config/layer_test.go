@@ -0,0 +1,177 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestLayer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dotEnv := filepath.Join(dir, ".env")
+	content := "# comment\nexport TEST_DATABASE_USER=dotenv\nTEST_DATABASE_PASSWORD=\"p@ss\\nword\"\nTEST_NAME='from env file' \nTEST_PORT=7070 # port\n"
+	if err := ioutil.WriteFile(dotEnv, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("TEST_DATABASE_HOST", "10.0.0.1")
+	os.Setenv("TEST_PORT", "9090")
+	os.Setenv("DATABASE_URL", "mysql://db")
+	defer os.Unsetenv("TEST_DATABASE_HOST")
+	defer os.Unsetenv("TEST_PORT")
+	defer os.Unsetenv("DATABASE_URL")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("mode", "dev", "")
+	fs.Int("port", 1, "")
+	fs.String("database.user", "flag-default", "")
+	fs.Bool("debug", false, "")
+	if err := fs.Parse([]string{"-mode", "prod"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newConfig()
+	// 命令行参数先加载，但是默认值的优先级最低
+	if err := c.LoadFlags(fs); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadEnv("TEST_", EnvBind("DATABASE_URL", "database.url")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadYAML([]byte("mode: test\nport: 8080\ndatabase:\n  host: 127.0.0.1\n  user: root\n  port: 3306\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.FileEnv(dotEnv, "TEST_"); err != nil {
+		t.Fatal(err)
+	}
+	SetDefault(c)
+
+	expects := map[string]string{
+		"mode":              "prod",
+		"port":              "9090",
+		"debug":             "false",
+		"name":              "from env file",
+		"database.host":     "10.0.0.1",
+		"database.user":     "dotenv",
+		"database.password": "p@ss\nword",
+		"database.port":     "3306",
+		"database.url":      "mysql://db",
+	}
+	for key, expect := range expects {
+		if value, err := C(key); err != nil || value != expect {
+			t.Errorf("%s: %q, err: %v", key, value, err)
+		}
+	}
+
+	c.Set("database.host", "override")
+	if host := D("database.host", ""); host != "override" {
+		t.Fatalf("override: %s", host)
+	}
+	if port := DI("database.port", 0); port != 3306 {
+		t.Fatalf("database.port after Set: %d", port)
+	}
+
+	// 重新加载时再次读取环境变量，Set 的值保留
+	os.Setenv("TEST_PORT", "9091")
+	if err := c.reload(nil); err != nil {
+		t.Fatal(err)
+	}
+	if port := DI("port", 0); port != 9091 {
+		t.Fatalf("port after reload: %d", port)
+	}
+	if host := D("database.host", ""); host != "override" {
+		t.Fatalf("override after reload: %s", host)
+	}
+
+	if err := c.LoadFlags(flag.NewFlagSet("unparsed", flag.ContinueOnError)); err == nil {
+		t.Fatal("LoadFlags before Parse should fail")
+	}
+	if _, err := parseDotEnv("bad.env", []byte("NAME\n")); err == nil || err.Error() != "bad.env:1: invalid line" {
+		t.Fatalf("parseDotEnv, err: %v", err)
+	}
+}
+
+func TestSetNested(t *testing.T) {
+	c := newConfig()
+	var (
+		mu      sync.Mutex
+		changed [][]string
+	)
+	c.OnChange(func(keys []string) {
+		mu.Lock()
+		changed = append(changed, keys)
+		mu.Unlock()
+	})
+
+	// 读取的同时修改，Set 不能修改已经发布的数据
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			c.Any("a.c")
+		}
+	}()
+	c.Set("a.b", 1)
+	c.Set("a.c", 2)
+	c.Set("a.c", 3)
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	expects := [][]string{{"a"}, {"a.c"}, {"a.c"}}
+	if !reflect.DeepEqual(changed, expects) {
+		t.Fatalf("changed: %v", changed)
+	}
+	if value, err := c.Any("a.c"); err != nil || value != 3 {
+		t.Fatalf("a.c: %v, err: %v", value, err)
+	}
+}
+
+func TestSetIndex(t *testing.T) {
+	os.Setenv("INDEX_SERVERS_1_ADDR", "env")
+	defer os.Unsetenv("INDEX_SERVERS_1_ADDR")
+
+	c := newConfig()
+	if err := c.LoadYAML([]byte("servers:\n  - addr: a\n    port: 80\n  - addr: b\n  - addr: c\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadEnv("INDEX_"); err != nil {
+		t.Fatal(err)
+	}
+	c.Set("servers.0.addr", "set")
+	c.Set("servers.2", map[string]interface{}{"addr": "replaced"})
+
+	// 只修改对应的元素，其它元素以及字段保留
+	expects := map[string]interface{}{
+		"servers.0.addr": "set",
+		"servers.0.port": 80,
+		"servers.1.addr": "env",
+		"servers.2.addr": "replaced",
+	}
+	for key, expect := range expects {
+		if value, err := c.Any(key); err != nil || value != expect {
+			t.Errorf("%s: %v, err: %v", key, value, err)
+		}
+	}
+	if servers, err := c.Any("servers"); err != nil || reflect.ValueOf(servers).Len() != 3 {
+		t.Fatalf("servers: %v, err: %v", servers, err)
+	}
+
+	// 合并的结果不与数据来源共享数组
+	servers, _ := c.Any("servers")
+	c.Set("servers.1.addr", "again")
+	if value, _ := child(reflect.ValueOf(servers).Index(1).Interface(), "addr"); value != "env" {
+		t.Fatalf("published servers changed: %v", value)
+	}
+	if value, _ := c.Any("servers.1.addr"); value != "again" {
+		t.Fatalf("servers.1.addr after Set: %v", value)
+	}
+}
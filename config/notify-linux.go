@@ -0,0 +1,72 @@
+//go:build linux
+// +build linux
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// inotifyMask 文件被修改、创建、删除以及移动，编辑器和 kubernetes 通常通过重命名替换文件
+const inotifyMask = syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB |
+	syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// inotify 监听文件所在的目录，目录中任意文件发生变化都会通知，由 reload 比较数据是否变化
+type inotify struct {
+	file   *os.File
+	events chan struct{}
+}
+
+func newNotifier(paths []string) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	// 非阻塞的 fd 由 runtime 的 poller 管理，Close 可以唤醒阻塞的 Read
+	file := os.NewFile(uintptr(fd), "inotify")
+
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		if _, err := syscall.InotifyAddWatch(fd, dir, inotifyMask); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	n := &inotify{
+		file:   file,
+		events: make(chan struct{}, 1),
+	}
+	go n.run()
+	return n, nil
+}
+
+func (n *inotify) run() {
+	buf := make([]byte, 4096)
+	for {
+		if _, err := n.file.Read(buf); err != nil {
+			return
+		}
+		select {
+		case n.events <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Events ..
+func (n *inotify) Events() <-chan struct{} {
+	return n.events
+}
+
+// Close ..
+func (n *inotify) Close() error {
+	return n.file.Close()
+}
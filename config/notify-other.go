@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package config
+
+import "errors"
+
+// newNotifier 只支持 linux，其它系统使用定时检查
+func newNotifier(paths []string) (notifier, error) {
+	return nil, errors.New("inotify is not supported")
+}
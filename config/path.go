@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// eg:
+// database:
+//   host: 127.0.0.1
+// servers:
+//   - addr: 10.0.0.1:80
+//   - addr: 10.0.0.2:80
+//
+// 使用 . 连接嵌套的 key，数组使用下标
+// host, _ := config.C("database.host")
+// addr, _ := config.C("servers.1.addr")
+
+// lookup 按照路径查找数据，路径为空时返回 data
+// 顶层的 key 本身包含 . 时优先匹配，兼容之前的用法
+func lookup(data map[string]interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return data, true
+	}
+	if value, exist := data[path]; exist {
+		return value, true
+	}
+
+	var current interface{} = data
+	for _, part := range strings.Split(path, ".") {
+		value, exist := child(current, part)
+		if !exist {
+			return nil, false
+		}
+		current = value
+	}
+	return current, true
+}
+
+// child 获取对象中 key 对应的值，或者数组中下标对应的值
+func child(v interface{}, key string) (interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		value, exist := m[key]
+		return value, exist
+	case map[interface{}]interface{}:
+		value, exist := m[key]
+		if !exist {
+			// yaml 中的数字 key
+			if i, err := strconv.Atoi(key); err == nil {
+				value, exist = m[i]
+			}
+		}
+		return value, exist
+	}
+
+	// 数组，包括 toml 中的 []map[string]interface{}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	index, err := strconv.Atoi(key)
+	if err != nil || index < 0 || index >= rv.Len() {
+		return nil, false
+	}
+	return rv.Index(index).Interface(), true
+}
+
+// toString 将标量转为字符串，对象以及数组返回 false
+func toString(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case []byte:
+		return string(s), true
+	case bool:
+		return strconv.FormatBool(s), true
+	case float32:
+		return strconv.FormatFloat(float64(s), 'f', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64), true
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(s), true
+	case fmt.Stringer:
+		return s.String(), true
+	}
+	return "", false
+}
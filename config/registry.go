@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// eg:
+// 应用的配置，第一个 NewConfig 创建的实例为默认实例
+// c := config.NewConfig()
+// c.FileYAML("./app.yaml")
+// port := config.DI("port", 8080)
+//
+// 库使用命名的实例，不会替换默认实例，相同名称返回同一个实例
+// flags := config.Named("feature-flags")
+// flags.FileJSON("./flags.json")
+//
+// 在其它地方获取
+// if flags, exist := config.Lookup("feature-flags"); exist {
+// 	enabled, _ := flags.Any("new-ui")
+// }
+//
+// 明确指定默认实例
+// config.SetDefault(c)
+
+var (
+	// ErrExist 名称已经被注册
+	ErrExist = errors.New("Configuration name already registered")
+)
+
+var (
+	// registryMu 保护 defaultConfig 以及 registry
+	registryMu    sync.RWMutex
+	defaultConfig Config
+	registry      = make(map[string]Config)
+)
+
+// Default 返回默认实例，没有时返回 nil
+func Default() Config {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return defaultConfig
+}
+
+// SetDefault 设置默认实例，config.C, config.D 等辅助函数读取该实例，c 为 nil 时清除默认实例
+func SetDefault(c Config) {
+	registryMu.Lock()
+	defaultConfig = c
+	registryMu.Unlock()
+}
+
+// setDefaultIfNil 没有默认实例时设置为 c
+func setDefaultIfNil(c Config) {
+	registryMu.Lock()
+	if defaultConfig == nil {
+		defaultConfig = c
+	}
+	registryMu.Unlock()
+}
+
+// Named 返回名称为 name 的实例，不存在时创建并注册，不会影响默认实例
+func Named(name string) Config {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	c, exist := registry[name]
+	if !exist {
+		c = newConfig()
+		registry[name] = c
+	}
+	return c
+}
+
+// Register 将 c 注册为 name，名称已经被注册时返回 ErrExist
+func Register(name string, c Config) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exist := registry[name]; exist {
+		return ErrExist
+	}
+	registry[name] = c
+	return nil
+}
+
+// Lookup 返回名称为 name 的实例
+func Lookup(name string) (Config, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	c, exist := registry[name]
+	return c, exist
+}
+
+// Unregister 取消注册，不影响默认实例
+func Unregister(name string) {
+	registryMu.Lock()
+	delete(registry, name)
+	registryMu.Unlock()
+}
+
+// Names 所有注册的名称，已排序
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	app := NewConfig()
+	app.LoadJSON([]byte(`{"name": "app"}`))
+	SetDefault(app)
+
+	// 之后创建的实例不会替换默认实例
+	other := NewConfig()
+	other.LoadJSON([]byte(`{"name": "other"}`))
+	if name, _ := C("name"); name != "app" {
+		t.Fatalf("NewConfig replaced default, name: %s", name)
+	}
+
+	flags := Named("feature-flags")
+	flags.LoadJSON([]byte(`{"new-ui": true}`))
+	if Named("feature-flags") != flags {
+		t.Fatal("Named should return the same instance")
+	}
+	if c, exist := Lookup("feature-flags"); !exist || c != flags {
+		t.Fatal("Lookup feature-flags failed")
+	}
+	if _, err := C("new-ui"); err != ErrNotExist {
+		t.Fatalf("Named instance leaked into default, err: %v", err)
+	}
+	if Default() != app {
+		t.Fatal("Named replaced default")
+	}
+
+	if err := Register("other", other); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register("other", app); err != ErrExist {
+		t.Fatalf("Register twice, err: %v", err)
+	}
+	if names := Names(); fmt.Sprint(names) != "[feature-flags other]" {
+		t.Fatalf("Names: %v", names)
+	}
+	Unregister("other")
+	Unregister("feature-flags")
+	if _, exist := Lookup("other"); exist {
+		t.Fatal("Unregister failed")
+	}
+
+	SetDefault(other)
+	if name, _ := C("name"); name != "other" {
+		t.Fatalf("SetDefault, name: %s", name)
+	}
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	c := NewConfig()
+	c.LoadJSON([]byte(`{"count": 0}`))
+	SetDefault(c)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				switch i % 4 {
+				case 0:
+					c.Set("count", j)
+				case 1:
+					DI("count", 0)
+				case 2:
+					Named(fmt.Sprintf("lib-%d", j%3)).Any("count")
+				case 3:
+					SetDefault(c)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for j := 0; j < 3; j++ {
+		Unregister(fmt.Sprintf("lib-%d", j))
+	}
+}
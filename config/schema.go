@@ -0,0 +1,462 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eg:
+// s := config.NewSchema()
+// s.Key("server.port").Required().Type(config.TypeInt).Range(1, 65535)
+// s.Key("mode").Enum("dev", "test", "prod")
+// s.Key("servers.*.addr").Required().Pattern(`^[\w.-]+:\d+$`)
+//
+// 启动时校验，返回所有不符合的配置
+// if err := s.Validate(c); err != nil {
+// 	log.Fatal(err)
+// }
+//
+// 也可以使用结构体的 validate 标签声明，key 与 Unmarshal 的规则相同
+// type Server struct {
+// 	Port int    `config:"port" validate:"required,min=1,max=65535"`
+// 	Mode string `config:"mode" validate:"enum=dev|test|prod"`
+// 	Name string `config:"name" validate:"pattern=^[a-z]+$"`
+// }
+// s := config.SchemaOf("server", Server{})
+//
+// 重新加载时校验
+// c.Watch(config.WatchValidate(s.Validate))
+
+// 配置的类型，字符串可以转换为对应类型时也认为符合，例如环境变量中的数字
+const (
+	TypeString   = "string"
+	TypeInt      = "int"
+	TypeFloat    = "float"
+	TypeBool     = "bool"
+	TypeDuration = "duration"
+	TypeMap      = "map"
+	TypeSlice    = "slice"
+)
+
+// Schema 配置的约束
+type Schema struct {
+	rules []*Rule
+}
+
+// NewSchema ..
+func NewSchema() *Schema {
+	return &Schema{}
+}
+
+// Rule 一个 key 的约束，key 中的 * 匹配对象中的所有 key 或者数组中的所有元素
+type Rule struct {
+	key string
+	// fold 与 Unmarshal 相同，key 不存在时不区分大小写匹配，用于 SchemaOf
+	fold     bool
+	required bool
+	typ      string
+	min, max *float64
+	enum     []string
+	pattern  *regexp.Regexp
+}
+
+// Key 添加 key 的约束
+func (s *Schema) Key(key string) *Rule {
+	r := &Rule{key: key}
+	s.rules = append(s.rules, r)
+	return r
+}
+
+// Required key 必须存在
+func (r *Rule) Required() *Rule {
+	r.required = true
+	return r
+}
+
+// Type 值的类型，TypeString, TypeInt 等
+func (r *Rule) Type(typ string) *Rule {
+	r.typ = typ
+	return r
+}
+
+// Min 最小值，字符串、数组以及对象为最小长度
+func (r *Rule) Min(min float64) *Rule {
+	r.min = &min
+	return r
+}
+
+// Max 最大值，字符串、数组以及对象为最大长度
+func (r *Rule) Max(max float64) *Rule {
+	r.max = &max
+	return r
+}
+
+// Range 相当于 Min(min).Max(max)
+func (r *Rule) Range(min, max float64) *Rule {
+	return r.Min(min).Max(max)
+}
+
+// Enum 值必须是 values 之一
+func (r *Rule) Enum(values ...string) *Rule {
+	r.enum = values
+	return r
+}
+
+// Pattern 值必须匹配正则表达式，expr 无效时 panic
+func (r *Rule) Pattern(expr string) *Rule {
+	r.pattern = regexp.MustCompile(expr)
+	return r
+}
+
+// Violation 不符合约束的配置
+type Violation struct {
+	Key string
+	// Source 提供该值的数据来源，文件为文件路径，key 不存在时为空
+	Source  string
+	Message string
+}
+
+// String ..
+func (v Violation) String() string {
+	if v.Source == "" {
+		return fmt.Sprintf("%s: %s", v.Key, v.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", v.Source, v.Key, v.Message)
+}
+
+// ValidationError 所有不符合约束的配置
+type ValidationError []Violation
+
+// Error ..
+func (e ValidationError) Error() string {
+	lines := make([]string, len(e))
+	for i, v := range e {
+		lines[i] = v.String()
+	}
+	return fmt.Sprintf("Configuration is invalid, %d violations:\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+// Validate 校验配置，有不符合的配置时返回 ValidationError
+func (s *Schema) Validate(c Config) error {
+	var violations ValidationError
+	for _, r := range s.rules {
+		violations = append(violations, r.validate(c)...)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return violations
+}
+
+func (r *Rule) validate(c Config) []Violation {
+	var violations []Violation
+	add := func(key, format string, v ...interface{}) {
+		violation := Violation{Key: key, Message: fmt.Sprintf(format, v...)}
+		if conf, ok := c.(*config); ok {
+			violation.Source = conf.origin(key)
+		}
+		violations = append(violations, violation)
+	}
+
+	// 展开 * 时不需要解密
+	var root interface{}
+	if conf, ok := c.(*config); ok {
+		root = conf.values()
+	} else {
+		root, _ = c.Any("")
+	}
+	for _, key := range expandKey(root, r.key, r.fold) {
+		value, err := c.Any(key)
+		if err == ErrNotExist {
+			if r.required {
+				add(key, "is required")
+			}
+			continue
+		}
+		if err != nil {
+			add(key, "%s", err.Error())
+			continue
+		}
+
+		if r.typ != "" && !checkType(value, r.typ) {
+			add(key, "must be %s, got %v", r.typ, value)
+			continue
+		}
+		if r.min != nil || r.max != nil {
+			if n, ok := measure(value, r.typ); !ok {
+				add(key, "must be a number, got %v", value)
+			} else if r.min != nil && n < *r.min {
+				add(key, "must be >= %v, got %v", *r.min, value)
+			} else if r.max != nil && n > *r.max {
+				add(key, "must be <= %v, got %v", *r.max, value)
+			}
+		}
+		if len(r.enum) > 0 {
+			s, _ := toString(value)
+			found := false
+			for _, e := range r.enum {
+				if s == e {
+					found = true
+					break
+				}
+			}
+			if !found {
+				add(key, "must be one of [%s], got %v", strings.Join(r.enum, ", "), value)
+			}
+		}
+		if r.pattern != nil {
+			if s, ok := toString(value); !ok || !r.pattern.MatchString(s) {
+				add(key, "must match %s, got %v", r.pattern, value)
+			}
+		}
+	}
+	return violations
+}
+
+// expandKey 将 key 中的 * 展开为实际存在的 key，* 对应的数据不存在时返回空
+// fold 为 true 时，与 Unmarshal 相同，key 不存在时使用不区分大小写匹配的 key
+func expandKey(root interface{}, key string, fold bool) []string {
+	if !fold && !strings.Contains(key, "*") {
+		return []string{key}
+	}
+
+	paths := []string{""}
+	values := []interface{}{root}
+	for _, part := range strings.Split(key, ".") {
+		var nextPaths []string
+		var nextValues []interface{}
+		for i, value := range values {
+			var children []string
+			if part != "*" {
+				name := part
+				if fold {
+					name = foldKey(value, part)
+				}
+				children = []string{name}
+			} else if m, ok := toStringMap(value); ok {
+				for child := range m {
+					children = append(children, child)
+				}
+				sort.Strings(children)
+			} else if rv := reflect.ValueOf(value); value != nil && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) {
+				for j := 0; j < rv.Len(); j++ {
+					children = append(children, strconv.Itoa(j))
+				}
+			}
+
+			for _, name := range children {
+				v, _ := child(value, name)
+				nextPaths = append(nextPaths, joinPath(paths[i], name))
+				nextValues = append(nextValues, v)
+			}
+		}
+		paths, values = nextPaths, nextValues
+	}
+	return paths
+}
+
+// foldKey value 中与 name 对应的 key，与 Unmarshal 相同，先精确匹配，再不区分大小写匹配
+// 都不存在时返回 name
+func foldKey(value interface{}, name string) string {
+	m, ok := toStringMap(value)
+	if !ok {
+		return name
+	}
+	if _, exist := m[name]; exist {
+		return name
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return name
+}
+
+// checkType 值是否可以转为 typ
+func checkType(value interface{}, typ string) bool {
+	switch typ {
+	case TypeString:
+		_, ok := toString(value)
+		return ok
+	case TypeInt:
+		_, ok := toInt(value)
+		return ok
+	case TypeFloat:
+		_, ok := toFloat(value)
+		return ok
+	case TypeBool:
+		_, ok := toBool(value)
+		return ok
+	case TypeDuration:
+		if s, ok := value.(string); ok {
+			_, err := time.ParseDuration(s)
+			return err == nil
+		}
+		_, ok := toInt(value)
+		return ok
+	case TypeMap:
+		_, ok := toStringMap(value)
+		return ok
+	case TypeSlice:
+		kind := reflect.ValueOf(value).Kind()
+		return kind == reflect.Slice || kind == reflect.Array
+	}
+	return false
+}
+
+// measure 用于 Min, Max 比较的值，数字为本身，字符串、数组以及对象为长度
+// typ 为 TypeString 时总是使用字符串的长度，例如密码 "9"
+func measure(value interface{}, typ string) (float64, bool) {
+	if typ == TypeString {
+		s, ok := toString(value)
+		return float64(len(s)), ok
+	}
+	switch v := value.(type) {
+	case string:
+		// 环境变量等来源的数字是字符串
+		if typ == "" || typ == TypeInt || typ == TypeFloat {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f, true
+			}
+		}
+		return float64(len(v)), true
+	}
+	if f, ok := toFloat(value); ok {
+		return f, true
+	}
+	if m, ok := toStringMap(value); ok {
+		return float64(len(m)), true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		return float64(rv.Len()), true
+	}
+	return 0, false
+}
+
+// SchemaOf 根据结构体 v 的 validate 标签生成约束，prefix 为结构体对应的 key，与 Unmarshal 的 path 相同
+// validate 标签使用 , 分隔: required, min=1, max=10, enum=a|b|c, pattern=正则表达式
+// pattern 必须放在最后，之后的内容都属于正则表达式
+// 没有指定类型时根据字段的类型检查，嵌套的结构体以及结构体数组会递归处理
+func SchemaOf(prefix string, v interface{}) *Schema {
+	s := NewSchema()
+	schemaOf(s, prefix, reflect.TypeOf(v))
+	return s
+}
+
+func schemaOf(s *Schema, prefix string, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get("config")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		key := joinPath(prefix, name)
+
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Time{}):
+			schemaOf(s, key, ft)
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct:
+			schemaOf(s, key+".*", ft.Elem())
+		}
+
+		tag, ok := field.Tag.Lookup("validate")
+		if !ok {
+			continue
+		}
+		r := s.Key(key).Type(typeOf(ft))
+		r.fold = true
+		for tag != "" {
+			var item string
+			if strings.HasPrefix(tag, "pattern=") {
+				item, tag = tag, ""
+			} else if j := strings.Index(tag, ","); j >= 0 {
+				item, tag = tag[:j], tag[j+1:]
+			} else {
+				item, tag = tag, ""
+			}
+
+			item = strings.TrimSpace(item)
+			arg := ""
+			if j := strings.Index(item, "="); j >= 0 {
+				item, arg = item[:j], item[j+1:]
+			}
+			switch item {
+			case "required":
+				r.Required()
+			case "min":
+				r.Min(mustParseFloat(field.Name, arg))
+			case "max":
+				r.Max(mustParseFloat(field.Name, arg))
+			case "enum":
+				r.Enum(strings.Split(arg, "|")...)
+			case "pattern":
+				r.Pattern(arg)
+			default:
+				panic(fmt.Sprintf("config: invalid validate tag %q on field %s", item, field.Name))
+			}
+		}
+	}
+}
+
+// typeOf 字段类型对应的配置类型，不需要检查时返回空字符串
+func typeOf(t reflect.Type) string {
+	switch t {
+	case durationType:
+		return TypeDuration
+	case reflect.TypeOf(time.Time{}):
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return TypeString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeInt
+	case reflect.Float32, reflect.Float64:
+		return TypeFloat
+	case reflect.Bool:
+		return TypeBool
+	case reflect.Map, reflect.Struct:
+		return TypeMap
+	case reflect.Slice, reflect.Array:
+		return TypeSlice
+	}
+	return ""
+}
+
+func mustParseFloat(field, s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		panic(fmt.Sprintf("config: invalid validate number %q on field %s", s, field))
+	}
+	return f
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.yaml")
+	content := "mode: staging\nserver:\n  port: 70000\n  timeout: 5x\nservers:\n  - addr: a:80\n  - addr: b\n  - name: c\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newConfig()
+	if err := c.FileYAML(path); err != nil {
+		t.Fatal(err)
+	}
+	c.Set("name", "ab")
+
+	s := NewSchema()
+	s.Key("mode").Enum("dev", "prod")
+	s.Key("server.host").Required()
+	s.Key("server.port").Required().Type(TypeInt).Range(1, 65535)
+	s.Key("server.timeout").Type(TypeDuration)
+	s.Key("servers.*.addr").Required().Pattern(`^\w+:\d+$`)
+	s.Key("name").Min(3)
+	s.Key("optional").Type(TypeInt)
+
+	err = s.Validate(c)
+	violations, ok := err.(ValidationError)
+	if !ok {
+		t.Fatalf("Validate, err: %v", err)
+	}
+	expects := []Violation{
+		{"mode", path, "must be one of [dev, prod], got staging"},
+		{"server.host", "", "is required"},
+		{"server.port", path, "must be <= 65535, got 70000"},
+		{"server.timeout", path, "must be duration, got 5x"},
+		{"servers.1.addr", path, "must match ^\\w+:\\d+$, got b"},
+		{"servers.2.addr", "", "is required"},
+		{"name", "<set>", "must be >= 3, got ab"},
+	}
+	if !reflect.DeepEqual([]Violation(violations), expects) {
+		t.Fatalf("violations:\n%v", err)
+	}
+
+	c.Set("mode", "dev")
+	c.Set("server.host", "localhost")
+	c.Set("server.port", "8080")
+	c.Set("server.timeout", "5s")
+	c.Set("servers", []interface{}{map[string]interface{}{"addr": "a:80"}})
+	c.Set("name", "abc")
+	if err := s.Validate(c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	type server struct {
+		Addr string `config:"addr" validate:"required,pattern=^\\w+:\\d+$"`
+	}
+	type app struct {
+		Port int    `config:"port" validate:"required,min=1,max=65535"`
+		Mode string `config:"mode" validate:"enum=dev|prod"`
+		// Password 数字字符串也比较长度
+		Password string        `config:"password" validate:"required,min=8"`
+		Timeout  time.Duration `config:"timeout" validate:""`
+		Servers  []server      `config:"servers"`
+		Started  time.Time     `config:"started" validate:"required"`
+	}
+
+	c := newConfig()
+	c.LoadJSON([]byte(`{"app": {"port": "http", "mode": "dev", "password": "9", "timeout": 3, "servers": [{"addr": "a:80"}, {"addr": "a,80"}]}}`))
+	err := SchemaOf("app", app{}).Validate(c)
+	violations, ok := err.(ValidationError)
+	if !ok || len(violations) != 4 {
+		t.Fatalf("Validate, err: %v", err)
+	}
+	if violations[0].Key != "app.port" || violations[1].Key != "app.password" ||
+		violations[2].Key != "app.servers.1.addr" || violations[3].Key != "app.started" {
+		t.Fatalf("violations:\n%v", err)
+	}
+
+	s := NewSchema()
+	s.Key("app.password").Type(TypeString).Min(8)
+	s.Key("app.mode").Max(3)
+	err = s.Validate(c)
+	if violations, ok = err.(ValidationError); !ok || len(violations) != 1 || violations[0].Message != "must be >= 8, got 9" {
+		t.Fatalf("Type(TypeString).Min, err: %v", err)
+	}
+}
+
+func TestSchemaOfFold(t *testing.T) {
+	type server struct {
+		Addr string `validate:"required"`
+	}
+	// 没有 config 标签时与 Unmarshal 相同，不区分大小写
+	type app struct {
+		Port    int      `validate:"required,min=1"`
+		Name    string   `validate:"required"`
+		Servers []server `validate:"min=1"`
+	}
+
+	c := newConfig()
+	c.LoadJSON([]byte(`{"app": {"port": 8080, "servers": [{"addr": "a:80"}, {"ADDR": ""}, {}]}}`))
+	var v app
+	if err := c.Unmarshal("app", &v); err != nil || v.Port != 8080 || len(v.Servers) != 3 {
+		t.Fatalf("Unmarshal: %+v, err: %v", v, err)
+	}
+
+	err := SchemaOf("app", app{}).Validate(c)
+	violations, ok := err.(ValidationError)
+	if !ok || len(violations) != 2 || violations[0].Key != "app.Name" || violations[1].Key != "app.servers.2.Addr" {
+		t.Fatalf("violations:\n%v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alex-my/ghelper/crypto"
+)
+
+// eg:
+// 加密，通常使用命令行工具 cmd/ghelper
+// value, _ := config.Encrypt("my-secret", "123456")
+//
+// app.yaml:
+// mysql:
+//   password: ENC(c2FsdGVkLi4u...)
+//
+// 通过环境变量 CONFIG_SECRET_KEY 或者 CONFIG_SECRET_KEY_FILE 指定密钥，也可以使用 SetSecretKey
+// password, err := config.C("mysql.password")
+//
+// 读取时才解密，Any, C, Unmarshal 等返回解密之后的值
+// 加密使用 AES-256-CBC，密钥为 sha256(secret)，每个值使用随机的 iv，格式为 ENC(base64(iv + 密文))
+
+const (
+	// SecretKeyEnv 保存密钥的环境变量
+	SecretKeyEnv = "CONFIG_SECRET_KEY"
+	// SecretKeyFileEnv 保存密钥文件路径的环境变量，文件末尾的空白字符会被忽略
+	SecretKeyFileEnv = "CONFIG_SECRET_KEY_FILE"
+
+	encPrefix = "ENC("
+	encSuffix = ")"
+)
+
+var (
+	// ErrNoSecretKey 配置中有加密的值，但是没有指定密钥
+	ErrNoSecretKey = errors.New("Secret key is required to decrypt configuration")
+)
+
+// IsEncrypted 是否为 ENC(...) 格式的加密值
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix) && strings.HasSuffix(value, encSuffix)
+}
+
+// Encrypt 使用 secret 加密 plaintext，返回 ENC(...) 格式的值
+func Encrypt(secret, plaintext string) (string, error) {
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+	ciphertext, err := crypto.AesCBCEncode(secretKey(secret), iv, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return encPrefix + base64.StdEncoding.EncodeToString(append(iv, ciphertext...)) + encSuffix, nil
+}
+
+// Decrypt 使用 secret 解密 ENC(...) 格式的值
+func Decrypt(secret, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("Value is not encrypted")
+	}
+	data, err := base64.StdEncoding.DecodeString(value[len(encPrefix) : len(value)-len(encSuffix)])
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aes.BlockSize*2 {
+		return "", errors.New("Encrypted value is too short")
+	}
+	plaintext, err := crypto.AesCBCDecode(secretKey(secret), data[:aes.BlockSize], data[aes.BlockSize:])
+	if err != nil {
+		return "", fmt.Errorf("Decrypt failed, wrong secret key? err: %s", err.Error())
+	}
+	return string(plaintext), nil
+}
+
+// secretKey 任意长度的 secret 转为 32 字节的密钥
+func secretKey(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// SetSecretKey 设置解密使用的密钥，优先于环境变量
+func (c *config) SetSecretKey(secret string) {
+	c.mu.Lock()
+	c.secret = secret
+	c.mu.Unlock()
+}
+
+// resolveSecret 优先使用 SetSecretKey 设置的密钥
+func (c *config) resolveSecret() (string, error) {
+	c.mu.Lock()
+	secret := c.secret
+	c.mu.Unlock()
+	if secret != "" {
+		return secret, nil
+	}
+	return SecretKeyFromEnv()
+}
+
+// SecretKeyFromEnv 从环境变量 CONFIG_SECRET_KEY 中读取密钥，没有时读取 CONFIG_SECRET_KEY_FILE 指定的文件
+func SecretKeyFromEnv() (string, error) {
+	if secret := os.Getenv(SecretKeyEnv); secret != "" {
+		return secret, nil
+	}
+	if path := os.Getenv(SecretKeyFileEnv); path != "" {
+		bytes, err := loadFile(path)
+		if err != nil {
+			return "", err
+		}
+		if secret := strings.TrimRight(string(bytes), " \t\r\n"); secret != "" {
+			return secret, nil
+		}
+	}
+	return "", ErrNoSecretKey
+}
+
+// decrypt 解密 value 中所有 ENC(...) 格式的字符串，对象以及数组会被复制，不修改原来的数据
+// 没有加密的值时直接返回 value
+func (c *config) decrypt(path string, value interface{}) (interface{}, error) {
+	if !hasEncrypted(value) {
+		return value, nil
+	}
+	secret, err := c.resolveSecret()
+	if err != nil {
+		return nil, err
+	}
+	return decryptValue(secret, path, value)
+}
+
+func hasEncrypted(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return IsEncrypted(v)
+	case []interface{}:
+		for _, item := range v {
+			if hasEncrypted(item) {
+				return true
+			}
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			if hasEncrypted(item) {
+				return true
+			}
+		}
+	default:
+		if m, ok := toStringMap(value); ok {
+			for _, item := range m {
+				if hasEncrypted(item) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func decryptValue(secret, path string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !IsEncrypted(v) {
+			return v, nil
+		}
+		plaintext, err := Decrypt(secret, v)
+		if err != nil {
+			return nil, fmt.Errorf("Configuration %s: %s", path, err.Error())
+		}
+		return plaintext, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			decrypted, err := decryptValue(secret, joinPath(path, fmt.Sprint(i)), item)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = decrypted
+		}
+		return result, nil
+	case []map[string]interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			decrypted, err := decryptValue(secret, joinPath(path, fmt.Sprint(i)), item)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = decrypted
+		}
+		return result, nil
+	}
+
+	m, ok := toStringMap(value)
+	if !ok {
+		return value, nil
+	}
+	result := make(map[string]interface{}, len(m))
+	for key, item := range m {
+		decrypted, err := decryptValue(secret, joinPath(path, key), item)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = decrypted
+	}
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	value, err := Encrypt("my-secret", "123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(value) {
+		t.Fatalf("Encrypt: %s", value)
+	}
+	if other, _ := Encrypt("my-secret", "123456"); other == value {
+		t.Fatal("Encrypt should use random iv")
+	}
+	if plaintext, err := Decrypt("my-secret", value); err != nil || plaintext != "123456" {
+		t.Fatalf("Decrypt: %s, err: %v", plaintext, err)
+	}
+	if _, err := Decrypt("wrong", value); err == nil {
+		t.Fatal("Decrypt with wrong key should fail")
+	}
+	if _, err := Decrypt("my-secret", "ENC(bad)"); err == nil {
+		t.Fatal("Decrypt invalid value should fail")
+	}
+
+	os.Unsetenv(SecretKeyEnv)
+	os.Unsetenv(SecretKeyFileEnv)
+
+	c := newConfig()
+	c.LoadJSON([]byte(fmt.Sprintf(`{"mysql": {"user": "root", "password": %q}, "tokens": [%q]}`, value, value)))
+	SetDefault(c)
+	if _, err := C("mysql.password"); err != ErrNoSecretKey {
+		t.Fatalf("no secret key, err: %v", err)
+	}
+	if user, _ := C("mysql.user"); user != "root" {
+		t.Fatalf("mysql.user: %s", user)
+	}
+
+	os.Setenv(SecretKeyEnv, "my-secret")
+	if password, _ := C("mysql.password"); password != "123456" {
+		t.Fatalf("env key, mysql.password: %s", password)
+	}
+	os.Unsetenv(SecretKeyEnv)
+
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "secret.key")
+	ioutil.WriteFile(keyFile, []byte("my-secret\n"), 0600)
+	os.Setenv(SecretKeyFileEnv, keyFile)
+	defer os.Unsetenv(SecretKeyFileEnv)
+	if password, _ := C("mysql.password"); password != "123456" {
+		t.Fatalf("key file, mysql.password: %s", password)
+	}
+
+	c.SetSecretKey("wrong")
+	if _, err := C("mysql.password"); err == nil {
+		t.Fatal("wrong key should fail")
+	}
+
+	c.SetSecretKey("my-secret")
+	var mysql struct {
+		User     string `config:"user"`
+		Password string `config:"password"`
+	}
+	if err := c.Unmarshal("mysql", &mysql); err != nil || mysql.Password != "123456" {
+		t.Fatalf("Unmarshal: %+v, err: %v", mysql, err)
+	}
+	var tokens []string
+	if err := c.Unmarshal("tokens", &tokens); err != nil || tokens[0] != "123456" {
+		t.Fatalf("Unmarshal tokens: %v, err: %v", tokens, err)
+	}
+	// 原始数据不会被修改
+	if raw, _ := lookup(c.values(), "mysql.password"); raw != value {
+		t.Fatalf("raw mysql.password: %v", raw)
+	}
+}
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eg:
+// database:
+//   host: 127.0.0.1
+//   port: "3306"
+//   timeout: 5s
+//
+// type Database struct {
+// 	Host    string        `config:"host"`
+// 	Port    int           `config:"port"`
+// 	User    string        `config:"user" default:"root"`
+// 	Timeout time.Duration `config:"timeout"`
+// }
+//
+// var db Database
+// err := config.Unmarshal("database", &db)
+//
+// 字段名称使用 config 标签指定，没有标签时使用字段名称，不区分大小写，标签为 - 时忽略该字段
+// 配置不存在时使用 default 标签的值，数组的默认值使用 , 分隔
+// 支持的类型转换:
+// 字符串与数字、bool 之间相互转换，数字转为整数时不能有小数部分
+// time.Duration 可以是 "5s" 这样的字符串，或者表示纳秒的数字
+// 嵌套的结构体、指针、数组以及 map[string]T
+
+// Unmarshal 将 path 对应的数据绑定到 v，v 必须是非空指针
+func (c *config) Unmarshal(path string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("Unmarshal requires a non-nil pointer")
+	}
+	value, err := c.Any(path)
+	if err != nil {
+		return err
+	}
+	return decode(path, value, rv.Elem())
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// decode 将 value 转换之后写入 out，path 用于错误信息
+func decode(path string, value interface{}, out reflect.Value) error {
+	if value == nil {
+		return nil
+	}
+
+	if out.Type() == durationType {
+		if s, ok := value.(string); ok {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return decodeError(path, value, out)
+			}
+			out.SetInt(int64(d))
+			return nil
+		}
+	}
+
+	switch out.Kind() {
+	case reflect.Interface:
+		// 非空接口，例如 fmt.Stringer，值没有实现时不能赋值
+		if !reflect.TypeOf(value).AssignableTo(out.Type()) {
+			return decodeError(path, value, out)
+		}
+		out.Set(reflect.ValueOf(value))
+	case reflect.Ptr:
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		return decode(path, value, out.Elem())
+	case reflect.Struct:
+		return decodeStruct(path, value, out)
+	case reflect.Map:
+		return decodeMap(path, value, out)
+	case reflect.Slice:
+		return decodeSlice(path, value, out)
+	case reflect.String:
+		s, ok := toString(value)
+		if !ok {
+			return decodeError(path, value, out)
+		}
+		out.SetString(s)
+	case reflect.Bool:
+		b, ok := toBool(value)
+		if !ok {
+			return decodeError(path, value, out)
+		}
+		out.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := toInt(value)
+		if !ok || out.OverflowInt(i) {
+			return decodeError(path, value, out)
+		}
+		out.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, ok := toInt(value)
+		if !ok || i < 0 || out.OverflowUint(uint64(i)) {
+			return decodeError(path, value, out)
+		}
+		out.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat(value)
+		if !ok || out.OverflowFloat(f) {
+			return decodeError(path, value, out)
+		}
+		out.SetFloat(f)
+	default:
+		return decodeError(path, value, out)
+	}
+	return nil
+}
+
+func decodeStruct(path string, value interface{}, out reflect.Value) error {
+	// time.Time 等没有导出字段的结构体，类型相同时直接赋值
+	if rv := reflect.ValueOf(value); rv.Type().AssignableTo(out.Type()) {
+		out.Set(rv)
+		return nil
+	}
+	m, ok := toStringMap(value)
+	if !ok {
+		return decodeError(path, value, out)
+	}
+
+	t := out.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get("config")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldValue, exist := m[name]
+		if !exist {
+			for key, v := range m {
+				if strings.EqualFold(key, name) {
+					fieldValue, exist = v, true
+					break
+				}
+			}
+		}
+		if !exist {
+			def, ok := field.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			fieldValue = defaultValue(def, field.Type)
+		}
+		if err := decode(joinPath(path, name), fieldValue, out.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultValue 数组的默认值使用 , 分隔
+func defaultValue(def string, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Slice {
+		return def
+	}
+	if def == "" {
+		return []interface{}{}
+	}
+	var values []interface{}
+	for _, s := range strings.Split(def, ",") {
+		values = append(values, strings.TrimSpace(s))
+	}
+	return values
+}
+
+func decodeMap(path string, value interface{}, out reflect.Value) error {
+	m, ok := toStringMap(value)
+	if !ok || out.Type().Key().Kind() != reflect.String {
+		return decodeError(path, value, out)
+	}
+	if out.IsNil() {
+		out.Set(reflect.MakeMapWithSize(out.Type(), len(m)))
+	}
+	for key, v := range m {
+		elem := reflect.New(out.Type().Elem()).Elem()
+		if err := decode(joinPath(path, key), v, elem); err != nil {
+			return err
+		}
+		out.SetMapIndex(reflect.ValueOf(key).Convert(out.Type().Key()), elem)
+	}
+	return nil
+}
+
+func decodeSlice(path string, value interface{}, out reflect.Value) error {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return decodeError(path, value, out)
+	}
+	slice := reflect.MakeSlice(out.Type(), rv.Len(), rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		if err := decode(joinPath(path, strconv.Itoa(i)), rv.Index(i).Interface(), slice.Index(i)); err != nil {
+			return err
+		}
+	}
+	out.Set(slice)
+	return nil
+}
+
+func decodeError(path string, value interface{}, out reflect.Value) error {
+	return fmt.Errorf("Configuration %s: cannot convert %v (%T) to %s", path, value, value, out.Type())
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// toInt 整数、没有小数部分的浮点数以及字符串转为 int64
+func toInt(v interface{}) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(f), true
+	case reflect.String:
+		i, err := strconv.ParseInt(strings.TrimSpace(rv.String()), 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// toFloat 数字以及字符串转为 float64
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		f, err := strconv.ParseFloat(strings.TrimSpace(rv.String()), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toBool bool 以及字符串转为 bool，字符串的规则与 CB 一致
+func toBool(v interface{}) (bool, bool) {
+	switch b := v.(type) {
+	case bool:
+		return b, true
+	case string:
+		switch b {
+		case "1", "t", "T", "true", "TRUE", "True":
+			return true, true
+		case "0", "f", "F", "false", "FALSE", "False":
+			return false, true
+		}
+	}
+	if i, ok := toInt(v); ok && (i == 0 || i == 1) {
+		return i == 1, true
+	}
+	return false, false
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testServer struct {
+	Addr   string `config:"addr"`
+	Weight int    `config:"weight" default:"1"`
+}
+
+type testApp struct {
+	Name     string
+	Debug    bool          `config:"debug"`
+	Port     int           `config:"port"`
+	Ratio    float64       `config:"ratio" default:"0.5"`
+	Timeout  time.Duration `config:"timeout"`
+	Tags     []string      `config:"tags" default:"a, b"`
+	Servers  []testServer  `config:"servers"`
+	Labels   map[string]int
+	Database *struct {
+		Host string `config:"host" default:"127.0.0.1"`
+		Port uint16 `config:"port"`
+	} `config:"database"`
+	Ignored string `config:"-"`
+}
+
+func TestPath(t *testing.T) {
+	c := newConfig()
+	if err := c.FileTOML("./toml_test.toml"); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := c.Any("server.group.count"); count != int64(10) {
+		t.Fatalf("server.group.count: %v", count)
+	}
+	if _, err := c.Any("server.none"); err != ErrNotExist {
+		t.Fatalf("server.none, err: %v", err)
+	}
+
+	c = newConfig()
+	c.LoadYAML([]byte("servers:\n  - addr: a:80\n  - addr: b:80\nport: 8080\n"))
+	c.LoadJSON([]byte(`{"a.b": "dotted"}`))
+	SetDefault(c)
+	if addr, _ := C("servers.1.addr"); addr != "b:80" {
+		t.Fatalf("servers.1.addr: %s", addr)
+	}
+	if _, err := C("servers.2.addr"); err != ErrNotExist {
+		t.Fatalf("servers.2.addr, err: %v", err)
+	}
+	if port := DI("port", 0); port != 8080 {
+		t.Fatalf("port: %d", port)
+	}
+	if _, err := C("servers"); err != ErrNotScalar {
+		t.Fatalf("C array, err: %v", err)
+	}
+	// 顶层的 key 包含 . 时优先匹配
+	if v, _ := C("a.b"); v != "dotted" {
+		t.Fatalf("a.b: %s", v)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	sources := map[string]func(c *config) error{
+		"json": func(c *config) error {
+			return c.LoadJSON([]byte(`{"app": {"name": "demo", "debug": "true", "port": "8080", "timeout": "5s",
+				"servers": [{"addr": "a:80", "weight": 3}, {"addr": "b:80"}], "labels": {"x": 1.0},
+				"database": {"port": 3306}, "ignored": "x"}}`))
+		},
+		"toml": func(c *config) error {
+			return c.LoadTOML([]byte(`[app]
+name = "demo"
+debug = true
+port = 8080
+timeout = "5s"
+labels = { x = 1 }
+ignored = "x"
+[[app.servers]]
+addr = "a:80"
+weight = 3
+[[app.servers]]
+addr = "b:80"
+[app.database]
+port = 3306
+`))
+		},
+		"yaml": func(c *config) error {
+			return c.LoadYAML([]byte(`app:
+  name: demo
+  debug: 1
+  port: 8080
+  timeout: 5s
+  servers:
+    - addr: a:80
+      weight: "3"
+    - addr: b:80
+  labels:
+    x: 1
+  database:
+    port: 3306
+  ignored: x
+`))
+		},
+	}
+
+	for format, load := range sources {
+		c := newConfig()
+		if err := load(c); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		var app testApp
+		if err := c.Unmarshal("app", &app); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if app.Name != "demo" || !app.Debug || app.Port != 8080 || app.Ratio != 0.5 || app.Timeout != 5*time.Second {
+			t.Fatalf("%s: %+v", format, app)
+		}
+		if !reflect.DeepEqual(app.Tags, []string{"a", "b"}) || app.Ignored != "" {
+			t.Fatalf("%s tags: %v, ignored: %s", format, app.Tags, app.Ignored)
+		}
+		if !reflect.DeepEqual(app.Servers, []testServer{{"a:80", 3}, {"b:80", 1}}) {
+			t.Fatalf("%s servers: %+v", format, app.Servers)
+		}
+		if app.Labels["x"] != 1 || app.Database == nil || app.Database.Host != "127.0.0.1" || app.Database.Port != 3306 {
+			t.Fatalf("%s labels: %v, database: %+v", format, app.Labels, app.Database)
+		}
+
+		var server testServer
+		if err := c.Unmarshal("app.servers.0", &server); err != nil || server.Weight != 3 {
+			t.Fatalf("%s server: %+v, err: %v", format, server, err)
+		}
+	}
+
+	c := newConfig()
+	c.LoadJSON([]byte(`{"port": "http", "ratio": 1.5}`))
+	var v struct {
+		Port  int
+		Ratio int
+	}
+	if err := c.Unmarshal("", &v); err == nil {
+		t.Fatal("Unmarshal invalid int should fail")
+	}
+	if err := c.Unmarshal("none", &v); err != ErrNotExist {
+		t.Fatalf("Unmarshal none, err: %v", err)
+	}
+	if err := c.Unmarshal("", v); err == nil {
+		t.Fatal("Unmarshal non-pointer should fail")
+	}
+
+	// 非空接口不能赋值时返回错误，不能 panic
+	var iface struct {
+		Port fmt.Stringer
+	}
+	if err := c.Unmarshal("", &iface); err == nil || !strings.Contains(err.Error(), "Port") {
+		t.Fatalf("Unmarshal non-empty interface, err: %v", err)
+	}
+	var empty struct {
+		Port interface{}
+	}
+	if err := c.Unmarshal("", &empty); err != nil || empty.Port != "http" {
+		t.Fatalf("Unmarshal empty interface: %+v, err: %v", empty, err)
+	}
+}
@@ -0,0 +1,317 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// eg:
+// c := config.NewConfig()
+// c.FileYAML("./app.yaml")
+// c.OnChange(func(keys []string) {
+// 	log.Infof("config changed: %v", keys)
+// })
+//
+// 新的配置校验失败时不会替换，继续使用原来的配置
+// err := c.Watch(config.WatchValidate(func(c config.Config) error {
+// 	if _, err := c.Any("addr"); err != nil {
+// 		return errors.New("addr is required")
+// 	}
+// 	return nil
+// }), config.WatchError(func(err error) {
+// 	log.Errorf("reload config failed, err: %s", err.Error())
+// }))
+//
+// 文件发生变化时重新读取所有的数据来源，包括 LoadJSON 等从 bytes 中读取的数据，然后按加载顺序合并
+// linux 下使用 inotify 监听文件所在的目录，其它系统或者 inotify 不可用时定时检查文件的修改时间以及大小
+
+var (
+	// ErrNoFile 没有通过 FileJSON, FileTOML, FileYAML, FileEnv 加载的文件
+	ErrNoFile = errors.New("No configuration file to watch")
+	// ErrWatching 已经在监听
+	ErrWatching = errors.New("Configuration is already being watched")
+)
+
+// watchDelay 收到文件变化的事件之后，等待一段时间再读取，合并短时间内的多次写入
+const watchDelay = 100 * time.Millisecond
+
+// watchConfig 监听参数
+type watchConfig struct {
+	interval  time.Duration
+	polling   bool
+	validates []func(c Config) error
+	onError   func(err error)
+}
+
+// WatchOption ..
+type WatchOption func(*watchConfig)
+
+// WatchInterval 定时检查文件的间隔，默认为 1s
+func WatchInterval(interval time.Duration) WatchOption {
+	return func(c *watchConfig) {
+		if interval > 0 {
+			c.interval = interval
+		}
+	}
+}
+
+// WatchPolling 总是定时检查文件，不使用 inotify，例如文件位于网络文件系统中
+func WatchPolling() WatchOption {
+	return func(c *watchConfig) {
+		c.polling = true
+	}
+}
+
+// WatchValidate 重新加载之后，校验新的配置，返回错误时不替换
+func WatchValidate(fn func(c Config) error) WatchOption {
+	return func(c *watchConfig) {
+		c.validates = append(c.validates, fn)
+	}
+}
+
+// WatchError 重新加载失败时调用，包括读取文件、解析以及校验失败
+func WatchError(fn func(err error)) WatchOption {
+	return func(c *watchConfig) {
+		c.onError = fn
+	}
+}
+
+// notifier 文件变化的通知
+type notifier interface {
+	// Events 文件发生变化时可读
+	Events() <-chan struct{}
+	Close() error
+}
+
+type watcher struct {
+	conf  *watchConfig
+	paths []string
+	// stats 最近一次读取时文件的状态
+	stats []fileStat
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// OnChange 注册配置发生变化时的回调
+func (c *config) OnChange(fn func(keys []string)) {
+	c.mu.Lock()
+	c.callbacks = append(c.callbacks, fn)
+	c.mu.Unlock()
+}
+
+// Watch 监听配置文件
+func (c *config) Watch(opts ...WatchOption) error {
+	conf := &watchConfig{interval: time.Second}
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.watcher != nil {
+		return ErrWatching
+	}
+	var paths []string
+	for _, src := range c.sources {
+		if src.path != "" {
+			paths = append(paths, src.path)
+		}
+	}
+	if len(paths) == 0 {
+		return ErrNoFile
+	}
+
+	w := &watcher{
+		conf:  conf,
+		paths: paths,
+		stats: statFiles(paths),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	var n notifier
+	if !conf.polling {
+		// inotify 不可用时使用定时检查
+		n, _ = newNotifier(paths)
+	}
+	c.watcher = w
+	go c.watch(w, n)
+	return nil
+}
+
+// StopWatch 停止监听，等待正在进行的重新加载完成
+func (c *config) StopWatch() {
+	c.mu.Lock()
+	w := c.watcher
+	c.watcher = nil
+	c.mu.Unlock()
+
+	if w != nil {
+		close(w.stop)
+		<-w.done
+	}
+}
+
+func (c *config) watch(w *watcher, n notifier) {
+	defer close(w.done)
+
+	var events <-chan struct{}
+	if n != nil {
+		defer n.Close()
+		events = n.Events()
+	}
+	// 使用 inotify 时也定时检查，避免遗漏事件，例如文件所在的目录被删除之后重新创建
+	ticker := time.NewTicker(w.conf.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-events:
+			select {
+			case <-w.stop:
+				return
+			case <-time.After(watchDelay):
+			}
+			// 丢弃等待期间的事件
+			for len(events) > 0 {
+				<-events
+			}
+		case <-ticker.C:
+			if reflect.DeepEqual(w.stats, statFiles(w.paths)) {
+				continue
+			}
+		}
+
+		w.stats = statFiles(w.paths)
+		if err := c.reload(w.conf.validates); err != nil && w.conf.onError != nil {
+			w.conf.onError(err)
+		}
+	}
+}
+
+// fileStat 用于判断文件是否发生变化
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFiles(paths []string) []fileStat {
+	stats := make([]fileStat, len(paths))
+	for i, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			stats[i] = fileStat{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stats
+}
+
+// reload 依次读取所有的数据来源，校验通过之后替换数据
+func (c *config) reload(validates []func(c Config) error) error {
+	c.mu.Lock()
+	keys, err := c.rebuild(validates)
+	callbacks := c.callbacks
+	c.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	notify(callbacks, keys)
+	return nil
+}
+
+// rebuild 需要持有 mu
+func (c *config) rebuild(validates []func(c Config) error) ([]string, error) {
+	sources := make([]*source, len(c.sources))
+	for i, src := range c.sources {
+		data, err := src.read()
+		if err != nil {
+			return nil, err
+		}
+		sources[i] = &source{layer: src.layer, name: src.name, data: data}
+	}
+	data := mergeSources(sources)
+
+	candidate := newConfig()
+	candidate.sources = sources
+	candidate.secret = c.secret
+	candidate.data.Store(data)
+	candidate.init = 1
+	for _, validate := range validates {
+		if err := validate(candidate); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, src := range c.sources {
+		src.data = sources[i].data
+	}
+	return c.swap(data), nil
+}
+
+// notify 有 key 发生变化时调用回调
+func notify(callbacks []func(keys []string), keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	for _, fn := range callbacks {
+		fn(keys)
+	}
+}
+
+// changedKeys 比较新旧数据，返回发生变化的 key，嵌套的 key 使用 . 连接
+func changedKeys(old, new map[string]interface{}) []string {
+	var keys []string
+	diffValue("", old, new, &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+func diffValue(prefix string, old, new interface{}, keys *[]string) {
+	oldMap, oldOK := toStringMap(old)
+	newMap, newOK := toStringMap(new)
+	if !oldOK || !newOK {
+		if !reflect.DeepEqual(old, new) {
+			*keys = append(*keys, prefix)
+		}
+		return
+	}
+
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	for key, value := range oldMap {
+		if newValue, exist := newMap[key]; exist {
+			diffValue(join(key), value, newValue, keys)
+		} else {
+			*keys = append(*keys, join(key))
+		}
+	}
+	for key := range newMap {
+		if _, exist := oldMap[key]; !exist {
+			*keys = append(*keys, join(key))
+		}
+	}
+}
+
+// toStringMap yaml 中嵌套的对象解析为 map[interface{}]interface{}，转为 map[string]interface{}
+func toStringMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(m))
+		for key, value := range m {
+			result[fmt.Sprint(key)] = value
+		}
+		return result, true
+	}
+	return nil, false
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	// 先写入临时文件再重命名，与编辑器的行为一致
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testWatch(t *testing.T, opts ...WatchOption) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.yaml")
+	writeFile(t, path, "addr: 127.0.0.1:8080\nserver:\n  port: 8080\n  host: localhost\n")
+
+	c := newConfig()
+	if err := c.FileYAML(path); err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan []string, 10)
+	c.OnChange(func(keys []string) {
+		changes <- keys
+	})
+	errs := make(chan error, 10)
+	opts = append(opts, WatchInterval(20*time.Millisecond), WatchValidate(func(c Config) error {
+		if _, err := c.Any("addr"); err != nil {
+			return errors.New("addr is required")
+		}
+		return nil
+	}), WatchError(func(err error) {
+		errs <- err
+	}))
+	if err := c.Watch(opts...); err != nil {
+		t.Fatal(err)
+	}
+	defer c.StopWatch()
+	if err := c.Watch(); err != ErrWatching {
+		t.Fatalf("Watch twice, err: %v", err)
+	}
+
+	writeFile(t, path, "addr: 127.0.0.1:9090\nserver:\n  port: 8080\n  host: example.com\nname: app\n")
+	select {
+	case keys := <-changes:
+		if !reflect.DeepEqual(keys, []string{"addr", "name", "server.host"}) {
+			t.Fatalf("changed keys: %v", keys)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change notified")
+	}
+	if addr, _ := c.Any("addr"); addr != "127.0.0.1:9090" {
+		t.Fatalf("addr: %v", addr)
+	}
+
+	// 校验失败时保留原来的配置
+	writeFile(t, path, "server:\n  port: 8081\n")
+	select {
+	case err := <-errs:
+		if err.Error() != "addr is required" {
+			t.Fatalf("reload err: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no validate error")
+	}
+	if addr, _ := c.Any("addr"); addr != "127.0.0.1:9090" {
+		t.Fatalf("addr after invalid reload: %v", addr)
+	}
+	select {
+	case keys := <-changes:
+		t.Fatalf("invalid config notified: %v", keys)
+	default:
+	}
+}
+
+func TestWatch(t *testing.T) {
+	testWatch(t)
+}
+
+func TestWatchPolling(t *testing.T) {
+	testWatch(t, WatchPolling())
+}
+
+func TestWatchNoFile(t *testing.T) {
+	c := newConfig()
+	c.LoadJSON([]byte(`{"a": "1"}`))
+	if err := c.Watch(); err != ErrNoFile {
+		t.Fatalf("Watch without file, err: %v", err)
+	}
+}
@@ -329,7 +329,7 @@ func AesCBCDecode(key, iv, ciphertext []byte) ([]byte, error) {
 	blockSize := block.BlockSize()
 
 	// 密文大小必须是快的倍数
-	if len(ciphertext)%blockSize != 0 {
+	if len(ciphertext) == 0 || len(ciphertext)%blockSize != 0 {
 		return nil, errors.New("ciphertext is not a multiple of the block size")
 	}
 
@@ -338,7 +338,7 @@ func AesCBCDecode(key, iv, ciphertext []byte) ([]byte, error) {
 	mode := cipher.NewCBCDecrypter(block, iv)
 	mode.CryptBlocks(plaintext, ciphertext)
 
-	return pkcs7Unpadding(plaintext), nil
+	return pkcs7Unpadding(plaintext, blockSize)
 }
 
 func pkcs7Padding(ciphertext []byte, blockSize int) []byte {
@@ -347,8 +347,17 @@ func pkcs7Padding(ciphertext []byte, blockSize int) []byte {
 	return append(ciphertext, padtext...)
 }
 
-func pkcs7Unpadding(data []byte) []byte {
+// pkcs7Unpadding 密钥错误时填充通常无效，返回错误而不是 panic
+func pkcs7Unpadding(data []byte, blockSize int) ([]byte, error) {
 	length := len(data)
 	unpadding := int(data[length-1])
-	return data[:(length - unpadding)]
+	if unpadding == 0 || unpadding > blockSize || unpadding > length {
+		return nil, errors.New("invalid padding")
+	}
+	for _, b := range data[length-unpadding:] {
+		if int(b) != unpadding {
+			return nil, errors.New("invalid padding")
+		}
+	}
+	return data[:(length - unpadding)], nil
 }
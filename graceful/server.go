@@ -21,7 +21,8 @@ type Server struct {
 }
 
 // NewServer 生成服务器，用来替代 `http.Server`
-func NewServer(handler http.Handler, logger logger.Logger) *Server {
+// 日志注册为 graceful，可以通过 logger.LevelHandler 修改级别
+func NewServer(handler http.Handler, l logger.Logger) *Server {
 	var tc tcpKeepAliveListener
 
 	// 结构体初始化: 如果匿名字段也要初始化，则采取不声明 key 的方式 或者都声明 key 的方式
@@ -30,7 +31,10 @@ func NewServer(handler http.Handler, logger logger.Logger) *Server {
 	}, tc}
 
 	gserver.server = server
-	gserver.logger = logger
+	gserver.logger = l
+	if l != nil {
+		logger.Register("graceful", l)
+	}
 
 	return server
 }
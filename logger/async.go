@@ -0,0 +1,226 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// eg:
+// 写文件在后台的 goroutine 中执行，队列满时丢弃最旧的日志
+// file, _ := logger.NewRotateWriter("./logs/app.log")
+// writer := logger.NewAsyncWriter(file, logger.AsyncQueueSize(4096), logger.AsyncPolicy(logger.DropOldest))
+// log := logger.NewWriterLogger(writer)
+//
+// 关闭服务器时写入队列中剩余的日志，然后关闭文件
+// graceful.RegisterShutdownHandler(func() {
+// 	writer.Close()
+// })
+//
+// 被丢弃的日志数量
+// dropped := writer.Dropped()
+
+// 队列满时的处理方式
+const (
+	// Block 等待队列中有空位，不会丢弃日志
+	Block = iota
+	// DropOldest 丢弃队列中最旧的日志
+	DropOldest
+	// DropNewest 丢弃正在写入的日志
+	DropNewest
+)
+
+// asyncConfig 异步写入的参数
+type asyncConfig struct {
+	queueSize int
+	policy    int
+}
+
+// AsyncOption ..
+type AsyncOption func(*asyncConfig)
+
+// AsyncQueueSize 队列的长度，默认 1024
+func AsyncQueueSize(size int) AsyncOption {
+	return func(c *asyncConfig) {
+		if size > 0 {
+			c.queueSize = size
+		}
+	}
+}
+
+// AsyncPolicy 队列满时的处理方式，Block, DropOldest, DropNewest，默认 Block
+func AsyncPolicy(policy int) AsyncOption {
+	return func(c *asyncConfig) {
+		c.policy = policy
+	}
+}
+
+// asyncEntry 队列中的一条日志，seq 用于 Flush
+type asyncEntry struct {
+	seq  uint64
+	data []byte
+}
+
+// AsyncWriter 在后台 goroutine 中写入 w，队列的长度有限
+type AsyncWriter struct {
+	w      io.Writer
+	policy int
+
+	// mu 保证 seq 的顺序与入队的顺序一致
+	mu     sync.Mutex
+	seq    uint64
+	closed bool
+	queue  chan asyncEntry
+
+	// doneMu 保护 done 以及 err
+	doneMu   sync.Mutex
+	doneCond *sync.Cond
+	// done 已经写入的最后一条日志的 seq
+	done uint64
+	// err 最近一次写入失败的错误，Flush 时返回
+	err error
+
+	dropped uint64
+	exit    chan struct{}
+}
+
+// NewAsyncWriter ..
+func NewAsyncWriter(w io.Writer, opts ...AsyncOption) *AsyncWriter {
+	conf := &asyncConfig{queueSize: 1024, policy: Block}
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	aw := &AsyncWriter{
+		w:      w,
+		policy: conf.policy,
+		queue:  make(chan asyncEntry, conf.queueSize),
+		exit:   make(chan struct{}),
+	}
+	aw.doneCond = sync.NewCond(&aw.doneMu)
+	go aw.run()
+	return aw
+}
+
+// Write 将 p 复制之后放入队列，关闭之后返回 os.ErrClosed
+func (aw *AsyncWriter) Write(p []byte) (int, error) {
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	if aw.closed {
+		return 0, os.ErrClosed
+	}
+	entry := asyncEntry{seq: aw.seq + 1, data: data}
+
+	switch aw.policy {
+	case DropNewest:
+		select {
+		case aw.queue <- entry:
+		default:
+			atomic.AddUint64(&aw.dropped, 1)
+			return len(p), nil
+		}
+	case DropOldest:
+		for sent := false; !sent; {
+			select {
+			case aw.queue <- entry:
+				sent = true
+			default:
+				select {
+				case <-aw.queue:
+					atomic.AddUint64(&aw.dropped, 1)
+				default:
+				}
+			}
+		}
+	default:
+		aw.queue <- entry
+	}
+
+	aw.seq = entry.seq
+	return len(p), nil
+}
+
+// Flush 等待调用 Flush 之前写入的日志全部写入 w，w 实现了 Sync 或者 Flush 时同时调用
+// 返回最近一次写入失败的错误
+func (aw *AsyncWriter) Flush() error {
+	aw.mu.Lock()
+	target := aw.seq
+	aw.mu.Unlock()
+
+	aw.doneMu.Lock()
+	for aw.done < target {
+		aw.doneCond.Wait()
+	}
+	err := aw.err
+	aw.err = nil
+	aw.doneMu.Unlock()
+
+	if syncErr := syncWriter(aw.w); err == nil {
+		err = syncErr
+	}
+	return err
+}
+
+// Close 写入队列中剩余的日志，然后关闭 w，w 需要实现 io.Closer
+func (aw *AsyncWriter) Close() error {
+	aw.mu.Lock()
+	if aw.closed {
+		aw.mu.Unlock()
+		return nil
+	}
+	aw.closed = true
+	close(aw.queue)
+	aw.mu.Unlock()
+
+	<-aw.exit
+
+	err := aw.Flush()
+	if closer, ok := aw.w.(io.Closer); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Dropped 队列满时被丢弃的日志数量
+func (aw *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&aw.dropped)
+}
+
+// Len 队列中等待写入的日志数量
+func (aw *AsyncWriter) Len() int {
+	return len(aw.queue)
+}
+
+func (aw *AsyncWriter) run() {
+	defer close(aw.exit)
+
+	for entry := range aw.queue {
+		_, err := aw.w.Write(entry.data)
+
+		aw.doneMu.Lock()
+		aw.done = entry.seq
+		if err != nil {
+			aw.err = err
+		}
+		aw.doneCond.Broadcast()
+		aw.doneMu.Unlock()
+	}
+}
+
+// syncWriter w 实现了 Sync 或者 Flush 时调用，例如 *os.File, *bufio.Writer
+func syncWriter(w io.Writer) error {
+	switch s := w.(type) {
+	case interface{ Sync() error }:
+		return s.Sync()
+	case interface{ Flush() error }:
+		return s.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// gateWriter 在 gate 关闭之前阻塞写入
+type gateWriter struct {
+	gate   chan struct{}
+	mu     sync.Mutex
+	buff   bytes.Buffer
+	closed bool
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{gate: make(chan struct{})}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buff.Write(p)
+}
+
+func (w *gateWriter) Close() error {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buff.String()
+}
+
+func TestAsyncDrop(t *testing.T) {
+	for _, policy := range []int{DropOldest, DropNewest} {
+		w := newGateWriter()
+		aw := NewAsyncWriter(w, AsyncQueueSize(2), AsyncPolicy(policy))
+
+		// 第一条被后台 goroutine 取出之后阻塞，队列中最多 2 条
+		aw.Write([]byte("0\n"))
+		for aw.Len() != 0 {
+			runtime.Gosched()
+		}
+		for i := 1; i <= 4; i++ {
+			aw.Write([]byte(fmt.Sprintf("%d\n", i)))
+		}
+		close(w.gate)
+		if err := aw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := "0\n3\n4\n"
+		if policy == DropNewest {
+			expect = "0\n1\n2\n"
+		}
+		if w.String() != expect || aw.Dropped() != 2 {
+			t.Errorf("policy: %d, output: %q, dropped: %d", policy, w.String(), aw.Dropped())
+		}
+		aw.Close()
+	}
+}
+
+func TestAsyncClose(t *testing.T) {
+	w := newGateWriter()
+	aw := NewAsyncWriter(w, AsyncQueueSize(4))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				aw.Write([]byte("line\n"))
+			}
+		}()
+	}
+	close(w.gate)
+	wg.Wait()
+
+	if err := aw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if count := strings.Count(w.String(), "line\n"); count != 100 || aw.Dropped() != 0 {
+		t.Fatalf("count: %d, dropped: %d", count, aw.Dropped())
+	}
+	if !w.closed {
+		t.Fatal("underlying writer not closed")
+	}
+	if _, err := aw.Write([]byte("closed")); err != os.ErrClosed {
+		t.Fatalf("write after close, err: %v", err)
+	}
+}
+
+func TestAsyncLogger(t *testing.T) {
+	w := newGateWriter()
+	close(w.gate)
+	log := NewWriterLogger(NewAsyncWriter(w))
+	log.With(String("module", "order")).Warn("async")
+	if err := log.(*simpleLog).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(w.String(), "[WARN] async module=order\n") || !strings.Contains(w.String(), "async_test.go") {
+		t.Fatalf("output: %q", w.String())
+	}
+}
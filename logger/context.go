@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"context"
+)
+
+// eg:
+// 在中间件中设置请求 ID
+// ctx := logger.ContextWithRequestID(r.Context(), r.Header.Get("X-Request-ID"))
+//
+// 在业务代码中输出，日志中附带 request_id 以及 trace_id
+// log.WithContext(ctx).Info("query user")
+
+// 从 context 中读取的字段名称
+const (
+	RequestIDKey = "request_id"
+	TraceIDKey   = "trace_id"
+)
+
+type contextKey int
+
+const (
+	requestIDContextKey contextKey = iota
+	traceIDContextKey
+	fieldsContextKey
+)
+
+// ContextWithRequestID 在 ctx 中设置请求 ID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+// RequestIDFromContext 获取 ctx 中的请求 ID，没有时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// ContextWithTraceID 在 ctx 中设置追踪 ID
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDContextKey, traceID)
+}
+
+// TraceIDFromContext 获取 ctx 中的追踪 ID，没有时返回空字符串
+func TraceIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDContextKey).(string)
+	return id
+}
+
+// ContextWithFields 在 ctx 中附加字段，与已有的字段合并
+func ContextWithFields(ctx context.Context, fields ...Field) context.Context {
+	old, _ := ctx.Value(fieldsContextKey).([]Field)
+	return context.WithValue(ctx, fieldsContextKey, appendFields(old, fields...))
+}
+
+// FieldsFromContext 获取 ctx 中需要输出的所有字段，包括请求 ID 以及追踪 ID
+func FieldsFromContext(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsContextKey).([]Field)
+	if id := TraceIDFromContext(ctx); id != "" {
+		fields = appendFields([]Field{String(TraceIDKey, id)}, fields...)
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		fields = appendFields([]Field{String(RequestIDKey, id)}, fields...)
+	}
+	return fields
+}
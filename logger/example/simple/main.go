@@ -9,6 +9,7 @@ func main() {
 
 	log.Debug("Debug log")
 	log.Info("Info log")
+	log.With(logger.String("module", "example"), logger.Int("uid", 10001)).Info("Info log with fields")
 	log.Warn("Warn log")
 	log.Error("Error log")
 	log.Fatal("Fatal log")
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eg:
+// log := logger.NewLogger().With(logger.String("module", "order"))
+// log.With(logger.Int("uid", 10001), logger.Err(err)).Error("create order failed")
+//
+// 输出:
+// [2019-04-18 17:01:47.168][25432][order.go:97-order.Create][ERROR] create order failed module=order uid=10001 error="connection refused"
+
+// Field 结构化日志的字段
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// String ..
+func String(key, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int ..
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int64 ..
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Uint64 ..
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Float64 ..
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Bool ..
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Duration ..
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Time ..
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err 错误，key 固定为 error，err 为 nil 时值为 nil
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// Any 任意类型的值
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// String 格式为 key=value，值包含空格等字符时使用双引号
+func (f Field) String() string {
+	return f.Key + "=" + fieldValue(f.Value)
+}
+
+// fieldValue 将值转为字符串
+func fieldValue(v interface{}) string {
+	var s string
+	switch value := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		s = value
+	case error:
+		s = value.Error()
+	case time.Time:
+		s = value.Format(timeLayout)
+	case fmt.Stringer:
+		s = value.String()
+	default:
+		s = fmt.Sprint(value)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// appendFields 合并字段，返回新的数组，不修改 fields
+func appendFields(fields []Field, more ...Field) []Field {
+	result := make([]Field, 0, len(fields)+len(more))
+	result = append(result, fields...)
+	return append(result, more...)
+}
@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// timeLayout 日志中的时间格式，与 time.YMDHMSM 相同
+const timeLayout = "2006-01-02 15:04:05.000"
+
+// Entry 一条日志
+type Entry struct {
+	Time    time.Time
+	Level   int
+	Message string
+	// Fields With 以及 WithContext 附加的字段
+	Fields []Field
+
+	Pid int
+	// File 文件名称，不包括路径
+	File string
+	Line int
+	// Func 函数名称，不包括包的路径，例如 core.(*server).Start
+	Func string
+	// PC 调用位置，用于 slog，可以为 0
+	PC uintptr
+}
+
+// Formatter 将日志转为输出的内容，结果需要包括换行符
+type Formatter interface {
+	Format(e *Entry) []byte
+}
+
+// TextFormatter 与 NewLogger 的格式相同
+// [2019-04-18 17:01:47.168][25432][server.go:97-core.(*server).Start][INFO] Framework Version: 0.1.0 module=core
+type TextFormatter struct {
+	// Color 日志级别是否带颜色，用于终端
+	Color bool
+}
+
+// Format ..
+func (f *TextFormatter) Format(e *Entry) []byte {
+	buff := Buffer()
+	defer ReleaseBuffer(buff)
+
+	buff.WriteString("[")
+	buff.WriteString(e.Time.Format(timeLayout))
+	buff.WriteString("][")
+	buff.WriteString(strconv.Itoa(e.Pid))
+	buff.WriteString("][")
+	buff.WriteString(e.File)
+	buff.WriteString(":")
+	buff.WriteString(strconv.Itoa(e.Line))
+	buff.WriteString("-")
+	buff.WriteString(e.Func)
+	buff.WriteString("]")
+
+	if f.Color {
+		buff.WriteString("\x1b[")
+		buff.WriteString(strconv.Itoa(levelColor(e.Level)))
+		buff.WriteString(";1m[")
+		buff.WriteString(levelName(e.Level))
+		buff.WriteString("]\x1b[39;22m")
+	} else {
+		buff.WriteString("[")
+		buff.WriteString(levelName(e.Level))
+		buff.WriteString("]")
+	}
+
+	buff.WriteString(" ")
+	buff.WriteString(e.Message)
+	for _, field := range e.Fields {
+		buff.WriteString(" ")
+		buff.WriteString(field.String())
+	}
+	buff.WriteString("\n")
+
+	return copyBytes(buff.Bytes())
+}
+
+// JSONFormatter 每条日志一行 JSON，字段与 time, level, msg 等同名时，名称改为 fields.key
+// {"file":"server.go","func":"core.(*server).Start","level":"INFO","line":97,"module":"core","msg":"Framework Version: 0.1.0","pid":25432,"time":"2019-04-18 17:01:47.168"}
+type JSONFormatter struct{}
+
+// Format ..
+func (f *JSONFormatter) Format(e *Entry) []byte {
+	data := map[string]interface{}{
+		"time":  e.Time.Format(timeLayout),
+		"level": levelName(e.Level),
+		"msg":   e.Message,
+		"pid":   e.Pid,
+		"file":  e.File,
+		"line":  e.Line,
+		"func":  e.Func,
+	}
+	for _, field := range e.Fields {
+		key := field.Key
+		if _, exist := data[key]; exist {
+			key = "fields." + key
+		}
+		data[key] = jsonValue(field.Value)
+	}
+
+	content, err := json.Marshal(data)
+	if err != nil {
+		content, _ = json.Marshal(map[string]interface{}{
+			"time":  data["time"],
+			"level": data["level"],
+			"msg":   fmt.Sprintf("%s (marshal fields failed: %s)", e.Message, err.Error()),
+		})
+	}
+	return append(content, '\n')
+}
+
+// jsonValue error 以及 time.Duration 等转为字符串，其它类型无法转为 JSON 时使用 fmt.Sprint
+func jsonValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return value
+	case error:
+		return value.Error()
+	case time.Time:
+		return value.Format(timeLayout)
+	case fmt.Stringer:
+		return value.String()
+	}
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+// SyslogFormatter RFC 5424 格式，用于发送到 syslog 服务器，见 NetSink
+// <14>1 2019-04-18T17:01:47.168+08:00 host app 25432 - - [server.go:97-core.(*server).Start] Framework Version: 0.1.0 module=core
+type SyslogFormatter struct {
+	// Facility 默认为 1 (user-level)，例如 local0 为 16
+	Facility int
+	// Tag 应用名称，默认为程序名称
+	Tag string
+	// Hostname 默认为 os.Hostname
+	Hostname string
+}
+
+// Format ..
+func (f *SyslogFormatter) Format(e *Entry) []byte {
+	facility := f.Facility
+	if facility == 0 {
+		facility = 1
+	}
+	tag := f.Tag
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	hostname := f.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+		if hostname == "" {
+			hostname = "-"
+		}
+	}
+
+	var buff bytes.Buffer
+	fmt.Fprintf(&buff, "<%d>1 %s %s %s %d - - [%s:%d-%s] %s",
+		facility*8+syslogSeverity(e.Level), e.Time.Format("2006-01-02T15:04:05.000Z07:00"),
+		hostname, tag, e.Pid, e.File, e.Line, e.Func, e.Message)
+	for _, field := range e.Fields {
+		buff.WriteString(" ")
+		buff.WriteString(field.String())
+	}
+	buff.WriteString("\n")
+	return buff.Bytes()
+}
+
+// syslogSeverity 日志级别对应的 syslog severity
+func syslogSeverity(level int) int {
+	switch level {
+	case DEBUG:
+		return 7
+	case INFO:
+		return 6
+	case WARN:
+		return 4
+	case ERROR:
+		return 3
+	}
+	return 2
+}
+
+func copyBytes(b []byte) []byte {
+	result := make([]byte, len(b))
+	copy(result, b)
+	return result
+}
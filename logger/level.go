@@ -0,0 +1,360 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// eg:
+// 注册日志，sign, graceful 等包的日志会自动注册
+// log := logger.NewLogger()
+// logger.Register("app", log)
+//
+// 通过 HTTP 查看以及修改级别，建议只在内网端口上开放
+// http.Handle("/debug/logger", logger.LevelHandler())
+//
+// curl http://127.0.0.1:8080/debug/logger
+// {"app":"INFO","sign":"INFO"}
+//
+// 自定义日志需要实现 GetLevel() int 才能返回级别以及临时修改级别，否则级别为 UNKNOWN
+//
+// 修改级别，name 为 * 时修改所有日志，指定 duration 时到期之后恢复之前的级别
+// curl -X PUT -d '{"name":"app","level":"DEBUG","duration":"5m"}' http://127.0.0.1:8080/debug/logger
+// curl -X PUT 'http://127.0.0.1:8080/debug/logger?name=app&level=DEBUG'
+//
+// 收到 SIGUSR2 时所有日志切换到 DEBUG，10 分钟之后恢复，期间再次收到信号时立即恢复
+// stop, err := logger.DebugOnSignal(10*time.Minute, syscall.SIGUSR2)
+// defer stop()
+
+var (
+	// ErrNotRegistered 日志没有注册
+	ErrNotRegistered = errors.New("logger is not registered")
+	// ErrLevelUnknown 日志没有实现 GetLevel，无法在到期之后恢复级别
+	ErrLevelUnknown = errors.New("logger level is unknown")
+)
+
+// unknownLevel 日志没有实现 GetLevel 时返回的级别名称
+const unknownLevel = "UNKNOWN"
+
+// GetLevel 获取 l 的级别，本包创建的日志都支持，自定义日志需要实现 GetLevel() int
+func GetLevel(l Logger) (int, bool) {
+	if lg, ok := l.(interface{ GetLevel() int }); ok {
+		return lg.GetLevel(), true
+	}
+	return 0, false
+}
+
+// getLevelName 级别名称，不支持 GetLevel 时为 UNKNOWN
+func getLevelName(l Logger) string {
+	if level, ok := GetLevel(l); ok {
+		return levelName(level)
+	}
+	return unknownLevel
+}
+
+var (
+	// registryMu 保护 registry 以及 reverts
+	registryMu sync.Mutex
+	registry   = make(map[string]Logger)
+	// reverts 临时修改级别时，用于恢复之前的级别
+	reverts = make(map[string]*levelRevert)
+)
+
+// levelRevert 临时修改的级别
+type levelRevert struct {
+	level int
+	timer *time.Timer
+}
+
+// Register 注册名称为 name 的日志，已经存在时替换，l 为 nil 时与 Unregister 相同
+func Register(name string, l Logger) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	cancelRevert(name)
+	if l == nil {
+		delete(registry, name)
+		return
+	}
+	registry[name] = l
+}
+
+// Unregister ..
+func Unregister(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	cancelRevert(name)
+	delete(registry, name)
+}
+
+// Lookup 获取名称为 name 的日志
+func Lookup(name string) (Logger, bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	l, exist := registry[name]
+	return l, exist
+}
+
+// Names 所有注册的名称，已排序
+func Names() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	return sortedNames()
+}
+
+func sortedNames() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetLevelFor 修改名称为 name 的日志的级别，d > 0 时到期之后恢复之前的级别
+// 临时修改期间再次修改时，恢复的仍然是最初的级别，日志没有实现 GetLevel 时 d > 0 返回 ErrLevelUnknown
+func SetLevelFor(name string, level int, d time.Duration) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	return setLevelLocked(name, level, d)
+}
+
+// RevertLevels 立即恢复所有临时修改的级别
+func RevertLevels() {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	revertLocked()
+}
+
+// revertLocked 需要持有 registryMu
+func revertLocked() {
+	for name, r := range reverts {
+		r.timer.Stop()
+		registry[name].SetLevel(r.level)
+		delete(reverts, name)
+	}
+}
+
+// setLevelLocked 需要持有 registryMu
+func setLevelLocked(name string, level int, d time.Duration) error {
+	l, exist := registry[name]
+	if !exist {
+		return ErrNotRegistered
+	}
+
+	original, known := GetLevel(l)
+	if r, exist := reverts[name]; exist {
+		original, known = r.level, true
+	}
+	if d > 0 && !known {
+		return ErrLevelUnknown
+	}
+	cancelRevert(name)
+	l.SetLevel(level)
+
+	if d > 0 {
+		r := &levelRevert{level: original}
+		r.timer = time.AfterFunc(d, func() {
+			registryMu.Lock()
+			defer registryMu.Unlock()
+
+			if reverts[name] == r {
+				l.SetLevel(r.level)
+				delete(reverts, name)
+			}
+		})
+		reverts[name] = r
+	}
+	return nil
+}
+
+// cancelRevert 取消恢复，需要持有 registryMu
+func cancelRevert(name string) {
+	if r, exist := reverts[name]; exist {
+		r.timer.Stop()
+		delete(reverts, name)
+	}
+}
+
+// ParseLevel 解析级别名称，不区分大小写，例如 debug, INFO
+func ParseLevel(name string) (int, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "DEBUG":
+		return DEBUG, nil
+	case "INFO":
+		return INFO, nil
+	case "WARN", "WARNING":
+		return WARN, nil
+	case "ERROR":
+		return ERROR, nil
+	case "FATAL":
+		return FATAL, nil
+	}
+	return 0, fmt.Errorf("invalid level: %s", name)
+}
+
+// levelRequest PUT 的参数
+type levelRequest struct {
+	// Name 为 * 时修改所有日志
+	Name  string `json:"name"`
+	Level string `json:"level"`
+	// Duration 例如 5m，到期之后恢复之前的级别，为空时不恢复
+	Duration string `json:"duration"`
+}
+
+// LevelHandler 查看以及修改注册的日志的级别
+// GET: 返回所有日志的级别，指定 name 参数时只返回该日志
+// PUT: 参数可以是 JSON 或者 query，name, level, duration，也可以使用 POST
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			levels, err := getLevels(r.URL.Query().Get("name"))
+			if err != nil {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, levels)
+		case http.MethodPut, http.MethodPost:
+			req := levelRequest{
+				Name:     r.URL.Query().Get("name"),
+				Level:    r.URL.Query().Get("level"),
+				Duration: r.URL.Query().Get("duration"),
+			}
+			if r.Body != nil {
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+					return
+				}
+			}
+			status, result := putLevel(req)
+			writeJSON(w, status, result)
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		}
+	})
+}
+
+func getLevels(name string) (map[string]string, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	levels := make(map[string]string)
+	if name != "" && name != "*" {
+		l, exist := registry[name]
+		if !exist {
+			return nil, ErrNotRegistered
+		}
+		levels[name] = getLevelName(l)
+		return levels, nil
+	}
+	for name, l := range registry {
+		levels[name] = getLevelName(l)
+	}
+	return levels, nil
+}
+
+func putLevel(req levelRequest) (int, interface{}) {
+	if req.Name == "" {
+		return http.StatusBadRequest, map[string]string{"error": "name is required"}
+	}
+	level, err := ParseLevel(req.Level)
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+	var d time.Duration
+	if req.Duration != "" {
+		if d, err = time.ParseDuration(req.Duration); err != nil {
+			return http.StatusBadRequest, map[string]string{"error": err.Error()}
+		}
+	}
+
+	registryMu.Lock()
+	names := []string{req.Name}
+	if req.Name == "*" {
+		names = sortedNames()
+	}
+	for _, name := range names {
+		err = setLevelLocked(name, level, d)
+		// 修改所有日志时，跳过无法恢复级别的日志
+		if err == ErrLevelUnknown && req.Name == "*" {
+			err = nil
+		}
+		if err != nil {
+			break
+		}
+	}
+	registryMu.Unlock()
+
+	switch err {
+	case nil:
+	case ErrNotRegistered:
+		return http.StatusNotFound, map[string]string{"error": err.Error()}
+	default:
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+	levels, _ := getLevels(req.Name)
+	return http.StatusOK, levels
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// DebugOnSignal 收到 sig 时所有注册的日志切换到 DEBUG，window 之后恢复之前的级别
+// 切换期间再次收到信号时立即恢复，返回的 stop 用于停止监听信号
+func DebugOnSignal(window time.Duration, sig ...os.Signal) (stop func(), err error) {
+	if len(sig) == 0 {
+		return nil, errors.New("sig does not be empty")
+	}
+	if window <= 0 {
+		return nil, errors.New("window must be positive")
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig...)
+	go func() {
+		for range ch {
+			toggleDebug(window)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(ch)
+		})
+	}, nil
+}
+
+// toggleDebug 有临时修改的级别时恢复，否则所有日志切换到 DEBUG
+func toggleDebug(window time.Duration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if len(reverts) > 0 {
+		revertLocked()
+		return
+	}
+	for _, name := range sortedNames() {
+		setLevelLocked(name, DEBUG, window)
+	}
+}
@@ -0,0 +1,210 @@
+package logger
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// levelTestLoggers 注册 simpleLog 以及 logrusLog，返回之后需要调用 cleanup
+func levelTestLoggers(t *testing.T) (simple, logrus Logger, cleanup func()) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logrus, err = NewLogrusLogger("test", dir, false, false, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	simple = NewLogger()
+	simple.SetLevel(INFO)
+	logrus.SetLevel(WARN)
+
+	Register("app", simple)
+	Register("logrus", logrus)
+	return simple, logrus, func() {
+		Unregister("app")
+		Unregister("logrus")
+		os.RemoveAll(dir)
+	}
+}
+
+// levelOf 本包的日志都实现了 GetLevel
+func levelOf(l Logger) int {
+	level, _ := GetLevel(l)
+	return level
+}
+
+// plainLogger 没有实现 GetLevel 的自定义日志
+type plainLogger struct {
+	Logger
+}
+
+func doLevelRequest(t *testing.T, method, target, body string) (int, map[string]string) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(w, req)
+
+	result := make(map[string]string)
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("invalid response: %s", w.Body.String())
+	}
+	return w.Code, result
+}
+
+func TestLevelHandler(t *testing.T) {
+	simple, logrus, cleanup := levelTestLoggers(t)
+	defer cleanup()
+
+	code, result := doLevelRequest(t, http.MethodGet, "/", "")
+	if code != http.StatusOK || result["app"] != "INFO" || result["logrus"] != "WARN" {
+		t.Fatalf("get: %d %v", code, result)
+	}
+
+	code, result = doLevelRequest(t, http.MethodPut, "/?name=logrus&level=debug", "")
+	if code != http.StatusOK || result["logrus"] != "DEBUG" || !logrus.IsDebugAble() {
+		t.Fatalf("put query: %d %v", code, result)
+	}
+
+	code, result = doLevelRequest(t, http.MethodPut, "/", `{"name":"*","level":"ERROR"}`)
+	if code != http.StatusOK || result["app"] != "ERROR" || result["logrus"] != "ERROR" ||
+		simple.IsWarnAble() || logrus.IsWarnAble() {
+		t.Fatalf("put all: %d %v", code, result)
+	}
+
+	code, result = doLevelRequest(t, http.MethodPost, "/", `{"name":"app","level":"ERROR"}`)
+	if code != http.StatusOK || result["app"] != "ERROR" {
+		t.Fatalf("post: %d %v", code, result)
+	}
+
+	code, result = doLevelRequest(t, http.MethodGet, "/?name=app", "")
+	if code != http.StatusOK || len(result) != 1 || result["app"] != "ERROR" {
+		t.Fatalf("get name: %d %v", code, result)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	w := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, PUT, POST" {
+		t.Fatalf("delete: %d %v", w.Code, w.Header())
+	}
+
+	tests := []struct {
+		method, target, body string
+		code                 int
+	}{
+		{http.MethodGet, "/?name=unknown", "", http.StatusNotFound},
+		{http.MethodPut, "/", `{"name":"unknown","level":"INFO"}`, http.StatusNotFound},
+		{http.MethodPut, "/", `{"name":"app","level":"TRACE"}`, http.StatusBadRequest},
+		{http.MethodPut, "/", `{"name":"app","level":"INFO","duration":"soon"}`, http.StatusBadRequest},
+		{http.MethodPut, "/", `{"level":"INFO"}`, http.StatusBadRequest},
+		{http.MethodPut, "/", `{"name":`, http.StatusBadRequest},
+		{http.MethodDelete, "/", "", http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		if code, result := doLevelRequest(t, test.method, test.target, test.body); code != test.code || result["error"] == "" {
+			t.Errorf("%s %s %s: %d %v", test.method, test.target, test.body, code, result)
+		}
+	}
+	if levelOf(simple) != ERROR {
+		t.Fatalf("level changed by invalid request: %d", levelOf(simple))
+	}
+
+	// 自定义日志没有实现 GetLevel 时级别未知，不能临时修改
+	plain := &plainLogger{NewLogger()}
+	Register("plain", plain)
+	defer Unregister("plain")
+	if code, result = doLevelRequest(t, http.MethodGet, "/?name=plain", ""); code != http.StatusOK || result["plain"] != "UNKNOWN" {
+		t.Fatalf("get plain: %d %v", code, result)
+	}
+	if code, result = doLevelRequest(t, http.MethodPut, "/", `{"name":"plain","level":"DEBUG","duration":"1m"}`); code != http.StatusBadRequest {
+		t.Fatalf("put plain with duration: %d %v", code, result)
+	}
+	if code, result = doLevelRequest(t, http.MethodPut, "/", `{"name":"*","level":"WARN","duration":"1m"}`); code != http.StatusOK ||
+		result["app"] != "WARN" || result["plain"] != "UNKNOWN" {
+		t.Fatalf("put all with duration: %d %v", code, result)
+	}
+	RevertLevels()
+	if code, _ = doLevelRequest(t, http.MethodPut, "/?name=plain&level=debug", ""); code != http.StatusOK || !plain.IsDebugAble() {
+		t.Fatalf("put plain: %d", code)
+	}
+
+	// 注册 nil 时删除，例如 sign.SetLog(nil)
+	Register("plain", nil)
+	if _, exist := Lookup("plain"); exist {
+		t.Fatal("nil logger should not be registered")
+	}
+	if code, result = doLevelRequest(t, http.MethodGet, "/", ""); code != http.StatusOK || len(result) != 2 {
+		t.Fatalf("get after nil: %d %v", code, result)
+	}
+}
+
+func TestSetLevelFor(t *testing.T) {
+	simple, logrus, cleanup := levelTestLoggers(t)
+	defer cleanup()
+
+	if err := SetLevelFor("app", DEBUG, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	// 临时修改期间再次修改，恢复的仍然是最初的级别
+	if err := SetLevelFor("app", WARN, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	code, _ := doLevelRequest(t, http.MethodPut, "/", `{"name":"logrus","level":"DEBUG","duration":"50ms"}`)
+	if code != http.StatusOK || levelOf(simple) != WARN || levelOf(logrus) != DEBUG {
+		t.Fatalf("set: %d %d %d", code, levelOf(simple), levelOf(logrus))
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if levelOf(simple) != INFO || levelOf(logrus) != WARN {
+		t.Fatalf("revert: %d %d", levelOf(simple), levelOf(logrus))
+	}
+
+	// 不指定时间时取消恢复
+	SetLevelFor("app", DEBUG, 50*time.Millisecond)
+	SetLevelFor("app", ERROR, 0)
+	time.Sleep(200 * time.Millisecond)
+	if levelOf(simple) != ERROR {
+		t.Fatalf("cancel revert: %d", levelOf(simple))
+	}
+
+	if err := SetLevelFor("unknown", DEBUG, 0); err != ErrNotRegistered {
+		t.Fatalf("unknown: %v", err)
+	}
+}
+
+func TestDebugOnSignal(t *testing.T) {
+	simple, logrus, cleanup := levelTestLoggers(t)
+	defer cleanup()
+
+	if _, err := DebugOnSignal(time.Minute); err == nil {
+		t.Fatal("empty sig should fail")
+	}
+	stop, err := DebugOnSignal(time.Minute, syscall.SIGUSR2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	waitLevel := func(simpleLevel, logrusLevel int) {
+		for i := 0; i < 100; i++ {
+			if levelOf(simple) == simpleLevel && levelOf(logrus) == logrusLevel {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("want %d %d, got %d %d", simpleLevel, logrusLevel, levelOf(simple), levelOf(logrus))
+	}
+
+	// 第一次切换到 DEBUG，第二次立即恢复
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	waitLevel(DEBUG, DEBUG)
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	waitLevel(INFO, WARN)
+}
@@ -1,5 +1,9 @@
 package logger
 
+import (
+	"context"
+)
+
 // Package 日志接口，自定义日志需要实现本接口
 
 // 日志级别
@@ -26,10 +30,16 @@ type Logger interface {
 	// Fatalf 最终调用 panic
 	Fatalf(format string, v ...interface{})
 
+	// With 返回附带 fields 的子日志，子日志与当前日志共享级别等设置，见 field.go
+	With(fields ...Field) Logger
+
+	// WithContext 返回附带 ctx 中请求 ID、追踪 ID 以及字段的子日志，见 context.go
+	WithContext(ctx context.Context) Logger
+
 	// SetPath 设置日志路径
 	SetPath(path string)
 
-	// SetLevel 设置日志响应级别
+	// SetLevel 设置日志响应级别，可以在运行中修改，见 level.go
 	SetLevel(level int)
 
 	// SetEnable 设置日志是否开启
@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureStdout 返回 fn 输出到终端的内容
+func captureStdout(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	fn()
+	os.Stdout = stdout
+	w.Close()
+
+	out, _ := ioutil.ReadAll(r)
+	return string(out)
+}
+
+func TestSimpleWith(t *testing.T) {
+	log := NewLogger()
+	child := log.With(String("module", "order"), Int("uid", 10001))
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	ctx = ContextWithTraceID(ctx, "trace-1")
+	ctx = ContextWithFields(ctx, Bool("vip", true))
+
+	out := captureStdout(t, func() {
+		child.With(Err(errors.New("connection refused"))).Error("create order failed")
+		child.WithContext(ctx).Infof("cost %s", time.Second)
+		log.Info("no fields")
+	})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output: %q", out)
+	}
+	if !strings.HasSuffix(lines[0], `create order failed module=order uid=10001 error="connection refused"`) {
+		t.Errorf("With: %s", lines[0])
+	}
+	if !strings.Contains(lines[0], "logger_test.go") {
+		t.Errorf("caller: %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "cost 1s module=order uid=10001 request_id=req-1 trace_id=trace-1 vip=true") {
+		t.Errorf("WithContext: %s", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], "no fields") {
+		t.Errorf("parent: %s", lines[2])
+	}
+
+	// 子日志共享级别
+	log.SetLevel(ERROR)
+	if out := captureStdout(t, func() { child.Info("hidden") }); out != "" {
+		t.Errorf("child level: %q", out)
+	}
+}
+
+func TestLogrusWith(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	log, err := NewLogrusLogger("test", dir, true, true, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buff bytes.Buffer
+	log.(*logrusLog).logger.SetOutput(&buff)
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	log.With(String("module", "order")).WithContext(ctx).Info("hello")
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(buff.Bytes(), &data); err != nil {
+		t.Fatalf("output: %s, err: %v", buff.String(), err)
+	}
+	if data["module"] != "order" || data[RequestIDKey] != "req-1" || data["msg"] != "hello" {
+		t.Errorf("fields: %v", data)
+	}
+	if data["file"] != "logger_test.go" {
+		t.Errorf("caller: %v", data["file"])
+	}
+}
@@ -5,9 +5,13 @@ package logger
 
 import (
 	"bufio"
+	"context"
+	"fmt"
 	"os"
 	"path"
 	"runtime"
+	"strings"
+	"sync"
 	"time"
 
 	rotatelogs "github.com/lestrrat/go-file-rotatelogs"
@@ -19,6 +23,19 @@ import (
 
 type logrusLog struct {
 	logger *logrus.Logger
+	// entry 附带 With 设置的字段，子日志共享 logger
+	entry *logrus.Entry
+	// shared 子日志共享的设置
+	shared *logrusShared
+}
+
+// logrusShared 子日志共享的设置
+type logrusShared struct {
+	// caller 是否打印函数信息
+	caller bool
+
+	mu      sync.RWMutex
+	sampler *Sampler
 }
 
 // NewLogrusLogger 生成 logrus 日志实例
@@ -79,9 +96,31 @@ func NewLogrusLogger(logName, logPath string, caller, json bool, maxAge, rotatio
 
 	return &logrusLog{
 		logger: logger,
+		entry:  logrus.NewEntry(logger),
+		shared: &logrusShared{caller: caller},
 	}, nil
 }
 
+// With ..
+func (l *logrusLog) With(fields ...Field) Logger {
+	data := make(logrus.Fields, len(fields))
+	for _, field := range fields {
+		data[field.Key] = field.Value
+	}
+	return &logrusLog{
+		logger: l.logger,
+		entry:  l.entry.WithFields(data),
+		shared: l.shared,
+	}
+}
+
+// WithContext ..
+func (l *logrusLog) WithContext(ctx context.Context) Logger {
+	child := l.With(FieldsFromContext(ctx)...).(*logrusLog)
+	child.entry = child.entry.WithContext(ctx)
+	return child
+}
+
 type callerHook struct{}
 
 func newCallerHook() *callerHook {
@@ -92,16 +131,31 @@ func (hook *callerHook) Levels() []logrus.Level {
 	return logrus.AllLevels
 }
 
+// Fire 跳过 logrus 以及本包的函数，找到实际调用日志的位置
 func (hook *callerHook) Fire(entry *logrus.Entry) error {
-	pc, file, line, ok := runtime.Caller(9)
-	if !ok {
-		file = "unknown???"
-		line = 0
+	entry.Data["pid"] = os.Getpid()
+	// 采样的汇总已经设置了位置
+	if _, exist := entry.Data["file"]; exist {
+		return nil
+	}
+
+	file, line, funcName := "unknown???", 0, ""
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		inLogger := strings.HasPrefix(frame.Function, "github.com/alex-my/ghelper/logger.") &&
+			!strings.HasSuffix(frame.File, "_test.go")
+		if !inLogger && !strings.HasPrefix(frame.Function, "github.com/sirupsen/logrus.") {
+			file, line, funcName = frame.File, frame.Line, frame.Function
+			break
+		}
+		if !more {
+			break
+		}
 	}
 	_, filename := path.Split(file)
-	funcName := runtime.FuncForPC(pc).Name()
 
-	entry.Data["pid"] = os.Getpid()
 	entry.Data["file"] = filename
 	entry.Data["line"] = line
 	entry.Data["func"] = funcName
@@ -110,52 +164,68 @@ func (hook *callerHook) Fire(entry *logrus.Entry) error {
 
 // Debug ..
 func (l *logrusLog) Debug(v ...interface{}) {
-	l.logger.Debugln(v...)
+	if l.sampled(DEBUG, "", v) {
+		l.entry.Debugln(v...)
+	}
 }
 
 // Debugf ..
 func (l *logrusLog) Debugf(format string, v ...interface{}) {
-	l.logger.Debugf(format, v...)
+	if l.sampled(DEBUG, format, v) {
+		l.entry.Debugf(format, v...)
+	}
 }
 
 // Info ..
 func (l *logrusLog) Info(v ...interface{}) {
-	l.logger.Infoln(v...)
+	if l.sampled(INFO, "", v) {
+		l.entry.Infoln(v...)
+	}
 }
 
 // Infof ..
 func (l *logrusLog) Infof(format string, v ...interface{}) {
-	l.logger.Infof(format, v...)
+	if l.sampled(INFO, format, v) {
+		l.entry.Infof(format, v...)
+	}
 }
 
 // Warn ..
 func (l *logrusLog) Warn(v ...interface{}) {
-	l.logger.Warnln(v...)
+	if l.sampled(WARN, "", v) {
+		l.entry.Warnln(v...)
+	}
 }
 
 // Warnf ..
 func (l *logrusLog) Warnf(format string, v ...interface{}) {
-	l.logger.Warnf(format, v...)
+	if l.sampled(WARN, format, v) {
+		l.entry.Warnf(format, v...)
+	}
 }
 
 // Error ..
 func (l *logrusLog) Error(v ...interface{}) {
-	l.logger.Errorln(v...)
+	if l.sampled(ERROR, "", v) {
+		l.entry.Errorln(v...)
+	}
 }
 
 // Errorf ..
 func (l *logrusLog) Errorf(format string, v ...interface{}) {
-	l.logger.Errorf(format, v...)
+	if l.sampled(ERROR, format, v) {
+		l.entry.Errorf(format, v...)
+	}
 }
 
 // Fatal ..
 func (l *logrusLog) Fatal(v ...interface{}) {
-	l.logger.Fatalln(v...)
+	l.entry.Fatalln(v...)
 }
 
 // Fatalf ..
 func (l *logrusLog) Fatalf(format string, v ...interface{}) {
-	l.logger.Fatalf(format, v...)
+	l.entry.Fatalf(format, v...)
 }
 
 // SetEnable 设置日志是否开启
@@ -171,6 +241,25 @@ func (l *logrusLog) SetPath(path string) {
 
 // SetLevel 设置日志响应级别
 func (l *logrusLog) SetLevel(level int) {
+	l.logger.SetLevel(toLogrusLevel(level))
+}
+
+// GetLevel ..
+func (l *logrusLog) GetLevel() int {
+	switch l.logger.GetLevel() {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return FATAL
+	case logrus.ErrorLevel:
+		return ERROR
+	case logrus.WarnLevel:
+		return WARN
+	case logrus.InfoLevel:
+		return INFO
+	}
+	return DEBUG
+}
+
+func toLogrusLevel(level int) logrus.Level {
 	logrusLevel := logrus.DebugLevel
 	switch level {
 	case INFO:
@@ -182,7 +271,57 @@ func (l *logrusLog) SetLevel(level int) {
 	case FATAL:
 		logrusLevel = logrus.FatalLevel
 	}
-	l.logger.SetLevel(logrusLevel)
+	return logrusLevel
+}
+
+// SetSampler 设置采样，s 为 nil 时关闭，见 sample.go
+func (l *logrusLog) SetSampler(s *Sampler) {
+	l.shared.mu.Lock()
+	l.shared.sampler = s
+	l.shared.mu.Unlock()
+}
+
+// sampled 没有设置采样时返回 true，需要在 Debug 等方法中直接调用
+// format 为空时与 Debugln 等相同，使用空格连接 v
+func (l *logrusLog) sampled(level int, format string, v []interface{}) bool {
+	l.shared.mu.RLock()
+	sampler := l.shared.sampler
+	l.shared.mu.RUnlock()
+	if sampler == nil {
+		return true
+	}
+
+	logrusLevel := toLogrusLevel(level)
+	if !l.logger.IsLevelEnabled(logrusLevel) {
+		return false
+	}
+	var message string
+	if format == "" {
+		message = strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+	} else {
+		message = fmt.Sprintf(format, v...)
+	}
+
+	pc, file, line, _ := runtime.Caller(2)
+	_, filename := path.Split(file)
+	funcName := runtime.FuncForPC(pc).Name()
+
+	return sampler.allow(level, filename, line, message, func(summary string) {
+		l.logEntry(&Entry{Time: time.Now(), Level: level, Message: summary, File: filename, Line: line, Func: funcName})
+	})
+}
+
+// logEntry 写入其它来源的日志，例如 slog，保留 e 中的位置，FATAL 级别不会退出
+func (l *logrusLog) logEntry(e *Entry) {
+	entry := l.entry
+	if l.shared.caller {
+		entry = entry.WithFields(logrus.Fields{
+			"file": e.File,
+			"line": e.Line,
+			"func": e.Func,
+		})
+	}
+	entry.WithTime(e.Time).Log(toLogrusLevel(e.Level), e.Message)
 }
 
 // SetConsoleEnable 是否开启控制台日志
@@ -199,14 +338,15 @@ func (l *logrusLog) SetConsoleEnable(able bool) {
 	}
 }
 
+// logrus 中级别越高输出越多，例如 DebugLevel > InfoLevel
 func (l *logrusLog) IsDebugAble() bool {
-	return logrus.DebugLevel == l.logger.GetLevel()
+	return l.logger.IsLevelEnabled(logrus.DebugLevel)
 }
 
 func (l *logrusLog) IsInfoAble() bool {
-	return l.logger.GetLevel() <= logrus.InfoLevel
+	return l.logger.IsLevelEnabled(logrus.InfoLevel)
 }
 
 func (l *logrusLog) IsWarnAble() bool {
-	return l.logger.GetLevel() <= logrus.WarnLevel
+	return l.logger.IsLevelEnabled(logrus.WarnLevel)
 }
@@ -0,0 +1,384 @@
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alex-my/ghelper/compress"
+)
+
+// eg:
+// 按大小以及时间切割，保留 7 个备份，备份使用 gzip 压缩
+// log, err := logger.NewFileLogger("./logs/app.log",
+// 	logger.RotateMaxSize(100*1024*1024),
+// 	logger.RotateInterval(24*time.Hour),
+// 	logger.RotateMaxBackups(7),
+// 	logger.RotateCompress(true),
+// )
+//
+// 退出时关闭文件
+// defer log.(io.Closer).Close()
+//
+// 当前日志写入 ./logs/app.log，切割之后的备份为 ./logs/app.20190418-170147.000.log.gz
+// 收到 SIGHUP 时重新打开日志文件，配合 logrotate 等外部工具使用
+
+const (
+	// backupTimeFormat 备份文件名称中的时间
+	backupTimeFormat = "20060102-150405.000"
+	compressSuffix   = ".gz"
+)
+
+// rotateConfig 切割的参数
+type rotateConfig struct {
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+	signals    []os.Signal
+}
+
+// RotateOption ..
+type RotateOption func(*rotateConfig)
+
+// RotateMaxSize 文件达到 size 字节时切割，0 表示不按大小切割，默认 100MB
+func RotateMaxSize(size int64) RotateOption {
+	return func(c *rotateConfig) {
+		c.maxSize = size
+	}
+}
+
+// RotateInterval 按时间切割，例如 24*time.Hour 每天 0 点切割，0 表示不按时间切割，默认 24 小时
+func RotateInterval(interval time.Duration) RotateOption {
+	return func(c *rotateConfig) {
+		c.interval = interval
+	}
+}
+
+// RotateMaxBackups 最多保留的备份数量，0 表示不限制
+func RotateMaxBackups(n int) RotateOption {
+	return func(c *rotateConfig) {
+		c.maxBackups = n
+	}
+}
+
+// RotateMaxAge 备份保留的时间，0 表示不限制
+func RotateMaxAge(age time.Duration) RotateOption {
+	return func(c *rotateConfig) {
+		c.maxAge = age
+	}
+}
+
+// RotateCompress 是否使用 gzip 压缩备份
+func RotateCompress(able bool) RotateOption {
+	return func(c *rotateConfig) {
+		c.compress = able
+	}
+}
+
+// RotateReopenSignal 收到 sig 时重新打开日志文件，默认 SIGHUP，不指定 sig 时不监听信号
+func RotateReopenSignal(sig ...os.Signal) RotateOption {
+	return func(c *rotateConfig) {
+		c.signals = sig
+	}
+}
+
+func defaultRotateConfig() *rotateConfig {
+	return &rotateConfig{
+		maxSize:  100 * 1024 * 1024,
+		interval: 24 * time.Hour,
+		signals:  []os.Signal{syscall.SIGHUP},
+	}
+}
+
+// RotateWriter 可以切割的日志文件，可以同时在多个 goroutine 中使用
+type RotateWriter struct {
+	path string
+	conf *rotateConfig
+	now  func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	// next 下一次按时间切割的时间
+	next time.Time
+
+	// mill 压缩以及清理备份
+	mill    chan struct{}
+	millWg  sync.WaitGroup
+	signals chan os.Signal
+	closed  bool
+}
+
+// NewRotateWriter 打开 path，文件夹不存在时创建
+func NewRotateWriter(path string, opts ...RotateOption) (*RotateWriter, error) {
+	conf := defaultRotateConfig()
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	w := &RotateWriter{
+		path: path,
+		conf: conf,
+		now:  time.Now,
+		mill: make(chan struct{}, 1),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	w.millWg.Add(1)
+	go w.millRun()
+
+	if len(conf.signals) > 0 {
+		w.signals = make(chan os.Signal, 1)
+		signal.Notify(w.signals, conf.signals...)
+		go func(signals chan os.Signal) {
+			for range signals {
+				w.Reopen()
+			}
+		}(w.signals)
+	}
+	return w, nil
+}
+
+// Write 写入日志，需要时先切割
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	now := w.now()
+	if (w.conf.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.conf.maxSize) ||
+		(w.conf.interval > 0 && !now.Before(w.next)) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate 立即切割
+func (w *RotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate(w.now())
+}
+
+// Reopen 关闭之后重新打开日志文件，文件被外部工具移动之后，会创建新的文件
+func (w *RotateWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+	return w.open()
+}
+
+// Sync 将文件的内容写入磁盘
+func (w *RotateWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close 关闭日志文件，等待正在进行的压缩以及清理完成
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	if w.signals != nil {
+		signal.Stop(w.signals)
+		close(w.signals)
+	}
+	close(w.mill)
+
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.mu.Unlock()
+
+	w.millWg.Wait()
+	return err
+}
+
+// open 以追加的方式打开日志文件，需要持有 mu
+func (w *RotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.next = w.nextRotateTime(w.now())
+	return nil
+}
+
+// rotate 将当前文件重命名为备份，然后打开新的文件，需要持有 mu
+func (w *RotateWriter) rotate(now time.Time) error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+
+	if w.size > 0 {
+		if err := os.Rename(w.path, w.backupName(now)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	select {
+	case w.mill <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// nextRotateTime 按照本地时间对齐，例如 interval 为 24 小时时，为下一个 0 点
+func (w *RotateWriter) nextRotateTime(now time.Time) time.Time {
+	if w.conf.interval <= 0 {
+		return time.Time{}
+	}
+	_, offset := now.Zone()
+	zone := time.Duration(offset) * time.Second
+	return now.Add(zone).Truncate(w.conf.interval).Add(w.conf.interval).Add(-zone)
+}
+
+// prefixAndExt app.log 的前缀为 app.，后缀为 .log
+func (w *RotateWriter) prefixAndExt() (string, string) {
+	filename := filepath.Base(w.path)
+	ext := filepath.Ext(filename)
+	return filename[:len(filename)-len(ext)] + ".", ext
+}
+
+func (w *RotateWriter) backupName(t time.Time) string {
+	prefix, ext := w.prefixAndExt()
+	name := filepath.Join(filepath.Dir(w.path), prefix+t.Format(backupTimeFormat)+ext)
+	// 同一毫秒内切割多次
+	for i := 1; fileExist(name) || fileExist(name+compressSuffix); i++ {
+		name = filepath.Join(filepath.Dir(w.path), prefix+t.Add(time.Duration(i)*time.Millisecond).Format(backupTimeFormat)+ext)
+	}
+	return name
+}
+
+func fileExist(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// backupFile 备份文件
+type backupFile struct {
+	path string
+	t    time.Time
+}
+
+// backups 所有备份，从新到旧排列
+func (w *RotateWriter) backups() ([]backupFile, error) {
+	dir := filepath.Dir(w.path)
+	prefix, ext := w.prefixAndExt()
+
+	file, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	names, err := file.Readdirnames(-1)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backupFile
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimSuffix(name[len(prefix):], compressSuffix), ext)
+		t, err := time.ParseInLocation(backupTimeFormat, ts, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), t: t})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].t.After(backups[j].t)
+	})
+	return backups, nil
+}
+
+// millRun 切割之后在后台压缩以及清理备份，不阻塞写日志
+func (w *RotateWriter) millRun() {
+	defer w.millWg.Done()
+	for range w.mill {
+		w.millOnce()
+	}
+}
+
+func (w *RotateWriter) millOnce() {
+	backups, err := w.backups()
+	if err != nil {
+		return
+	}
+
+	cutoff := w.now().Add(-w.conf.maxAge)
+	for i, backup := range backups {
+		if (w.conf.maxBackups > 0 && i >= w.conf.maxBackups) ||
+			(w.conf.maxAge > 0 && backup.t.Before(cutoff)) {
+			os.Remove(backup.path)
+			continue
+		}
+		if w.conf.compress && !strings.HasSuffix(backup.path, compressSuffix) {
+			if err := compress.GzipFile(backup.path, backup.path+compressSuffix); err == nil {
+				os.Remove(backup.path)
+			}
+		}
+	}
+}
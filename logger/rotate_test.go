@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alex-my/ghelper/compress"
+)
+
+func listDir(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotateSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	w, err := NewRotateWriter(path, RotateMaxSize(10), RotateInterval(0), RotateMaxBackups(2), RotateCompress(true), RotateReopenSignal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 后台清理备份时也会调用 now
+	var mu sync.Mutex
+	now := time.Date(2019, 4, 18, 17, 1, 47, 0, time.Local)
+	w.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(time.Second)
+		return now
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names := listDir(t, dir)
+	if len(names) != 3 || names[2] != "app.log" {
+		t.Fatalf("files: %v", names)
+	}
+	for _, name := range names[:2] {
+		if !strings.HasPrefix(name, "app.20190418-1701") || !strings.HasSuffix(name, ".log.gz") {
+			t.Fatalf("backup: %s", name)
+		}
+	}
+	content, _ := ioutil.ReadFile(filepath.Join(dir, names[1]))
+	if plain, err := compress.GzipUncompress(content); err != nil || string(plain) != "third\n" {
+		t.Fatalf("backup content: %q, err: %v", plain, err)
+	}
+	if content, _ := ioutil.ReadFile(path); string(content) != "fourth\n" {
+		t.Fatalf("current content: %q", content)
+	}
+}
+
+func TestRotateInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2019, 4, 18, 23, 59, 0, 0, time.Local)
+	w, err := NewRotateWriter(path, RotateMaxSize(0), RotateReopenSignal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 后台清理备份时也会调用 now
+	var mu sync.Mutex
+	w.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	w.next = w.nextRotateTime(now)
+	if expect := time.Date(2019, 4, 19, 0, 0, 0, 0, time.Local); !w.next.Equal(expect) {
+		t.Fatalf("next: %v", w.next)
+	}
+
+	w.Write([]byte("day 1\n"))
+	mu.Lock()
+	now = now.Add(2 * time.Minute)
+	mu.Unlock()
+	w.Write([]byte("day 2\n"))
+
+	// 外部工具移动文件之后重新打开
+	os.Rename(path, filepath.Join(dir, "moved.log"))
+	if err := w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("reopened\n"))
+	w.Close()
+
+	names := listDir(t, dir)
+	if len(names) != 3 || names[0] != "app.20190419-000100.000.log" {
+		t.Fatalf("files: %v", names)
+	}
+	if content, _ := ioutil.ReadFile(path); string(content) != "reopened\n" {
+		t.Fatalf("current content: %q", content)
+	}
+	if _, err := w.Write([]byte("closed")); err != os.ErrClosed {
+		t.Fatalf("write after close, err: %v", err)
+	}
+}
+
+func TestFileLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logs", "app.log")
+	log, err := NewFileLogger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.SetLevel(INFO)
+	log.Debug("hidden")
+	log.With(String("module", "order")).Info("hello")
+	log.(*simpleLog).Close()
+
+	content, _ := ioutil.ReadFile(path)
+	if !strings.HasSuffix(string(content), "[INFO] hello module=order\n") || strings.Contains(string(content), "\x1b[") {
+		t.Fatalf("content: %q", content)
+	}
+	if strings.Count(string(content), "\n") != 1 {
+		t.Fatalf("content: %q", content)
+	}
+}
@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// eg:
+// 每秒内相同的日志 (级别、位置以及内容相同) 输出前 10 条，之后每 100 条输出 1 条
+// logger.SetSampler(log, logger.NewSampler(logger.SampleFirst(10), logger.SampleThereafter(100)))
+//
+// 去重，每秒内相同位置的日志只输出 1 条，不比较日志内容
+// logger.SetSampler(log, logger.NewSampler(logger.SampleFirst(1), logger.SampleThereafter(0), logger.SampleByCaller()))
+//
+// 被丢弃的日志在每个周期结束时输出一条汇总，级别以及位置与被丢弃的日志相同
+// [2019-04-18 17:01:48.000][25432][order.go:97-order.Create][ERROR] suppressed 1234 messages: connect to db failed
+//
+// 关闭
+// logger.SetSampler(log, nil)
+
+// SetSampler 设置采样，相同的日志过多时丢弃，并定期输出汇总，s 为 nil 时关闭
+// 支持 NewLogger, NewLogrusLogger 等本包创建的日志，l 不支持采样时返回 false
+func SetSampler(l Logger, s *Sampler) bool {
+	sl, ok := l.(interface{ SetSampler(s *Sampler) })
+	if ok {
+		sl.SetSampler(s)
+	}
+	return ok
+}
+
+// sampleConfig 采样的参数
+type sampleConfig struct {
+	interval   time.Duration
+	first      int64
+	thereafter int64
+	byCaller   bool
+}
+
+// SampleOption ..
+type SampleOption func(*sampleConfig)
+
+// SampleInterval 统计的周期，默认 1 秒
+func SampleInterval(interval time.Duration) SampleOption {
+	return func(c *sampleConfig) {
+		if interval > 0 {
+			c.interval = interval
+		}
+	}
+}
+
+// SampleFirst 每个周期内相同的日志输出前 n 条，默认 100
+func SampleFirst(n int) SampleOption {
+	return func(c *sampleConfig) {
+		c.first = int64(n)
+	}
+}
+
+// SampleThereafter 超过 SampleFirst 之后每 m 条输出 1 条，0 表示全部丢弃，默认 100
+func SampleThereafter(m int) SampleOption {
+	return func(c *sampleConfig) {
+		c.thereafter = int64(m)
+	}
+}
+
+// SampleByCaller 只根据级别以及位置判断是否为相同的日志，不比较日志内容，例如 Errorf 中的参数不同
+func SampleByCaller() SampleOption {
+	return func(c *sampleConfig) {
+		c.byCaller = true
+	}
+}
+
+// sampleState 一种日志在当前周期内的统计
+type sampleState struct {
+	start      time.Time
+	count      int64
+	suppressed int64
+	// summary 输出汇总，使用最近一条被丢弃的日志
+	summary func(message string)
+	message string
+}
+
+// Sampler 日志采样，FATAL 级别的日志不会被丢弃，可以同时在多个日志实例中使用
+type Sampler struct {
+	conf *sampleConfig
+	now  func() time.Time
+
+	mu      sync.Mutex
+	states  map[string]*sampleState
+	running bool
+}
+
+// NewSampler ..
+func NewSampler(opts ...SampleOption) *Sampler {
+	conf := &sampleConfig{interval: time.Second, first: 100, thereafter: 100}
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return &Sampler{
+		conf:   conf,
+		now:    time.Now,
+		states: make(map[string]*sampleState),
+	}
+}
+
+// key 相同的日志使用相同的 key
+func (s *Sampler) key(level int, file string, line int, message string) string {
+	key := strconv.Itoa(level) + ":" + file + ":" + strconv.Itoa(line)
+	if !s.conf.byCaller {
+		key += ":" + message
+	}
+	return key
+}
+
+// allow 是否输出日志，不输出时记录 summary，周期结束时调用
+func (s *Sampler) allow(level int, file string, line int, message string, summary func(message string)) bool {
+	if level >= FATAL {
+		return true
+	}
+	key := s.key(level, file, line, message)
+	now := s.now()
+
+	s.mu.Lock()
+	st := s.states[key]
+	var expired *sampleState
+	if st == nil || now.Sub(st.start) >= s.conf.interval {
+		expired = st
+		st = &sampleState{start: now}
+		s.states[key] = st
+		// 定期清理过期的统计
+		if !s.running {
+			s.running = true
+			go s.run()
+		}
+	}
+	st.count++
+	ok := st.count <= s.conf.first ||
+		(s.conf.thereafter > 0 && (st.count-s.conf.first)%s.conf.thereafter == 0)
+	if !ok {
+		st.suppressed++
+		st.summary = summary
+		st.message = message
+	}
+	s.mu.Unlock()
+
+	if expired != nil {
+		expired.flush()
+	}
+	return ok
+}
+
+// run 定期输出已经结束的周期的汇总，并清理统计，没有需要统计的日志时退出
+func (s *Sampler) run() {
+	ticker := time.NewTicker(s.conf.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !s.tick() {
+			return
+		}
+	}
+}
+
+// tick 输出汇总，返回 false 表示没有需要统计的日志
+func (s *Sampler) tick() bool {
+	now := s.now()
+
+	s.mu.Lock()
+	var expired []*sampleState
+	for key, st := range s.states {
+		if now.Sub(st.start) >= s.conf.interval {
+			expired = append(expired, st)
+			delete(s.states, key)
+		}
+	}
+	running := len(s.states) > 0
+	s.running = running
+	s.mu.Unlock()
+
+	for _, st := range expired {
+		st.flush()
+	}
+	return running
+}
+
+// Flush 立即输出所有汇总，例如退出之前
+func (s *Sampler) Flush() {
+	s.mu.Lock()
+	states := s.states
+	s.states = make(map[string]*sampleState)
+	s.mu.Unlock()
+
+	for _, st := range states {
+		st.flush()
+	}
+}
+
+func (st *sampleState) flush() {
+	if st.suppressed > 0 && st.summary != nil {
+		st.summary(fmt.Sprintf("suppressed %d messages: %s", st.suppressed, st.message))
+	}
+}
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	w := newGateWriter()
+	close(w.gate)
+	log := NewWriterLogger(w)
+
+	now := time.Date(2019, 4, 18, 17, 1, 47, 0, time.Local)
+	sampler := NewSampler(SampleFirst(10), SampleThereafter(100))
+	sampler.now = func() time.Time { return now }
+	SetSampler(log, sampler)
+
+	for i := 0; i < 250; i++ {
+		log.Errorf("connect to db failed")
+	}
+	log.Info("other")
+	if count := strings.Count(w.String(), "connect to db failed\n"); count != 12 {
+		t.Fatalf("sampled count: %d", count)
+	}
+
+	// 周期结束时输出汇总
+	now = now.Add(time.Second)
+	sampler.tick()
+	lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+	if last := lines[len(lines)-1]; !strings.HasSuffix(last, "[ERROR] suppressed 238 messages: connect to db failed") ||
+		!strings.Contains(last, "sample_test.go:23") {
+		t.Fatalf("summary: %s", last)
+	}
+
+	// 只比较位置，定时输出汇总
+	w.mu.Lock()
+	w.buff.Reset()
+	w.mu.Unlock()
+	dedup := NewSampler(SampleFirst(1), SampleThereafter(0), SampleByCaller(), SampleInterval(10*time.Millisecond))
+	SetSampler(log, dedup)
+	for i := 0; i < 5; i++ {
+		log.Warnf("request %d timeout", i)
+	}
+	for deadline := time.Now().Add(3 * time.Second); !strings.Contains(w.String(), "suppressed") && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if out := w.String(); strings.Count(out, "\n") != 2 || !strings.Contains(out, "request 0 timeout") ||
+		!strings.Contains(out, "suppressed 4 messages: request 4 timeout") {
+		t.Fatalf("dedup: %q", out)
+	}
+}
+
+func TestSamplerLogrus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	log, err := NewLogrusLogger("test", dir, true, false, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buff bytes.Buffer
+	log.(*logrusLog).logger.SetOutput(&buff)
+
+	sampler := NewSampler(SampleFirst(2), SampleThereafter(0))
+	SetSampler(log, sampler)
+	for i := 0; i < 5; i++ {
+		log.Error("flapping", "downstream")
+	}
+	sampler.Flush()
+
+	out := buff.String()
+	if strings.Count(out, "flapping downstream") != 3 || !strings.Contains(out, `msg="suppressed 3 messages: flapping downstream"`) ||
+		strings.Count(out, "file=sample_test.go") != 3 {
+		t.Fatalf("output: %s", out)
+	}
+}
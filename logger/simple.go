@@ -1,19 +1,20 @@
 package logger
 
-// 简易日志，只输出到终端
-// 带日志定位，时间，颜色
+// 简易日志，输出到终端以及文件
+// 带日志定位，时间，颜色，文件中不带颜色，文件切割见 rotate.go，多个输出目标见 sink.go
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"runtime"
-	"strconv"
 	"strings"
 	"sync"
-
-	"github.com/alex-my/ghelper/time"
+	"sync/atomic"
+	"time"
 )
 
 // eg:
@@ -67,19 +68,63 @@ const (
 
 // simpleLog 简单日志，输出到终端
 type simpleLog struct {
+	*simpleCore
+
+	// fields With 附加的字段，输出在日志内容之后
+	fields []Field
+}
+
+// simpleCore 设置，子日志与父日志共享
+type simpleCore struct {
 	// able true: 开启日志，false 关闭日志
 	able bool
 
 	// console true: 开启终端日志，false 关闭终端日志
 	console bool
 
-	// level 日志级别
-	level int
+	// level 日志级别，使用 atomic 读写，可以在运行中修改，见 level.go
+	level int32
+
+	// mu 保护 sinks, pathSink 以及 sampler
+	mu sync.RWMutex
+	// sampler 采样，见 sample.go
+	sampler *Sampler
+	// sinks 输出目标，见 sink.go
+	sinks []*Sink
+	// pathSink SetPath 设置的文件
+	pathSink *Sink
 }
 
 // NewLogger ..
 func NewLogger() Logger {
-	return &simpleLog{able: true, console: true}
+	return NewMultiLogger(ConsoleSink(DEBUG))
+}
+
+// NewFileLogger 生成写入文件的日志实例，默认不输出到终端，可以使用 SetConsoleEnable 开启
+// path: 日志文件路径，文件夹不存在时创建
+// opts: 切割的参数，见 rotate.go
+func NewFileLogger(path string, opts ...RotateOption) (Logger, error) {
+	writer, err := NewRotateWriter(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterLogger(writer), nil
+}
+
+// NewWriterLogger 生成写入 w 的日志实例，不带颜色，默认不输出到终端
+// 例如使用 AsyncWriter 在后台写入文件，见 async.go
+func NewWriterLogger(w io.Writer) Logger {
+	return NewMultiLogger(NewSink(w, DEBUG, &TextFormatter{}))
+}
+
+// With ..
+func (l *simpleLog) With(fields ...Field) Logger {
+	return &simpleLog{simpleCore: l.simpleCore, fields: appendFields(l.fields, fields...)}
+}
+
+// WithContext ..
+func (l *simpleLog) WithContext(ctx context.Context) Logger {
+	return l.With(FieldsFromContext(ctx)...)
 }
 
 // Debug ..
@@ -134,14 +179,101 @@ func (l *simpleLog) Fatalf(format string, v ...interface{}) {
 	panic("")
 }
 
-// SetPath ..
+// SetPath 设置日志文件路径，使用默认的切割参数，已经设置过时关闭之前的文件，打开失败时 panic
 func (l *simpleLog) SetPath(path string) {
+	writer, err := NewRotateWriter(path)
+	if err != nil {
+		panic(err.Error())
+	}
+	sink := NewSink(writer, DEBUG, &TextFormatter{})
+
+	l.mu.Lock()
+	old := l.pathSink
+	sinks := make([]*Sink, 0, len(l.sinks)+1)
+	for _, s := range l.sinks {
+		if s != old {
+			sinks = append(sinks, s)
+		}
+	}
+	l.sinks = append(sinks, sink)
+	l.pathSink = sink
+	l.mu.Unlock()
+
+	if old != nil {
+		closeWriter(old.writer)
+	}
+}
 
+// SetSampler ..
+func (l *simpleLog) SetSampler(s *Sampler) {
+	l.mu.Lock()
+	l.sampler = s
+	l.mu.Unlock()
+}
+
+// AddSink 添加输出目标
+func (l *simpleLog) AddSink(sink *Sink) {
+	l.mu.Lock()
+	sinks := make([]*Sink, 0, len(l.sinks)+1)
+	l.sinks = append(append(sinks, l.sinks...), sink)
+	l.mu.Unlock()
+}
+
+// Flush 输出目标实现了 Flush 或者 Sync 时调用，例如 AsyncWriter
+func (l *simpleLog) Flush() error {
+	var err error
+	for _, sink := range l.getSinks() {
+		if sink.console {
+			continue
+		}
+		if syncErr := syncWriter(sink.writer); err == nil {
+			err = syncErr
+		}
+	}
+	return err
+}
+
+// Close 关闭所有实现了 io.Closer 的输出目标，终端除外
+func (l *simpleLog) Close() error {
+	l.mu.Lock()
+	sinks := l.sinks
+	l.sinks = nil
+	l.pathSink = nil
+	l.mu.Unlock()
+
+	var err error
+	for _, sink := range sinks {
+		if sink.console {
+			continue
+		}
+		if closeErr := closeWriter(sink.writer); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (l *simpleLog) getSinks() []*Sink {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.sinks
+}
+
+func closeWriter(w io.Writer) error {
+	if closer, ok := w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
 }
 
 // SetLevel ..
 func (l *simpleLog) SetLevel(level int) {
-	l.level = level
+	atomic.StoreInt32(&l.level, int32(level))
+}
+
+// GetLevel ..
+func (l *simpleLog) GetLevel() int {
+	return int(atomic.LoadInt32(&l.level))
 }
 
 // SetEnable 设置日志是否开启
@@ -152,21 +284,19 @@ func (l *simpleLog) SetEnable(able bool) {
 
 // SetConsoleEnable ..
 func (l *simpleLog) SetConsoleEnable(able bool) {
-	// 本日志仅输出到终端
-	l.able = able
 	l.console = able
 }
 
 func (l *simpleLog) IsDebugAble() bool {
-	return DEBUG == l.level
+	return DEBUG == l.GetLevel()
 }
 
 func (l *simpleLog) IsInfoAble() bool {
-	return l.level <= INFO
+	return l.GetLevel() <= INFO
 }
 
 func (l *simpleLog) IsWarnAble() bool {
-	return l.level <= WARN
+	return l.GetLevel() <= WARN
 }
 
 func levelName(level int) string {
@@ -223,7 +353,8 @@ func ReleaseBuffer(buff *bytes.Buffer) {
 	bufferPool.Put(buff)
 }
 
-func runtimeMessage(level int) string {
+// runtimeEntry 生成日志，包括时间、进程号、文件名称、函数名称，需要在 log, logf 中直接调用
+func runtimeEntry(level int, message string) *Entry {
 	pc, file, line, ok := runtime.Caller(3)
 	if !ok {
 		file = "unknown???"
@@ -232,44 +363,61 @@ func runtimeMessage(level int) string {
 	_, filename := path.Split(file)
 
 	funcName := strings.Split(runtime.FuncForPC(pc).Name(), "/")
-	pid := os.Getpid()
-
-	buff := Buffer()
-	defer ReleaseBuffer(buff)
-
-	buff.WriteString("[")
-	buff.WriteString(time.Date(time.YMDHMSM))
-	buff.WriteString("][")
-	buff.WriteString(strconv.FormatInt(int64(pid), 10))
-	buff.WriteString("][")
-	buff.WriteString(filename)
-	buff.WriteString(":")
-	buff.WriteString(strconv.FormatInt(int64(line), 10))
-	buff.WriteString("-")
-	buff.WriteString(funcName[len(funcName)-1])
-	buff.WriteString("]")
 
-	buff.WriteString("\x1b[")
-	buff.WriteString(strconv.Itoa(levelColor(level)))
-	buff.WriteString(";1m[")
+	return &Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: message,
+		Pid:     os.Getpid(),
+		File:    filename,
+		Line:    line,
+		Func:    funcName[len(funcName)-1],
+	}
+}
 
-	buff.WriteString(levelName(level))
-	buff.WriteString("]")
+func (l *simpleLog) log(level int, v ...interface{}) {
+	if l.able && level >= l.GetLevel() {
+		l.output(runtimeEntry(level, fmt.Sprint(v...)))
+	}
+}
 
-	buff.WriteString("\x1b[39;22m")
+func (l *simpleLog) logf(level int, format string, v ...interface{}) {
+	if l.able && level >= l.GetLevel() {
+		l.output(runtimeEntry(level, fmt.Sprintf(format, v...)))
+	}
+}
 
-	return buff.String()
+// logEntry 写入其它来源的日志，例如 slog，保留 e 中的位置
+func (l *simpleLog) logEntry(e *Entry) {
+	if l.able && e.Level >= l.GetLevel() {
+		l.output(e)
+	}
 }
 
-func (l *simpleLog) log(level int, v ...interface{}) {
-	if l.able && l.console && level >= l.level {
-		fmt.Println(runtimeMessage(level), fmt.Sprint(v...))
+// output 采样之后写入所有输出目标
+func (l *simpleLog) output(e *Entry) {
+	e.Fields = l.fields
+
+	l.mu.RLock()
+	sampler := l.sampler
+	l.mu.RUnlock()
+	if sampler != nil && !sampler.allow(e.Level, e.File, e.Line, e.Message, func(summary string) {
+		e.Time = time.Now()
+		e.Message = summary
+		l.write(e)
+	}) {
+		return
 	}
+	l.write(e)
 }
 
-func (l *simpleLog) logf(level int, format string, v ...interface{}) {
-	if l.able && l.console && level >= l.level {
-		fmt.Println(runtimeMessage(level), fmt.Sprintf(format, v...))
+// write 写入所有输出目标
+func (l *simpleLog) write(e *Entry) {
+	for _, sink := range l.getSinks() {
+		if sink.console && !l.console {
+			continue
+		}
+		sink.write(e)
 	}
 }
 
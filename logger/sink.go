@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// eg:
+// 终端输出 DEBUG 及以上，文件中为 JSON 格式，只记录 INFO 及以上，ERROR 及以上发送到 syslog
+// file, err := logger.FileSink("./logs/app.log", logger.INFO, logger.RotateMaxBackups(7))
+// if err != nil {
+// 	return err
+// }
+// syslog := logger.NetSink("udp", "127.0.0.1:514", logger.ERROR, &logger.SyslogFormatter{Tag: "app"})
+//
+// log := logger.NewMultiLogger(logger.ConsoleSink(logger.DEBUG), file, syslog)
+// log.With(logger.String("module", "order")).Info("create order")
+//
+// 修改某个输出目标的级别
+// file.SetLevel(logger.WARN)
+
+// Sink 日志的输出目标，每个 Sink 有独立的级别以及格式
+type Sink struct {
+	level     int32
+	writer    io.Writer
+	formatter Formatter
+	// console 是否为终端，受 SetConsoleEnable 控制
+	console bool
+}
+
+// NewSink 输出到 w，level 以下的日志不会输出，f 为 nil 时使用 TextFormatter
+func NewSink(w io.Writer, level int, f Formatter) *Sink {
+	if f == nil {
+		f = &TextFormatter{}
+	}
+	return &Sink{level: int32(level), writer: w, formatter: f}
+}
+
+// ConsoleSink 输出到终端，日志级别带颜色
+func ConsoleSink(level int) *Sink {
+	s := NewSink(stdout{}, level, &TextFormatter{Color: true})
+	s.console = true
+	return s
+}
+
+// stdout 每次写入时使用当前的 os.Stdout
+type stdout struct{}
+
+func (stdout) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+// FileSink 输出到文件，JSON 格式，文件切割的参数见 rotate.go
+func FileSink(path string, level int, opts ...RotateOption) (*Sink, error) {
+	writer, err := NewRotateWriter(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewSink(writer, level, &JSONFormatter{}), nil
+}
+
+// NetSink 通过 tcp 或者 udp 发送到日志收集服务，例如 syslog，连接断开时自动重连
+// f 为 nil 时使用 SyslogFormatter
+func NetSink(network, addr string, level int, f Formatter) *Sink {
+	if f == nil {
+		f = &SyslogFormatter{}
+	}
+	return NewSink(NewNetWriter(network, addr), level, f)
+}
+
+// SetLevel 设置级别，可以在运行中修改
+func (s *Sink) SetLevel(level int) {
+	atomic.StoreInt32(&s.level, int32(level))
+}
+
+// Level ..
+func (s *Sink) Level() int {
+	return int(atomic.LoadInt32(&s.level))
+}
+
+// Writer ..
+func (s *Sink) Writer() io.Writer {
+	return s.writer
+}
+
+// write 级别足够时格式化之后写入
+func (s *Sink) write(e *Entry) {
+	if e.Level >= s.Level() {
+		s.writer.Write(s.formatter.Format(e))
+	}
+}
+
+// NetWriter 通过 tcp 或者 udp 发送数据，第一次写入时连接，写入失败时断开，下次写入时重新连接
+type NetWriter struct {
+	network string
+	addr    string
+	// timeout 连接以及写入的超时时间
+	timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewNetWriter network 为 tcp, udp 等，与 net.Dial 相同
+func NewNetWriter(network, addr string) *NetWriter {
+	return &NetWriter{network: network, addr: addr, timeout: 3 * time.Second}
+}
+
+// Write 写入失败时数据会丢失
+func (w *NetWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		conn, err := net.DialTimeout(w.network, w.addr, w.timeout)
+		if err != nil {
+			return 0, err
+		}
+		w.conn = conn
+	}
+
+	w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	n, err := w.conn.Write(p)
+	if err != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	return n, err
+}
+
+// Close ..
+func (w *NetWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// NewMultiLogger 同时输出到多个目标
+// SetLevel 设置的级别对所有目标生效，SetConsoleEnable 控制 ConsoleSink，SetPath 添加一个文件目标，格式与 NewLogger 相同
+func NewMultiLogger(sinks ...*Sink) Logger {
+	return &simpleLog{simpleCore: &simpleCore{able: true, console: true, sinks: sinks}}
+}
@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMultiLogger(t *testing.T) {
+	text := newGateWriter()
+	close(text.gate)
+	jsonWriter := newGateWriter()
+	close(jsonWriter.gate)
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+
+	textSink := NewSink(text, DEBUG, nil)
+	jsonSink := NewSink(jsonWriter, INFO, &JSONFormatter{})
+	syslogSink := NetSink("udp", udp.LocalAddr().String(), ERROR, &SyslogFormatter{Tag: "app", Hostname: "host", Facility: 16})
+	log := NewMultiLogger(textSink, jsonSink, syslogSink)
+
+	log = log.With(String("module", "order"), String("msg", "field"))
+	log.Debug("debug")
+	log.Info("info")
+	log.Errorf("error %d", 1)
+
+	if lines := strings.Split(strings.TrimSpace(text.String()), "\n"); len(lines) != 3 ||
+		!strings.HasSuffix(lines[0], "[DEBUG] debug module=order msg=field") || strings.Contains(lines[0], "\x1b[") {
+		t.Fatalf("text: %q", text.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(jsonWriter.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("json: %q", jsonWriter.String())
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data["level"] != "INFO" || data["msg"] != "info" || data["fields.msg"] != "field" || data["module"] != "order" || data["file"] != "sink_test.go" {
+		t.Fatalf("json: %v", data)
+	}
+
+	udp.SetReadDeadline(time.Now().Add(3 * time.Second))
+	buff := make([]byte, 1024)
+	n, _, err := udp.ReadFrom(buff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// local0.err = 16*8+3
+	if msg := string(buff[:n]); !strings.HasPrefix(msg, "<131>1 ") || !strings.Contains(msg, " host app ") ||
+		!strings.HasSuffix(msg, "error 1 module=order msg=field\n") {
+		t.Fatalf("syslog: %q", msg)
+	}
+
+	// 运行中修改级别
+	jsonSink.SetLevel(ERROR)
+	log.Warn("warn")
+	if strings.Count(jsonWriter.String(), "\n") != 2 {
+		t.Fatalf("json after SetLevel: %q", jsonWriter.String())
+	}
+	if err := log.(*simpleLog).Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNetWriterReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}(conn)
+		}
+	}()
+
+	w := NewNetWriter("tcp", ln.Addr().String())
+	defer w.Close()
+	if _, err := w.Write([]byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+	if line := <-lines; line != "first" {
+		t.Fatalf("line: %s", line)
+	}
+
+	// 连接断开之后重新连接
+	w.conn.Close()
+	w.Write([]byte("lost\n"))
+	if _, err := w.Write([]byte("second\n")); err != nil {
+		t.Fatal(err)
+	}
+	if line := <-lines; line != "second" {
+		t.Fatalf("line: %s", line)
+	}
+}
@@ -0,0 +1,368 @@
+//go:build go1.21
+// +build go1.21
+
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// eg:
+// 使用 logger.Logger 输出 slog 的日志
+// slog.SetDefault(slog.New(logger.NewSlogHandler(logger.NewLogger())))
+// slog.Info("hello", "uid", 10001)
+//
+// 将 slog.Handler 作为 logger.Logger 传给 sign, graceful 等
+// log := logger.NewSlogLogger(slog.NewJSONHandler(os.Stdout, nil))
+// sign.SetLog(log)
+//
+// 级别对应关系，slog 中没有 FATAL，使用 LevelFatal
+// DEBUG <-> slog.LevelDebug
+// INFO  <-> slog.LevelInfo
+// WARN  <-> slog.LevelWarn
+// ERROR <-> slog.LevelError
+// FATAL <-> LevelFatal
+//
+// 需要 go1.21 及以上
+
+// LevelFatal FATAL 对应的 slog 级别
+const LevelFatal = slog.Level(12)
+
+// toSlogLevel ..
+func toSlogLevel(level int) slog.Level {
+	switch level {
+	case DEBUG:
+		return slog.LevelDebug
+	case INFO:
+		return slog.LevelInfo
+	case WARN:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	}
+	return LevelFatal
+}
+
+// fromSlogLevel 自定义的级别向下取整，例如 slog.LevelInfo+2 对应 INFO
+func fromSlogLevel(level slog.Level) int {
+	switch {
+	case level < slog.LevelInfo:
+		return DEBUG
+	case level < slog.LevelWarn:
+		return INFO
+	case level < slog.LevelError:
+		return WARN
+	case level < LevelFatal:
+		return ERROR
+	}
+	return FATAL
+}
+
+// entryLogger 可以直接写入 Entry 的日志，保留调用位置，NewLogger, NewLogrusLogger 等都实现了该接口
+type entryLogger interface {
+	logEntry(e *Entry)
+}
+
+// slogHandler 使用 Logger 实现 slog.Handler
+type slogHandler struct {
+	logger Logger
+	// group WithGroup 设置的前缀
+	group string
+}
+
+// NewSlogHandler 使用 l 输出 slog 的日志
+// l 为本包的日志时，保留 slog 中的调用位置，FATAL 级别不会 panic
+// 其它实现使用 Debug, Info 等方法，FATAL 级别使用 Error 输出
+func NewSlogHandler(l Logger) slog.Handler {
+	return &slogHandler{logger: l}
+}
+
+// Enabled ..
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	switch fromSlogLevel(level) {
+	case DEBUG:
+		return h.logger.IsDebugAble()
+	case INFO:
+		return h.logger.IsInfoAble()
+	case WARN:
+		return h.logger.IsWarnAble()
+	}
+	return true
+}
+
+// Handle ..
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	l := h.logger
+	if ctx != nil {
+		if fields := FieldsFromContext(ctx); len(fields) > 0 {
+			l = l.With(fields...)
+		}
+	}
+	if r.NumAttrs() > 0 {
+		fields := make([]Field, 0, r.NumAttrs())
+		r.Attrs(func(attr slog.Attr) bool {
+			fields = appendAttr(fields, h.group, attr)
+			return true
+		})
+		l = l.With(fields...)
+	}
+
+	level := fromSlogLevel(r.Level)
+	if el, ok := l.(entryLogger); ok {
+		e := &Entry{Time: r.Time, Level: level, Message: r.Message, Pid: os.Getpid(), PC: r.PC}
+		if e.Time.IsZero() {
+			e.Time = time.Now()
+		}
+		if r.PC != 0 {
+			frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+			_, e.File = path.Split(frame.File)
+			e.Line = frame.Line
+			funcName := strings.Split(frame.Function, "/")
+			e.Func = funcName[len(funcName)-1]
+		}
+		el.logEntry(e)
+		return nil
+	}
+
+	switch level {
+	case DEBUG:
+		l.Debug(r.Message)
+	case INFO:
+		l.Info(r.Message)
+	case WARN:
+		l.Warn(r.Message)
+	default:
+		l.Error(r.Message)
+	}
+	return nil
+}
+
+// WithAttrs ..
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []Field
+	for _, attr := range attrs {
+		fields = appendAttr(fields, h.group, attr)
+	}
+	return &slogHandler{logger: h.logger.With(fields...), group: h.group}
+}
+
+// WithGroup 组内的字段名称为 group.key
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, group: h.group + name + "."}
+}
+
+// appendAttr 将 slog.Attr 转为 Field，组展开为 group.key
+func appendAttr(fields []Field, prefix string, attr slog.Attr) []Field {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, a := range value.Group() {
+			fields = appendAttr(fields, prefix, a)
+		}
+		return fields
+	}
+	if attr.Key == "" {
+		return fields
+	}
+	return append(fields, Field{Key: prefix + attr.Key, Value: value.Any()})
+}
+
+// slogLog 使用 slog.Handler 实现 Logger
+type slogLog struct {
+	handler slog.Handler
+	ctx     context.Context
+	*slogShared
+}
+
+// slogShared 子日志共享的设置
+type slogShared struct {
+	mu      sync.RWMutex
+	able    bool
+	level   int
+	sampler *Sampler
+}
+
+// NewSlogLogger 将日志写入 h，调用位置为调用 Debug 等方法的位置，Fatal 以 LevelFatal 写入之后 panic
+func NewSlogLogger(h slog.Handler) Logger {
+	return &slogLog{handler: h, slogShared: &slogShared{able: true}}
+}
+
+// With ..
+func (l *slogLog) With(fields ...Field) Logger {
+	if len(fields) == 0 {
+		return l
+	}
+	attrs := make([]slog.Attr, len(fields))
+	for i, field := range fields {
+		attrs[i] = slog.Any(field.Key, field.Value)
+	}
+	return &slogLog{handler: l.handler.WithAttrs(attrs), ctx: l.ctx, slogShared: l.slogShared}
+}
+
+// WithContext ctx 会传给 slog.Handler
+func (l *slogLog) WithContext(ctx context.Context) Logger {
+	child := l.With(FieldsFromContext(ctx)...).(*slogLog)
+	return &slogLog{handler: child.handler, ctx: ctx, slogShared: l.slogShared}
+}
+
+// Debug ..
+func (l *slogLog) Debug(v ...interface{}) {
+	l.log(DEBUG, fmt.Sprint(v...))
+}
+
+// Debugf ..
+func (l *slogLog) Debugf(format string, v ...interface{}) {
+	l.log(DEBUG, fmt.Sprintf(format, v...))
+}
+
+// Info ..
+func (l *slogLog) Info(v ...interface{}) {
+	l.log(INFO, fmt.Sprint(v...))
+}
+
+// Infof ..
+func (l *slogLog) Infof(format string, v ...interface{}) {
+	l.log(INFO, fmt.Sprintf(format, v...))
+}
+
+// Warn ..
+func (l *slogLog) Warn(v ...interface{}) {
+	l.log(WARN, fmt.Sprint(v...))
+}
+
+// Warnf ..
+func (l *slogLog) Warnf(format string, v ...interface{}) {
+	l.log(WARN, fmt.Sprintf(format, v...))
+}
+
+// Error ..
+func (l *slogLog) Error(v ...interface{}) {
+	l.log(ERROR, fmt.Sprint(v...))
+}
+
+// Errorf ..
+func (l *slogLog) Errorf(format string, v ...interface{}) {
+	l.log(ERROR, fmt.Sprintf(format, v...))
+}
+
+// Fatal ..
+func (l *slogLog) Fatal(v ...interface{}) {
+	l.log(FATAL, fmt.Sprint(v...))
+	panic("")
+}
+
+// Fatalf ..
+func (l *slogLog) Fatalf(format string, v ...interface{}) {
+	l.log(FATAL, fmt.Sprintf(format, v...))
+	panic("")
+}
+
+// SetSampler ..
+func (l *slogLog) SetSampler(s *Sampler) {
+	l.mu.Lock()
+	l.sampler = s
+	l.mu.Unlock()
+}
+
+// SetPath 输出位置由 slog.Handler 决定，需要写入文件时使用写入文件的 slog.Handler
+func (l *slogLog) SetPath(path string) {
+
+}
+
+// SetLevel ..
+func (l *slogLog) SetLevel(level int) {
+	l.mu.Lock()
+	l.level = level
+	l.mu.Unlock()
+}
+
+// GetLevel ..
+func (l *slogLog) GetLevel() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.level
+}
+
+// SetEnable ..
+func (l *slogLog) SetEnable(able bool) {
+	l.mu.Lock()
+	l.able = able
+	l.mu.Unlock()
+}
+
+// SetConsoleEnable 输出位置由 slog.Handler 决定
+func (l *slogLog) SetConsoleEnable(able bool) {
+
+}
+
+func (l *slogLog) IsDebugAble() bool {
+	return l.enabled(DEBUG)
+}
+
+func (l *slogLog) IsInfoAble() bool {
+	return l.enabled(INFO)
+}
+
+func (l *slogLog) IsWarnAble() bool {
+	return l.enabled(WARN)
+}
+
+func (l *slogLog) context() context.Context {
+	if l.ctx == nil {
+		return context.Background()
+	}
+	return l.ctx
+}
+
+func (l *slogLog) enabled(level int) bool {
+	l.mu.RLock()
+	able, min := l.able, l.level
+	l.mu.RUnlock()
+	return able && level >= min && l.handler.Enabled(l.context(), toSlogLevel(level))
+}
+
+// log 需要在 Debug 等方法中直接调用
+func (l *slogLog) log(level int, message string) {
+	if !l.enabled(level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	l.logEntry(&Entry{Time: time.Now(), Level: level, Message: message, PC: pcs[0]})
+}
+
+// logEntry 采样之后写入 slog.Handler
+func (l *slogLog) logEntry(e *Entry) {
+	l.mu.RLock()
+	sampler := l.sampler
+	l.mu.RUnlock()
+
+	write := func(e *Entry) {
+		r := slog.NewRecord(e.Time, toSlogLevel(e.Level), e.Message, e.PC)
+		l.handler.Handle(l.context(), r)
+	}
+	if sampler != nil {
+		frame, _ := runtime.CallersFrames([]uintptr{e.PC}).Next()
+		if !sampler.allow(e.Level, frame.File, frame.Line, e.Message, func(summary string) {
+			e.Time = time.Now()
+			e.Message = summary
+			write(e)
+		}) {
+			return
+		}
+	}
+	write(e)
+}
@@ -0,0 +1,68 @@
+//go:build go1.21
+// +build go1.21
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	w := newGateWriter()
+	close(w.gate)
+	l := NewWriterLogger(w)
+	l.SetLevel(INFO)
+
+	log := slog.New(NewSlogHandler(l)).With("module", "order").WithGroup("req")
+	log.Debug("hidden")
+	log.Info("hello", "id", 1)
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	log.Log(ctx, LevelFatal, "boom")
+
+	lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("output: %q", w.String())
+	}
+	if !strings.HasSuffix(lines[0], "[INFO] hello module=order req.id=1") || !strings.Contains(lines[0], "slog_test.go:22-logger.TestSlogHandler") {
+		t.Errorf("info: %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "[FATAL] boom module=order request_id=req-1") {
+		t.Errorf("fatal: %s", lines[1])
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buff bytes.Buffer
+	log := NewSlogLogger(slog.NewTextHandler(&buff, &slog.HandlerOptions{AddSource: true}))
+	log.Debug("hidden")
+	log.With(String("module", "order")).Errorf("create order %d failed", 1)
+	if out := buff.String(); !strings.Contains(out, "level=ERROR") || !strings.Contains(out, `msg="create order 1 failed"`) ||
+		!strings.Contains(out, "slog_test.go:42") || !strings.Contains(out, "module=order") || strings.Contains(out, "hidden") {
+		t.Fatalf("output: %s", out)
+	}
+
+	buff.Reset()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Fatal should panic")
+			}
+		}()
+		log.Fatal("boom")
+	}()
+	if out := buff.String(); !strings.Contains(out, "level=ERROR+4") || !strings.Contains(out, "msg=boom") {
+		t.Fatalf("fatal: %s", out)
+	}
+
+	// 输出位置由 slog.Handler 决定，SetPath 不生效
+	buff.Reset()
+	log.SetPath("./logs/app.log")
+	log.Info("after SetPath")
+	if out := buff.String(); !strings.Contains(out, `msg="after SetPath"`) {
+		t.Fatalf("after SetPath: %s", out)
+	}
+}
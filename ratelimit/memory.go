@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// memoryLimiter 基于进程内存的限流器，只对当前进程有效
+type memoryLimiter struct {
+	limit Limit
+	conf  *config
+
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	// sweepAt 下次清理过期 key 的时间
+	sweepAt time.Time
+}
+
+type memoryEntry struct {
+	// TokenBucket
+	tokens float64
+	last   time.Time
+	// FixedWindow
+	count int
+	// SlidingLog 请求的时间，从旧到新
+	log []time.Time
+
+	// expireAt 之后 key 恢复到初始状态，可以删除
+	expireAt time.Time
+}
+
+// NewMemory 创建基于进程内存的限流器
+func NewMemory(limit Limit, opts ...Option) Limiter {
+	conf := defaultConfig()
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return &memoryLimiter{
+		limit:   limit,
+		conf:    conf,
+		entries: make(map[string]*memoryEntry),
+	}
+}
+
+// Allow ..
+func (l *memoryLimiter) Allow(key string) (*Result, error) {
+	return l.AllowN(key, 1)
+}
+
+// AllowN ..
+func (l *memoryLimiter) AllowN(key string, n int) (*Result, error) {
+	if err := check(l.limit, l.conf.algorithm, n); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	e, exist := l.entries[key]
+	if !exist {
+		e = &memoryEntry{}
+		l.entries[key] = e
+	}
+
+	var r *Result
+	switch l.conf.algorithm {
+	case FixedWindow:
+		r = l.fixedWindow(e, now, n)
+	case SlidingLog:
+		r = l.slidingLog(e, now, n)
+	default:
+		e.tokens, r = take(l.limit, e.tokens, e.last, now, n)
+		e.last = now
+	}
+	e.expireAt = now.Add(r.ResetAfter)
+	return r, nil
+}
+
+func (l *memoryLimiter) fixedWindow(e *memoryEntry, now time.Time, n int) *Result {
+	if e.count == 0 || !now.Before(e.expireAt) {
+		e.count = 0
+		e.expireAt = now.Add(l.limit.Period)
+	}
+	e.count += n
+
+	r := &Result{
+		Allowed:    e.count <= l.limit.Rate,
+		Limit:      l.limit.Rate,
+		ResetAfter: e.expireAt.Sub(now),
+	}
+	if r.Allowed {
+		r.Remaining = l.limit.Rate - e.count
+	} else {
+		r.RetryAfter = r.ResetAfter
+	}
+	return r
+}
+
+func (l *memoryLimiter) slidingLog(e *memoryEntry, now time.Time, n int) *Result {
+	// 删除窗口之外的记录
+	start := now.Add(-l.limit.Period)
+	i := 0
+	for i < len(e.log) && !e.log[i].After(start) {
+		i++
+	}
+	e.log = e.log[i:]
+
+	r := &Result{Limit: l.limit.Rate}
+	if len(e.log)+n <= l.limit.Rate {
+		for i := 0; i < n; i++ {
+			e.log = append(e.log, now)
+		}
+		r.Allowed = true
+	} else {
+		// 需要等待最旧的若干条记录离开窗口
+		oldest := e.log[len(e.log)+n-l.limit.Rate-1]
+		r.RetryAfter = oldest.Add(l.limit.Period).Sub(now)
+	}
+	r.Remaining = l.limit.Rate - len(e.log)
+	if len(e.log) > 0 {
+		r.ResetAfter = e.log[len(e.log)-1].Add(l.limit.Period).Sub(now)
+	}
+	return r
+}
+
+// sweep 删除已经恢复到初始状态的 key，每个 Period 最多执行一次
+func (l *memoryLimiter) sweep(now time.Time) {
+	if now.Before(l.sweepAt) {
+		return
+	}
+	l.sweepAt = now.Add(l.limit.Period)
+	for key, e := range l.entries {
+		if !now.Before(e.expireAt) {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// KeyFunc 从请求中获取限流的 key，返回空字符串时不限流
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP 按照客户端 IP 限流，使用 RemoteAddr
+// 位于反向代理之后时，需要使用 KeyByHeader 读取代理设置的请求头 (例如 X-Real-IP)
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader 按照请求头限流，例如用户 ID、API Key
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// Middleware 限流中间件
+// 设置 RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset 响应头
+// 被限流时返回 429 Too Many Requests，并设置 Retry-After
+// 限流器出错时 (例如 redis 不可用) 不限流，ErrBusy 除外，返回 429
+func Middleware(l Limiter, key KeyFunc, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k := key(r)
+		if k == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		result, err := l.Allow(k)
+		if err == ErrBusy {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", ceilSeconds(result.ResetAfter))
+
+		if !result.Allowed {
+			header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ceilSeconds 秒数，向上取整
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+// Algorithm 限流算法
+type Algorithm int
+
+const (
+	// TokenBucket 令牌桶，令牌按照固定速率生成，桶的容量为 Limit.Burst，允许一定的突发请求
+	TokenBucket Algorithm = iota
+	// FixedWindow 固定窗口，从窗口内第一个请求开始计数，窗口结束之后清零
+	// 被拒绝的请求同样计数
+	FixedWindow
+	// SlidingLog 滑动窗口日志，记录每个请求的时间，统计最近 Limit.Period 内的请求数
+	// 精确但是每个请求都会占用存储，适合 Rate 较小的场景
+	SlidingLog
+)
+
+// config 配置
+type config struct {
+	algorithm Algorithm
+	// prefix redis 中 key 的前缀
+	prefix string
+}
+
+func defaultConfig() *config {
+	return &config{
+		algorithm: TokenBucket,
+		prefix:    "ratelimit:",
+	}
+}
+
+// Option ..
+type Option func(*config)
+
+// WithAlgorithm 限流算法，默认为 TokenBucket
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(c *config) {
+		c.algorithm = algorithm
+	}
+}
+
+// WithPrefix redis 中 key 的前缀，默认为 ratelimit:
+func WithPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+	}
+}
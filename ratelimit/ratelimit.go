@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// eg:
+// 每个用户每分钟最多 100 次请求，允许突发 20 次
+// limit := ratelimit.PerMinute(100)
+// limit.Burst = 20
+// l := ratelimit.NewRedis(c, limit)
+//
+// r, err := l.Allow("user:1001")
+// if err == nil && !r.Allowed {
+// 	return fmt.Errorf("retry after %s", r.RetryAfter)
+// }
+//
+// 单机使用，按照 IP 限流，固定窗口
+// l := ratelimit.NewMemory(ratelimit.PerSecond(10), ratelimit.WithAlgorithm(ratelimit.FixedWindow))
+// http.ListenAndServe(":8080", ratelimit.Middleware(l, ratelimit.KeyByIP, mux))
+
+var (
+	// ErrInvalidLimit Limit 的 Rate 或 Period 不大于 0
+	ErrInvalidLimit = errors.New("ratelimit: invalid limit")
+	// ErrExceedsLimit 单次请求的数量超过了限流的容量，永远不会被允许
+	ErrExceedsLimit = errors.New("ratelimit: n exceeds limit")
+	// ErrInvalidN 单次请求的数量小于 1
+	ErrInvalidN = errors.New("ratelimit: n must be at least 1")
+	// ErrBusy 内存模式下 key 被并发修改，重试之后仍然失败，可以视为被限流
+	ErrBusy = errors.New("ratelimit: key is busy")
+)
+
+// Limit 限流规则: 每 Period 时间内最多 Rate 次请求
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	// Burst 令牌桶的容量，只对 TokenBucket 有效，不大于 0 时与 Rate 相同
+	Burst int
+}
+
+// PerSecond 每秒 rate 次
+func PerSecond(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Second}
+}
+
+// PerMinute 每分钟 rate 次
+func PerMinute(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Minute}
+}
+
+// PerHour 每小时 rate 次
+func PerHour(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Hour}
+}
+
+func (l Limit) valid() bool {
+	return l.Rate > 0 && l.Period > 0
+}
+
+// capacity 同时允许的最大请求数
+func (l Limit) capacity(algorithm Algorithm) int {
+	if algorithm == TokenBucket && l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Rate
+}
+
+// Result 限流结果
+type Result struct {
+	// Allowed 是否允许本次请求
+	Allowed bool
+	// Limit 同时允许的最大请求数
+	Limit int
+	// Remaining 剩余可用的请求数
+	Remaining int
+	// RetryAfter 被拒绝时，需要等待多久才能再次请求，允许时为 0
+	RetryAfter time.Duration
+	// ResetAfter 多久之后剩余可用的请求数恢复到 Limit
+	ResetAfter time.Duration
+}
+
+// Limiter 限流器
+type Limiter interface {
+	// Allow 等同于 AllowN(key, 1)
+	Allow(key string) (*Result, error)
+
+	// AllowN 请求 n 次，n 小于 1 时返回 ErrInvalidN，n 大于限流的容量时返回 ErrExceedsLimit
+	AllowN(key string, n int) (*Result, error)
+}
+
+// check 检查参数
+func check(limit Limit, algorithm Algorithm, n int) error {
+	if !limit.valid() {
+		return ErrInvalidLimit
+	}
+	if n < 1 {
+		return ErrInvalidN
+	}
+	if n > limit.capacity(algorithm) {
+		return ErrExceedsLimit
+	}
+	return nil
+}
+
+// take 令牌桶: 根据上次的令牌数以及时间补充令牌，然后取出 n 个
+// last 为零值表示桶不存在，此时桶是满的
+// 返回取出之后的令牌数
+func take(limit Limit, tokens float64, last, now time.Time, n int) (float64, *Result) {
+	burst := limit.capacity(TokenBucket)
+	// rate 每秒生成的令牌数
+	rate := float64(limit.Rate) / limit.Period.Seconds()
+
+	if last.IsZero() {
+		tokens = float64(burst)
+	} else if elapsed := now.Sub(last); elapsed > 0 {
+		tokens = math.Min(float64(burst), tokens+elapsed.Seconds()*rate)
+	}
+
+	allowed := tokens >= float64(n)
+	if allowed {
+		tokens -= float64(n)
+	}
+	return tokens, bucketResult(limit, tokens, n, allowed)
+}
+
+// bucketResult 令牌桶的结果，tokens 为取出之后 (被拒绝时为补充之后) 的令牌数
+func bucketResult(limit Limit, tokens float64, n int, allowed bool) *Result {
+	burst := limit.capacity(TokenBucket)
+	rate := float64(limit.Rate) / limit.Period.Seconds()
+
+	r := &Result{Limit: burst, Allowed: allowed}
+	if !allowed {
+		r.RetryAfter = seconds((float64(n) - tokens) / rate)
+	}
+	r.Remaining = int(tokens)
+	r.ResetAfter = seconds((float64(burst) - tokens) / rate)
+	return r
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,226 @@
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alex-my/ghelper/cache"
+)
+
+func newCache(t *testing.T) cache.Cache {
+	c := cache.NewCache(cache.WithMemory())
+	if err := c.Open(); err != nil {
+		t.Fatalf("open memory cache failed: %s", err.Error())
+	}
+	return c
+}
+
+// newLimiters 同一个规则的内存以及 redis 限流器
+func newLimiters(t *testing.T, limit Limit, algorithm Algorithm) (map[string]Limiter, func()) {
+	c := newCache(t)
+	return map[string]Limiter{
+		"memory": NewMemory(limit, WithAlgorithm(algorithm)),
+		"redis":  NewRedis(c, limit, WithAlgorithm(algorithm)),
+	}, func() { c.Close() }
+}
+
+func allow(t *testing.T, name string, l Limiter, key string, n int, allowed bool) *Result {
+	r, err := l.AllowN(key, n)
+	if err != nil {
+		t.Fatalf("%s AllowN failed: %s", name, err.Error())
+	}
+	if r.Allowed != allowed {
+		t.Fatalf("%s AllowN(%s, %d): %+v", name, key, n, r)
+	}
+	return r
+}
+
+func TestInvalidN(t *testing.T) {
+	for _, algorithm := range []Algorithm{TokenBucket, FixedWindow, SlidingLog} {
+		limiters, closer := newLimiters(t, PerMinute(10), algorithm)
+		for name, l := range limiters {
+			for _, n := range []int{0, -100} {
+				if r, err := l.AllowN("a", n); err != ErrInvalidN {
+					t.Errorf("%s %v AllowN(%d): %+v, err: %v", name, algorithm, n, r, err)
+				}
+			}
+			// 不影响之后的请求
+			if r := allow(t, name, l, "a", 10, true); r.Remaining != 0 {
+				t.Errorf("%s %v Remaining: %+v", name, algorithm, r)
+			}
+			allow(t, name, l, "a", 1, false)
+		}
+		closer()
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	// 每 20ms 生成一个令牌，容量为 3
+	limiters, closer := newLimiters(t, Limit{Rate: 5, Period: 100 * time.Millisecond, Burst: 3}, TokenBucket)
+	defer closer()
+
+	for name, l := range limiters {
+		for i := 2; i >= 0; i-- {
+			if r := allow(t, name, l, "a", 1, true); r.Remaining != i || r.Limit != 3 {
+				t.Fatalf("%s Remaining: %+v", name, r)
+			}
+		}
+		r := allow(t, name, l, "a", 1, false)
+		if r.RetryAfter <= 0 || r.RetryAfter > 20*time.Millisecond {
+			t.Fatalf("%s RetryAfter: %s", name, r.RetryAfter)
+		}
+		// 其它 key 不受影响
+		allow(t, name, l, "b", 3, true)
+
+		time.Sleep(25 * time.Millisecond)
+		allow(t, name, l, "a", 1, true)
+		allow(t, name, l, "a", 1, false)
+
+		if _, err := l.AllowN("a", 4); err != ErrExceedsLimit {
+			t.Fatalf("%s AllowN exceeds burst, err: %v", name, err)
+		}
+	}
+}
+
+func TestFixedWindow(t *testing.T) {
+	limiters, closer := newLimiters(t, Limit{Rate: 3, Period: 50 * time.Millisecond}, FixedWindow)
+	defer closer()
+
+	for name, l := range limiters {
+		allow(t, name, l, "a", 2, true)
+		if r := allow(t, name, l, "a", 1, true); r.Remaining != 0 {
+			t.Fatalf("%s Remaining: %+v", name, r)
+		}
+		r := allow(t, name, l, "a", 1, false)
+		if r.RetryAfter <= 0 || r.RetryAfter > 50*time.Millisecond || r.RetryAfter != r.ResetAfter {
+			t.Fatalf("%s RetryAfter: %+v", name, r)
+		}
+
+		time.Sleep(60 * time.Millisecond)
+		if r := allow(t, name, l, "a", 1, true); r.Remaining != 2 {
+			t.Fatalf("%s Remaining after window: %+v", name, r)
+		}
+	}
+}
+
+func TestSlidingLog(t *testing.T) {
+	limiters, closer := newLimiters(t, Limit{Rate: 3, Period: 60 * time.Millisecond}, SlidingLog)
+	defer closer()
+
+	for name, l := range limiters {
+		allow(t, name, l, "a", 1, true)
+		time.Sleep(30 * time.Millisecond)
+		allow(t, name, l, "a", 2, true)
+
+		// 被拒绝的请求不记录，第一个请求离开窗口之后即可请求
+		r := allow(t, name, l, "a", 1, false)
+		if r.Remaining != 0 || r.RetryAfter <= 0 || r.RetryAfter > 30*time.Millisecond {
+			t.Fatalf("%s RetryAfter: %+v", name, r)
+		}
+		if r.ResetAfter < 50*time.Millisecond || r.ResetAfter > 60*time.Millisecond {
+			t.Fatalf("%s ResetAfter: %+v", name, r)
+		}
+
+		time.Sleep(r.RetryAfter + 5*time.Millisecond)
+		allow(t, name, l, "a", 1, true)
+		allow(t, name, l, "a", 1, false)
+	}
+}
+
+func TestSlidingLogConcurrent(t *testing.T) {
+	c := newCache(t)
+	defer c.Close()
+	l := NewRedis(c, Limit{Rate: 10, Period: time.Minute}, WithAlgorithm(SlidingLog))
+
+	// 被拒绝的请求不能影响其它请求，共允许 Rate 个
+	var allowed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				r, err := l.Allow("a")
+				if err != nil {
+					t.Errorf("Allow failed: %s", err.Error())
+					return
+				}
+				if r.Allowed {
+					atomic.AddInt32(&allowed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 10 {
+		t.Fatalf("allowed: %d", allowed)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	l := NewMemory(PerMinute(2))
+	handler := Middleware(l, KeyByIP, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	serve("10.0.0.1:1000")
+	w := serve("10.0.0.1:1001")
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("response: %d, header: %v", w.Code, w.Header())
+	}
+
+	w = serve("10.0.0.1:1002")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+		t.Fatalf("limited response: %d, header: %v", w.Code, w.Header())
+	}
+
+	if w = serve("10.0.0.2:1000"); w.Code != http.StatusOK {
+		t.Fatalf("other ip response: %d", w.Code)
+	}
+}
+
+// errLimiter 总是返回 err 的限流器
+type errLimiter struct {
+	err error
+}
+
+func (l errLimiter) Allow(key string) (*Result, error) {
+	return l.AllowN(key, 1)
+}
+
+func (l errLimiter) AllowN(key string, n int) (*Result, error) {
+	return nil, l.err
+}
+
+func TestMiddlewareError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{errors.New("redis is unavailable"), http.StatusOK},
+		{ErrBusy, http.StatusTooManyRequests},
+	}
+	for _, test := range tests {
+		handler := Middleware(errLimiter{test.err}, KeyByIP, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != test.code {
+			t.Errorf("%v: %d", test.err, w.Code)
+		}
+	}
+}
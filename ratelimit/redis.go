@@ -0,0 +1,357 @@
+package ratelimit
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/alex-my/ghelper/cache"
+	"github.com/alex-my/ghelper/random"
+)
+
+// errScriptReply lua 脚本的返回值格式错误
+var errScriptReply = errors.New("ratelimit: unexpected script reply")
+
+// watchRetries 内存模式下令牌桶被并发修改时的重试次数
+const watchRetries = 10
+
+// tokenBucketScript 令牌桶
+// KEYS[1] 令牌桶，ARGV: 容量，每微秒生成的令牌数，请求数
+// 返回值都是字符串，{是否允许, 取出之后 (被拒绝时为补充之后) 的令牌数}
+var tokenBucketScript = cache.NewScript(`
+redis.replicate_commands()
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+
+local tokens = burst
+local values = redis.call("HMGET", KEYS[1], "tokens", "ts")
+if values[1] and values[2] then
+	tokens = tonumber(values[1])
+	local elapsed = now - tonumber(values[2])
+	if elapsed > 0 then
+		tokens = math.min(burst, tokens + elapsed * rate)
+	end
+end
+
+local allowed = "0"
+if tokens >= n then
+	tokens = tokens - n
+	allowed = "1"
+	redis.call("HMSET", KEYS[1], "tokens", string.format("%.17g", tokens), "ts", string.format("%.0f", now))
+	redis.call("PEXPIRE", KEYS[1], math.max(1, math.ceil((burst - tokens) / rate / 1000)))
+end
+return {allowed, string.format("%.17g", tokens)}
+`)
+
+// slidingLogScript 滑动日志，只记录允许的请求
+// KEYS[1] sorted set，ARGV: 窗口的微秒数，窗口内最多的请求数，请求数，本次请求的 member 前缀
+// 返回值都是字符串，{是否允许, 窗口内已有的请求数, 当前时间, 被拒绝时需要等待离开窗口的记录的时间, 最新记录的时间}，时间为微秒
+var slidingLogScript = cache.NewScript(`
+redis.replicate_commands()
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local period = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", string.format("%.0f", now - period))
+local count = redis.call("ZCARD", KEYS[1])
+if count + n <= rate then
+	for i = 1, n do
+		redis.call("ZADD", KEYS[1], string.format("%.0f", now), ARGV[4] .. ":" .. i)
+	end
+	redis.call("PEXPIRE", KEYS[1], math.max(1, math.ceil(period / 1000)))
+	return {"1", tostring(count), string.format("%.0f", now), "", ""}
+end
+
+local k = count + n - rate - 1
+local oldest = redis.call("ZRANGE", KEYS[1], k, k, "WITHSCORES")
+local newest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+return {"0", tostring(count), string.format("%.0f", now), oldest[2] or "", newest[2] or ""}
+`)
+
+// redisLimiter 基于 cache.Cache 的限流器，多个进程共享限流状态
+// 所有的时间都使用 redis 服务器的时间 (TIME)，不受各个进程之间时钟误差的影响
+// TokenBucket: hash 保存令牌数以及更新时间，使用 lua 脚本保证原子性
+// FixedWindow: 在 MULTI 中执行 SET NX PX, INCRBY
+// SlidingLog: sorted set 保存请求时间，使用 lua 脚本保证原子性，只记录允许的请求
+// 内存模式 (cache.WithMemory) 不支持 lua 脚本，TokenBucket 以及 SlidingLog 改用 WATCH 实现
+type redisLimiter struct {
+	c     cache.Cache
+	limit Limit
+	conf  *config
+}
+
+// NewRedis 创建基于 redis 的限流器
+func NewRedis(c cache.Cache, limit Limit, opts ...Option) Limiter {
+	conf := defaultConfig()
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return &redisLimiter{c: c, limit: limit, conf: conf}
+}
+
+// Allow ..
+func (l *redisLimiter) Allow(key string) (*Result, error) {
+	return l.AllowN(key, 1)
+}
+
+// AllowN ..
+func (l *redisLimiter) AllowN(key string, n int) (*Result, error) {
+	if err := check(l.limit, l.conf.algorithm, n); err != nil {
+		return nil, err
+	}
+
+	key = l.conf.prefix + key
+	switch l.conf.algorithm {
+	case FixedWindow:
+		return l.fixedWindow(key, n)
+	case SlidingLog:
+		return l.slidingLog(key, n)
+	default:
+		return l.tokenBucket(key, n)
+	}
+}
+
+func (l *redisLimiter) tokenBucket(key string, n int) (*Result, error) {
+	burst := l.limit.capacity(TokenBucket)
+	rate := float64(l.limit.Rate) / float64(l.limit.Period/time.Microsecond)
+	values, err := l.c.Strings(tokenBucketScript.Run(l.c, []string{key}, burst, strconv.FormatFloat(rate, 'g', -1, 64), n))
+	if cache.IsNoScripting(err) {
+		return l.tokenBucketWatch(key, n)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 2 {
+		return nil, errScriptReply
+	}
+	tokens, err := strconv.ParseFloat(values[1], 64)
+	if err != nil {
+		return nil, errScriptReply
+	}
+	return bucketResult(l.limit, tokens, n, values[0] == "1"), nil
+}
+
+// tokenBucketWatch 使用 WATCH 实现的令牌桶，用于不支持 lua 脚本的内存模式
+func (l *redisLimiter) tokenBucketWatch(key string, n int) (*Result, error) {
+	var r *Result
+	err := l.c.WatchRetry(watchRetries, func(tx cache.Tx) error {
+		now, err := l.time(tx.DO("TIME"))
+		if err != nil {
+			return err
+		}
+		values, err := l.c.Strings(tx.DO("HMGET", key, "tokens", "ts"))
+		if err != nil {
+			return err
+		}
+
+		var tokens float64
+		var last time.Time
+		if values[0] != "" && values[1] != "" {
+			tokens, _ = strconv.ParseFloat(values[0], 64)
+			us, _ := strconv.ParseInt(values[1], 10, 64)
+			last = time.Unix(0, us*int64(time.Microsecond))
+		}
+
+		tokens, r = take(l.limit, tokens, last, now, n)
+		if !r.Allowed {
+			return nil
+		}
+
+		p := tx.Pipeline()
+		p.HMSet(key, "tokens", strconv.FormatFloat(tokens, 'f', -1, 64), "ts", micro(now))
+		p.Do("PEXPIRE", key, ms(r.ResetAfter))
+		return p.Exec()
+	}, key)
+	if err == cache.ErrTxFailed {
+		return nil, ErrBusy
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (l *redisLimiter) fixedWindow(key string, n int) (*Result, error) {
+	p := l.c.TxPipeline()
+	// 窗口从第一个请求开始
+	p.Do("SET", key, 0, "PX", ms(l.limit.Period), "NX")
+	incr := p.Incrby(key, int64(n))
+	pttl := p.PTTL(key)
+	if err := p.Exec(); err != nil {
+		return nil, err
+	}
+
+	count, err := incr.Result()
+	if err != nil {
+		return nil, err
+	}
+	remain, err := pttl.Result()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Result{
+		Allowed:    count <= int64(l.limit.Rate),
+		Limit:      l.limit.Rate,
+		ResetAfter: time.Duration(remain) * time.Millisecond,
+	}
+	if r.Allowed {
+		r.Remaining = l.limit.Rate - int(count)
+	} else {
+		r.RetryAfter = r.ResetAfter
+	}
+	return r, nil
+}
+
+func (l *redisLimiter) slidingLog(key string, n int) (*Result, error) {
+	values, err := l.c.Strings(slidingLogScript.Run(l.c, []string{key},
+		int64(l.limit.Period/time.Microsecond), l.limit.Rate, n, random.NewUUID()))
+	if cache.IsNoScripting(err) {
+		return l.slidingLogWatch(key, n)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 5 {
+		return nil, errScriptReply
+	}
+	count, err := strconv.Atoi(values[1])
+	if err != nil {
+		return nil, errScriptReply
+	}
+	now, ok := parseMicro(values[2])
+	if !ok {
+		return nil, errScriptReply
+	}
+
+	r := &Result{Limit: l.limit.Rate}
+	if values[0] == "1" {
+		r.Allowed = true
+		r.Remaining = l.limit.Rate - count - n
+		r.ResetAfter = l.limit.Period
+		return r, nil
+	}
+	if r.Remaining = l.limit.Rate - count; r.Remaining < 0 {
+		r.Remaining = 0
+	}
+	if t, ok := parseMicro(values[3]); ok {
+		r.RetryAfter = t.Add(l.limit.Period).Sub(now)
+	}
+	if t, ok := parseMicro(values[4]); ok {
+		r.ResetAfter = t.Add(l.limit.Period).Sub(now)
+	}
+	return r, nil
+}
+
+// slidingLogWatch 使用 WATCH 实现的滑动日志，用于不支持 lua 脚本的内存模式
+func (l *redisLimiter) slidingLogWatch(key string, n int) (*Result, error) {
+	var r *Result
+	err := l.c.WatchRetry(watchRetries, func(tx cache.Tx) error {
+		now, err := l.time(tx.DO("TIME"))
+		if err != nil {
+			return err
+		}
+		// WATCH 之后只读取，过期的记录在 MULTI 中删除
+		start := "(" + strconv.FormatInt(micro(now.Add(-l.limit.Period)), 10)
+		count, err := l.c.Int(tx.DO("ZCOUNT", key, start, "+inf"))
+		if err != nil {
+			return err
+		}
+
+		r = &Result{Limit: l.limit.Rate}
+		if count+n > l.limit.Rate {
+			// 被拒绝的请求不记录，需要等待最旧的若干条记录离开窗口
+			if r.Remaining = l.limit.Rate - count; r.Remaining < 0 {
+				r.Remaining = 0
+			}
+			k := count + n - l.limit.Rate - 1
+			if t, ok := l.score(tx.DO("ZRANGEBYSCORE", key, start, "+inf", "WITHSCORES", "LIMIT", k, 1)); ok {
+				r.RetryAfter = t.Add(l.limit.Period).Sub(now)
+			}
+			if t, ok := l.score(tx.DO("ZRANGE", key, -1, -1, "WITHSCORES")); ok {
+				r.ResetAfter = t.Add(l.limit.Period).Sub(now)
+			}
+			return nil
+		}
+		r.Allowed = true
+		r.Remaining = l.limit.Rate - count - n
+		r.ResetAfter = l.limit.Period
+
+		// 同一时间的多个请求需要不同的 member
+		id := random.NewUUID()
+		args := []interface{}{key}
+		for i := 0; i < n; i++ {
+			args = append(args, micro(now), id+":"+strconv.Itoa(i))
+		}
+
+		p := tx.Pipeline()
+		p.Do("ZREMRANGEBYSCORE", key, "-inf", micro(now.Add(-l.limit.Period)))
+		p.Do("ZADD", args...)
+		p.Do("PEXPIRE", key, ms(l.limit.Period))
+		return p.Exec()
+	}, key)
+	if err == cache.ErrTxFailed {
+		return nil, ErrBusy
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// time 解析 TIME 的返回值
+func (l *redisLimiter) time(reply interface{}, err error) (time.Time, error) {
+	values, err := l.c.Strings(reply, err)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(values) != 2 {
+		return time.Time{}, cache.ErrInvalidParamCount
+	}
+	sec, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	us, err := strconv.ParseInt(values[1], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, us*int64(time.Microsecond)), nil
+}
+
+// score 解析 ZRANGE WITHSCORES 返回的第一个成员的时间
+func (l *redisLimiter) score(reply interface{}, err error) (time.Time, bool) {
+	values, err := l.c.Strings(reply, err)
+	if err != nil || len(values) != 2 {
+		return time.Time{}, false
+	}
+	return parseMicro(values[1])
+}
+
+// parseMicro 解析微秒时间戳，可以是浮点数，例如 sorted set 的 score
+func parseMicro(s string) (time.Time, bool) {
+	us, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(us)*int64(time.Microsecond)), true
+}
+
+// micro 微秒时间戳
+func micro(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
+
+// ms 转换为毫秒，向上取整，最小为 1
+func ms(d time.Duration) int64 {
+	n := int64((d + time.Millisecond - 1) / time.Millisecond)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
@@ -46,9 +46,10 @@ func SetCalcFunc(f func(string, string) string) {
 	calcFunc = f
 }
 
-// SetLog 设置日志
+// SetLog 设置日志，日志注册为 sign，可以通过 logger.LevelHandler 修改级别
 func SetLog(l logger.Logger) {
 	log = l
+	logger.Register("sign", l)
 }
 
 // SetDebug 是否打印日志
@@ -174,4 +175,6 @@ func init() {
 	bufferPool.New = func() interface{} {
 		return &bytes.Buffer{}
 	}
+
+	logger.Register("sign", log)
 }